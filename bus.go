@@ -0,0 +1,237 @@
+//  bus.go - a Connection shared by the cameras on a daisy chain
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package visca
+
+import (
+	"sync"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Bus shares one Connection between the cameras daisy-chained on it, e.g. up to 7 cameras on an RS-232 or
+// RS-422 port
+//
+// Each camera gets its own Connection from Camera. The underlying Connection is started when the first
+// camera is started and stopped when the last one is stopped, writes are serialized, and received packets
+// are routed to the receive queue of the camera they came from. Broadcasts are delivered once to every
+// distinct receive queue, so cameras that share a queue (like a Controller's) don't see duplicates.
+// Controller.EnumerateBus uses the Connection for BroadcastAddress to assign the cameras their addresses.
+//
+// Example
+//
+//  port, err := visca.NewSerialConnection("/dev/ttyUSB0")
+//  bus := visca.NewBus(port)
+//  cam1, err := bus.Camera(1)
+//  ctrl.AddCamera(1, cam1)
+//  cam2, err := bus.Camera(2)
+//  ctrl.AddCamera(2, cam2)
+type Bus struct {
+	conn    Connection
+	logger  zerolog.Logger
+	mu      sync.Mutex      // protects everything below
+	writeMu sync.Mutex      // serializes writes to conn
+	cameras [9]*busCamera   // Connection for each address; 0 is not used
+	queues  [9]chan *Packet // receive queue for each address; 0 is not used
+	started int             // number of cameras started
+	quit    chan struct{}   // closed to stop the route goroutine
+}
+
+// NewBus creates a Bus on the given Connection
+func NewBus(conn Connection) *Bus {
+	return &Bus{
+		conn:   conn,
+		logger: log.Logger,
+	}
+}
+
+// SetLogger sets where the Bus logs; it must be called before any of its cameras are started
+func (b *Bus) SetLogger(logger zerolog.Logger) {
+	b.logger = logger
+}
+
+// Camera returns the Connection for the camera with the given address (1-7) on the bus
+//
+// The Connection for BroadcastAddress sends broadcasts and receives only the broadcasts that come back.
+func (b *Bus) Camera(address int) (Connection, error) {
+	if address > BroadcastAddress || address <= 0 {
+		return nil, ErrAddressOutOfBounds
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cameras[address] == nil {
+		b.cameras[address] = &busCamera{
+			bus:     b,
+			address: address,
+		}
+	}
+	return b.cameras[address], nil
+}
+
+// start starts the underlying Connection if this is the first camera to be started
+func (b *Bus) start() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.started > 0 {
+		b.started++
+		return nil
+	}
+
+	incoming := make(chan *Packet)
+	b.conn.SetReceiveQueue(incoming)
+	err := b.conn.Start()
+	if err != nil {
+		return err
+	}
+	b.started++
+	b.quit = make(chan struct{})
+	go b.route(incoming, b.quit)
+	return nil
+}
+
+// stop stops the underlying Connection if this is the last camera to be stopped
+func (b *Bus) stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.started == 0 {
+		return
+	}
+	b.started--
+	if b.started == 0 {
+		b.conn.Stop()
+		close(b.quit)
+	}
+}
+
+// send serializes writes to the underlying Connection
+func (b *Bus) send(pkt *Packet) error {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+	return b.conn.Send(pkt)
+}
+
+// setReceiveQueue sets the receive queue for packets from the given address
+func (b *Bus) setReceiveQueue(address int, q chan *Packet) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.queues[address] = q
+}
+
+// route delivers packets from the underlying Connection until quit is closed, or it closes the incoming queue
+func (b *Bus) route(incoming chan *Packet, quit chan struct{}) {
+	for {
+		select {
+		case pkt, ok := <-incoming:
+			if !ok {
+				return
+			}
+			for _, q := range b.destinations(pkt) {
+				select {
+				case q <- pkt:
+				case <-quit:
+					return
+				}
+			}
+		case <-quit:
+			return
+		}
+	}
+}
+
+// destinations returns the receive queues a packet should be delivered to
+func (b *Bus) destinations(pkt *Packet) []chan *Packet {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !pkt.IsBroadcast() {
+		q := b.queues[pkt.Source()]
+		if q == nil {
+			b.logger.Debug().Msgf("dropping packet from %v; no camera on the bus with that address", pkt.Source())
+			return nil
+		}
+		return []chan *Packet{q}
+	}
+
+	queues := make([]chan *Packet, 0, len(b.queues))
+	for _, q := range b.queues {
+		if q == nil {
+			continue
+		}
+		dup := false
+		for _, seen := range queues {
+			if q == seen {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			queues = append(queues, q)
+		}
+	}
+	return queues
+}
+
+// busCamera is the Connection to a single camera on a Bus
+type busCamera struct {
+	bus     *Bus
+	address int
+	mu      sync.Mutex
+	started bool
+}
+
+// Start the connection; the first camera on the bus to start starts the underlying Connection
+func (c *busCamera) Start() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.started {
+		return nil
+	}
+	err := c.bus.start()
+	if err != nil {
+		return err
+	}
+	c.started = true
+	return nil
+}
+
+// Stop the connection; the last camera on the bus to stop stops the underlying Connection
+func (c *busCamera) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.started {
+		c.bus.logger.Warn().Msg("Never Started")
+		return
+	}
+	c.started = false
+	c.bus.stop()
+}
+
+// Send a packet
+func (c *busCamera) Send(pkt *Packet) error {
+	c.mu.Lock()
+	started := c.started
+	c.mu.Unlock()
+	if !started {
+		return ErrNotStarted
+	}
+	return c.bus.send(pkt)
+}
+
+// SetReceiveQueue for packets received from this camera
+func (c *busCamera) SetReceiveQueue(q chan *Packet) {
+	c.bus.setReceiveQueue(c.address, q)
+}
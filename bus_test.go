@@ -0,0 +1,159 @@
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package visca
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBusInterface(t *testing.T) {
+	var _ Connection = new(busCamera)
+}
+
+func TestBus(t *testing.T) {
+	var incoming chan *Packet
+	port := &MockConnection{}
+	port.On("SetReceiveQueue", mock.Anything).Run(func(args mock.Arguments) {
+		incoming = args.Get(0).(chan *Packet)
+	}).Return().Once()
+	port.On("Start").Return(nil).Once()
+	port.On("Stop").Run(func(mock.Arguments) {
+		close(incoming)
+	}).Return().Once()
+	port.On("Send", mock.Anything).Return(nil)
+
+	bus := NewBus(port)
+	_, err := bus.Camera(9)
+	assert.Equal(t, ErrAddressOutOfBounds, err)
+
+	cam1, err := bus.Camera(1)
+	assert.Nil(t, err)
+	cam2, err := bus.Camera(2)
+	assert.Nil(t, err)
+
+	// Not started yet
+	assert.Equal(t, ErrNotStarted, cam1.Send(&Packet{0, 1, Message{0x01}}))
+
+	q1 := make(chan *Packet)
+	q2 := make(chan *Packet)
+	cam1.SetReceiveQueue(q1)
+	cam1.SetReceiveQueue(q1) // again, for good measure
+	cam2.SetReceiveQueue(q2)
+	assert.Nil(t, cam1.Start())
+	assert.Nil(t, cam1.Start())
+	assert.Nil(t, cam2.Start())
+
+	// Routed by source
+	incoming <- reply(t, 0xA0, 0x41, 0xFF)
+	assert.Equal(t, 2, (<-q2).Source())
+	incoming <- reply(t, 0x90, 0x41, 0xFF)
+	assert.Equal(t, 1, (<-q1).Source())
+
+	// Broadcasts go to everyone
+	incoming <- reply(t, 0x88, 0x30, 0x03, 0xFF)
+	got := []*Packet{}
+	got = append(got, <-q1)
+	got = append(got, <-q2)
+	assert.True(t, got[0].IsBroadcast())
+	assert.True(t, got[1].IsBroadcast())
+
+	// Writes from several cameras at once
+	var wg sync.WaitGroup
+	for _, cam := range []Connection{cam1, cam2} {
+		wg.Add(1)
+		go func(cam Connection) {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				assert.Nil(t, cam.Send(&Packet{0, 1, Message{0x01, 0x04, 0x07, 0x00}}))
+			}
+		}(cam)
+	}
+	wg.Wait()
+	port.AssertNumberOfCalls(t, "Send", 20)
+
+	// The port stays up until the last camera stops
+	cam1.Stop()
+	port.AssertNotCalled(t, "Stop")
+	assert.Equal(t, ErrNotStarted, cam1.Send(&Packet{0, 1, Message{0x01}}))
+	assert.Nil(t, cam2.Send(&Packet{0, 2, Message{0x01}}))
+	cam2.Stop()
+	port.AssertExpectations(t)
+}
+
+func TestBusRestart(t *testing.T) {
+	incoming := make(chan chan *Packet, 2)
+	port := &MockConnection{}
+	port.On("SetReceiveQueue", mock.Anything).Run(func(args mock.Arguments) {
+		incoming <- args.Get(0).(chan *Packet)
+	}).Return()
+	port.On("Start").Return(nil)
+	port.On("Stop").Return()
+
+	bus := NewBus(port)
+	cam, _ := bus.Camera(1)
+	same, _ := bus.Camera(1)
+	assert.Equal(t, cam, same)
+	q := make(chan *Packet)
+	cam.SetReceiveQueue(q)
+
+	// Stopping doesn't close the incoming queue, so the first route goroutine has to quit by itself
+	assert.Nil(t, cam.Start())
+	first := <-incoming
+	cam.Stop()
+	assert.Nil(t, cam.Start())
+	second := <-incoming
+
+	second <- reply(t, 0x90, 0x41, 0xFF)
+	assert.Equal(t, 1, (<-q).Source())
+	select {
+	case first <- reply(t, 0x90, 0x41, 0xFF):
+		t.Error("stopped bus is still routing")
+	default:
+	}
+	cam.Stop()
+}
+
+func TestBusSharedQueue(t *testing.T) {
+	var incoming chan *Packet
+	port := &MockConnection{}
+	port.On("SetReceiveQueue", mock.Anything).Run(func(args mock.Arguments) {
+		incoming = args.Get(0).(chan *Packet)
+	}).Return()
+	port.On("Start").Return(nil)
+
+	bus := NewBus(port)
+	q := make(chan *Packet, 2)
+	for address := 1; address <= 3; address++ {
+		cam, _ := bus.Camera(address)
+		cam.SetReceiveQueue(q)
+		cam.Start()
+	}
+
+	// A broadcast is delivered only once to a shared queue
+	incoming <- reply(t, 0x88, 0x01, 0x00, 0x01, 0xFF)
+	incoming <- reply(t, 0xB0, 0x51, 0xFF)
+	assert.True(t, (<-q).IsBroadcast())
+	assert.Equal(t, 3, (<-q).Source())
+
+	// Packets from cameras that aren't on the bus are dropped
+	incoming <- reply(t, 0xC0, 0x51, 0xFF)
+	incoming <- reply(t, 0x90, 0x51, 0xFF)
+	assert.Equal(t, 1, (<-q).Source())
+}
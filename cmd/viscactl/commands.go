@@ -0,0 +1,465 @@
+//  commands.go - what viscactl can tell a camera to do
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/josh23french/visca"
+	cmds "github.com/josh23french/visca/commands"
+)
+
+// Error constants
+var (
+	ErrUnknownCommand = errors.New("unknown command")
+	ErrWrongArgs      = errors.New("wrong number of arguments")
+	ErrBadArg         = errors.New("bad argument")
+	ErrBadReply       = errors.New("unexpected reply")
+)
+
+// modelNames lists the models -model knows
+func modelNames() string {
+	names := make([]string, len(visca.Models))
+	for i, m := range visca.Models {
+		names[i] = m.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// command is something viscactl can do
+type command struct {
+	name   string  // the words that pick the command, e.g. "zoom in"
+	args   string  // usage for the arguments, if any
+	help   string  // one line about what it does
+	build  builder // makes the message to send
+	decode decoder // decodes an inquiry's Completion; nil for commands
+}
+
+// builder makes the message to send from a command's arguments
+type builder func(args []string, model *visca.Model) (visca.Message, error)
+
+// decoder decodes the Completion of an inquiry
+type decoder func(msg visca.Message, model *visca.Model) (interface{}, error)
+
+// commands are all the commands viscactl knows, in the order they're listed in the usage
+var commands = []command{
+	{name: "power on", help: "turn the camera on", build: fixed(&cmds.PowerOn{})},
+	{name: "power off", help: "put the camera in standby", build: fixed(&cmds.PowerOff{})},
+
+	{name: "preset reset", args: "<n>", help: "clear preset n", build: preset(func() numbered { return &cmds.MemoryReset{} })},
+	{name: "preset set", args: "<n>", help: "save the current position as preset n", build: preset(func() numbered { return &cmds.MemorySet{} })},
+	{name: "preset recall", args: "<n>", help: "move to preset n", build: preset(func() numbered { return &cmds.MemoryRecall{} })},
+
+	{name: "zoom stop", help: "stop zooming", build: fixed(&cmds.ZoomStop{})},
+	{name: "zoom in", args: "[speed 0-7]", help: "zoom in (tele)", build: zoom(&cmds.ZoomTeleStandard{}, func() variable { return &cmds.ZoomTeleVariable{} })},
+	{name: "zoom out", args: "[speed 0-7]", help: "zoom out (wide)", build: zoom(&cmds.ZoomWideStandard{}, func() variable { return &cmds.ZoomWideVariable{} })},
+	{name: "zoom tele", args: "[speed 0-7]", help: "same as zoom in", build: zoom(&cmds.ZoomTeleStandard{}, func() variable { return &cmds.ZoomTeleVariable{} })},
+	{name: "zoom wide", args: "[speed 0-7]", help: "same as zoom out", build: zoom(&cmds.ZoomWideStandard{}, func() variable { return &cmds.ZoomWideVariable{} })},
+	{name: "zoom to", args: "<position>", help: "zoom to a position, 0 (wide) to 16384 (tele) on most cameras", build: direct(cmds.MaxZoomPosition, func() positioned { return &cmds.ZoomDirect{} })},
+	{name: "zoom digital on", help: "zoom past the optical zoom's limit", build: fixed(&cmds.DZoomOn{})},
+	{name: "zoom digital off", help: "optical zoom only", build: fixed(&cmds.DZoomOff{})},
+	{name: "zoom digital combine", help: "drive digital zoom with the optical zoom", build: fixed(&cmds.DZoomCombineMode{})},
+	{name: "zoom digital separate", help: "drive digital zoom on its own", build: fixed(&cmds.DZoomSeparateMode{})},
+	{name: "zoom digital to", args: "<position>", help: "in separate mode, set digital zoom, 0 (x1) to 235 (x12) on most cameras", build: direct(cmds.MaxDZoomPosition, func() positioned { return &cmds.DZoomDirect{} })},
+
+	{name: "focus stop", help: "stop focusing", build: fixed(&cmds.FocusStop{})},
+	{name: "focus far", args: "[speed 0-7]", help: "focus farther away", build: focus(&cmds.FocusFarStandard{}, func() variable { return &cmds.FocusFarVariable{} })},
+	{name: "focus near", args: "[speed 0-7]", help: "focus nearer", build: focus(&cmds.FocusNearStandard{}, func() variable { return &cmds.FocusNearVariable{} })},
+	{name: "focus to", args: "<position>", help: "focus at a position, in manual focus", build: direct(cmds.MaxFocusPosition, func() positioned { return &cmds.FocusDirect{} })},
+	{name: "focus auto", help: "turn autofocus on", build: fixed(&cmds.FocusAuto{})},
+	{name: "focus manual", help: "turn autofocus off", build: fixed(&cmds.FocusManual{})},
+	{name: "focus onepush", help: "focus automatically, once", build: fixed(&cmds.FocusOnePushTrigger{})},
+	{name: "focus infinity", help: "focus at infinity", build: fixed(&cmds.FocusInfinity{})},
+
+	{name: "pantilt stop", help: "stop panning and tilting", build: panTiltDrive(func() driven { return &cmds.PanTiltStop{} })},
+	{name: "pantilt up", args: "[speed]", help: "tilt up", build: panTiltDrive(func() driven { return &cmds.PanTiltUp{} })},
+	{name: "pantilt down", args: "[speed]", help: "tilt down", build: panTiltDrive(func() driven { return &cmds.PanTiltDown{} })},
+	{name: "pantilt left", args: "[speed]", help: "pan left", build: panTiltDrive(func() driven { return &cmds.PanTiltLeft{} })},
+	{name: "pantilt right", args: "[speed]", help: "pan right", build: panTiltDrive(func() driven { return &cmds.PanTiltRight{} })},
+	{name: "pantilt abs", args: "<pan°> <tilt°> [speed]", help: "move to an absolute position", build: panTiltPosition(func() moved { return &cmds.PanTiltAbsolutePosition{} })},
+	{name: "pantilt rel", args: "<pan°> <tilt°> [speed]", help: "move relative to the current position", build: panTiltPosition(func() moved { return &cmds.PanTiltRelativePosition{} })},
+	{name: "pantilt home", help: "move to the home position", build: fixed(&cmds.PanTiltHome{})},
+	{name: "pantilt reset", help: "recalibrate pan and tilt", build: fixed(&cmds.PanTiltReset{})},
+	{name: "pantilt limit set", args: "<upright|downleft> <pan°> <tilt°>", help: "limit how far the camera moves", build: panTiltLimitSet},
+	{name: "pantilt limit clear", args: "<upright|downleft>", help: "remove a limit", build: panTiltLimitClear},
+
+	{name: "inq power", help: "is the camera on?", build: fixed(&cmds.PowerInquiry{}), decode: decodePower},
+	{name: "inq zoom", help: "the zoom position", build: fixed(&cmds.ZoomPositionInquiry{}), decode: decodeZoom},
+	{name: "inq dzoom", help: "is digital zoom on?", build: fixed(&cmds.DZoomModeInquiry{}), decode: decodeDZoom},
+	{name: "inq focus", help: "the focus position", build: fixed(&cmds.FocusPositionInquiry{}), decode: decodeFocus},
+	{name: "inq focusmode", help: "is autofocus on?", build: fixed(&cmds.FocusModeInquiry{}), decode: decodeFocusMode},
+	{name: "inq pantilt", help: "the pan and tilt position", build: fixed(&cmds.PanTiltPositionInquiry{}), decode: decodePanTilt},
+}
+
+// lookup finds the command named by the first words of args, returning it with the rest of the args
+func lookup(args []string) (*command, []string, error) {
+	for i := range commands {
+		words := strings.Fields(commands[i].name)
+		if len(args) < len(words) {
+			continue
+		}
+		match := true
+		for j, w := range words {
+			if !strings.EqualFold(args[j], w) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return &commands[i], args[len(words):], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("%w: %q", ErrUnknownCommand, strings.Join(args, " "))
+}
+
+// message is a command or inquiry from the commands package
+type message interface {
+	Message() visca.Message
+}
+
+// numbered is a Memory command
+type numbered interface {
+	message
+	SetNumber(int) error
+}
+
+// variable is a variable speed Zoom or Focus command
+type variable interface {
+	message
+	SetSpeed(int) error
+}
+
+// positioned is a Zoom, DZoom or Focus Direct command
+type positioned interface {
+	message
+	SetPosition(int) error
+}
+
+// driven is a Pan-tiltDrive command
+type driven interface {
+	message
+	SetPanSpeed(int) error
+	SetTiltSpeed(int) error
+}
+
+// moved is an AbsolutePosition or RelativePosition command
+type moved interface {
+	driven
+	SetModel(*visca.Model) error
+	SetDegrees(pan, tilt float64) error
+}
+
+// fixed builds a command or inquiry that takes no arguments
+func fixed(cmd message) builder {
+	return func(args []string, model *visca.Model) (visca.Message, error) {
+		if len(args) != 0 {
+			return nil, ErrWrongArgs
+		}
+		return cmd.Message(), nil
+	}
+}
+
+// preset builds a Memory (preset) command
+func preset(newCmd func() numbered) builder {
+	return func(args []string, model *visca.Model) (visca.Message, error) {
+		if len(args) != 1 {
+			return nil, ErrWrongArgs
+		}
+		n, err := parseInt(args[0], 0, cmds.MaxMemoryNumber)
+		if err != nil {
+			return nil, err
+		}
+		cmd := newCmd()
+		if err := cmd.SetNumber(n); err != nil {
+			return nil, badArg(err)
+		}
+		return cmd.Message(), nil
+	}
+}
+
+// zoom builds Zoom in a direction, Standard without a speed and Variable with one
+func zoom(standard message, newVariable func() variable) builder {
+	return drive(cmds.MaxZoomSpeed, standard, newVariable)
+}
+
+// focus builds Focus in a direction, Standard without a speed and Variable with one
+func focus(standard message, newVariable func() variable) builder {
+	return drive(cmds.MaxFocusSpeed, standard, newVariable)
+}
+
+// drive builds the Standard command without a speed, and the Variable one with a speed from 0 to max
+func drive(max int, standard message, newVariable func() variable) builder {
+	return func(args []string, model *visca.Model) (visca.Message, error) {
+		switch len(args) {
+		case 0:
+			return standard.Message(), nil
+		case 1:
+			speed, err := parseInt(args[0], 0, max)
+			if err != nil {
+				return nil, err
+			}
+			cmd := newVariable()
+			if err := cmd.SetSpeed(speed); err != nil {
+				return nil, badArg(err)
+			}
+			return cmd.Message(), nil
+		default:
+			return nil, ErrWrongArgs
+		}
+	}
+}
+
+// direct builds a Direct command to a position from 0 to max
+func direct(max int, newCmd func() positioned) builder {
+	return func(args []string, model *visca.Model) (visca.Message, error) {
+		if len(args) != 1 {
+			return nil, ErrWrongArgs
+		}
+		pos, err := parseInt(args[0], 0, max)
+		if err != nil {
+			return nil, err
+		}
+		cmd := newCmd()
+		if err := cmd.SetPosition(pos); err != nil {
+			return nil, badArg(err)
+		}
+		return cmd.Message(), nil
+	}
+}
+
+// panTiltDrive builds Pan-tiltDrive, with an optional speed for both pan and tilt
+func panTiltDrive(newCmd func() driven) builder {
+	return func(args []string, model *visca.Model) (visca.Message, error) {
+		speed := 0x0C
+		switch len(args) {
+		case 0:
+		case 1:
+			var err error
+			speed, err = parseInt(args[0], 1, visca.MaxTiltSpeed)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, ErrWrongArgs
+		}
+		cmd := newCmd()
+		if err := setSpeed(cmd, speed); err != nil {
+			return nil, err
+		}
+		return cmd.Message(), nil
+	}
+}
+
+// panTiltPosition builds AbsolutePosition or RelativePosition from degrees
+func panTiltPosition(newCmd func() moved) builder {
+	return func(args []string, model *visca.Model) (visca.Message, error) {
+		if len(args) != 2 && len(args) != 3 {
+			return nil, ErrWrongArgs
+		}
+		pan, err := parseDegrees(args[0])
+		if err != nil {
+			return nil, err
+		}
+		tilt, err := parseDegrees(args[1])
+		if err != nil {
+			return nil, err
+		}
+		speed := visca.MaxTiltSpeed
+		if len(args) == 3 {
+			speed, err = parseInt(args[2], 1, visca.MaxTiltSpeed)
+			if err != nil {
+				return nil, err
+			}
+		}
+		cmd := newCmd()
+		if err := cmd.SetModel(model); err != nil {
+			return nil, badArg(err)
+		}
+		if err := cmd.SetDegrees(pan, tilt); err != nil {
+			return nil, badArg(err)
+		}
+		if err := setSpeed(cmd, speed); err != nil {
+			return nil, err
+		}
+		return cmd.Message(), nil
+	}
+}
+
+// panTiltLimitSet builds Pan-tiltLimitSet from degrees
+func panTiltLimitSet(args []string, model *visca.Model) (visca.Message, error) {
+	if len(args) != 3 {
+		return nil, ErrWrongArgs
+	}
+	corner, err := parseCorner(args[0])
+	if err != nil {
+		return nil, err
+	}
+	pan, err := parseDegrees(args[1])
+	if err != nil {
+		return nil, err
+	}
+	tilt, err := parseDegrees(args[2])
+	if err != nil {
+		return nil, err
+	}
+	cmd := cmds.PanTiltLimitSet{}
+	if err := cmd.SetCorner(corner); err != nil {
+		return nil, badArg(err)
+	}
+	if err := cmd.SetModel(model); err != nil {
+		return nil, badArg(err)
+	}
+	if err := cmd.SetDegrees(pan, tilt); err != nil {
+		return nil, badArg(err)
+	}
+	return cmd.Message(), nil
+}
+
+// panTiltLimitClear builds Pan-tiltLimitClear
+func panTiltLimitClear(args []string, model *visca.Model) (visca.Message, error) {
+	if len(args) != 1 {
+		return nil, ErrWrongArgs
+	}
+	corner, err := parseCorner(args[0])
+	if err != nil {
+		return nil, err
+	}
+	cmd := cmds.PanTiltLimitClear{}
+	if err := cmd.SetCorner(corner); err != nil {
+		return nil, badArg(err)
+	}
+	cmd.SetModel(model)
+	return cmd.Message(), nil
+}
+
+// setSpeed sets both the pan and tilt speed of a Pan/Tilt command
+func setSpeed(cmd driven, speed int) error {
+	if err := cmd.SetPanSpeed(speed); err != nil {
+		return badArg(err)
+	}
+	if err := cmd.SetTiltSpeed(speed); err != nil {
+		return badArg(err)
+	}
+	return nil
+}
+
+// badArg wraps an error from the commands package as ErrBadArg
+func badArg(err error) error {
+	return fmt.Errorf("%w: %v", ErrBadArg, err)
+}
+
+// parseCorner parses the corner of the Pan-tiltLimitSet box
+func parseCorner(s string) (int, error) {
+	switch strings.ToLower(s) {
+	case "upright":
+		return cmds.LimitUpRight, nil
+	case "downleft":
+		return cmds.LimitDownLeft, nil
+	}
+	return 0, fmt.Errorf("%w: %q should be upright or downleft", ErrBadArg, s)
+}
+
+// parseInt parses a decimal (or 0x hex) integer and checks it's in range
+func parseInt(s string, min, max int) (int, error) {
+	n, err := strconv.ParseInt(s, 0, 32)
+	if err != nil || int(n) < min || int(n) > max {
+		return 0, fmt.Errorf("%w: %q should be %d-%d", ErrBadArg, s, min, max)
+	}
+	return int(n), nil
+}
+
+// parseDegrees parses an angle in degrees
+func parseDegrees(s string) (float64, error) {
+	deg, err := strconv.ParseFloat(strings.TrimSuffix(s, "°"), 64)
+	if err != nil || math.IsNaN(deg) || math.IsInf(deg, 0) {
+		return 0, fmt.Errorf("%w: %q should be an angle in degrees", ErrBadArg, s)
+	}
+	return deg, nil
+}
+
+// decodePower decodes the reply to CAM_PowerInq
+func decodePower(msg visca.Message, _ *visca.Model) (interface{}, error) {
+	inq := cmds.PowerInquiry{}
+	if err := inq.ParseCompletion(msg); err != nil {
+		return nil, ErrBadReply
+	}
+	return map[string]bool{"power": inq.On()}, nil
+}
+
+// decodeZoom decodes the reply to CAM_ZoomPosInq
+func decodeZoom(msg visca.Message, _ *visca.Model) (interface{}, error) {
+	inq := cmds.ZoomPositionInquiry{}
+	if err := inq.ParseCompletion(msg); err != nil {
+		return nil, ErrBadReply
+	}
+	return map[string]int{"zoom": inq.Position()}, nil
+}
+
+// decodeDZoom decodes the reply to CAM_DZoomModeInq
+func decodeDZoom(msg visca.Message, _ *visca.Model) (interface{}, error) {
+	inq := cmds.DZoomModeInquiry{}
+	if err := inq.ParseCompletion(msg); err != nil {
+		return nil, ErrBadReply
+	}
+	return map[string]bool{"dzoom": inq.On()}, nil
+}
+
+// decodeFocus decodes the reply to CAM_FocusPosInq
+func decodeFocus(msg visca.Message, _ *visca.Model) (interface{}, error) {
+	inq := cmds.FocusPositionInquiry{}
+	if err := inq.ParseCompletion(msg); err != nil {
+		return nil, ErrBadReply
+	}
+	return map[string]int{"focus": inq.Position()}, nil
+}
+
+// decodeFocusMode decodes the reply to CAM_FocusModeInq
+func decodeFocusMode(msg visca.Message, _ *visca.Model) (interface{}, error) {
+	inq := cmds.FocusModeInquiry{}
+	if err := inq.ParseCompletion(msg); err != nil {
+		return nil, ErrBadReply
+	}
+	return map[string]bool{"autofocus": inq.Auto()}, nil
+}
+
+// panTilt is the decoded reply to Pan-tiltPosInq
+type panTilt struct {
+	Pan  float64 `json:"pan"`
+	Tilt float64 `json:"tilt"`
+}
+
+// String formats the position in degrees
+func (p panTilt) String() string {
+	return fmt.Sprintf("pan: %.2f° tilt: %.2f°", p.Pan, p.Tilt)
+}
+
+// decodePanTilt decodes the reply to Pan-tiltPosInq into degrees
+func decodePanTilt(msg visca.Message, model *visca.Model) (interface{}, error) {
+	inq := cmds.PanTiltPositionInquiry{}
+	inq.SetModel(model)
+	if err := inq.ParseCompletion(msg); err != nil {
+		return nil, ErrBadReply
+	}
+	return panTilt{
+		Pan:  math.Round(inq.Pan()*100) / 100,
+		Tilt: math.Round(inq.Tilt()*100) / 100,
+	}, nil
+}
@@ -0,0 +1,297 @@
+//  lineedit.go - a small line editor with history and completion
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// errInterrupted is returned by readLine when Ctrl-C is pressed
+var errInterrupted = errors.New("interrupted")
+
+// maxHistory is how many lines of history are kept
+const maxHistory = 500
+
+// Keys
+const (
+	keyCtrlA     = 0x01
+	keyCtrlC     = 0x03
+	keyCtrlD     = 0x04
+	keyCtrlE     = 0x05
+	keyBackspace = 0x08
+	keyTab       = 0x09
+	keyCtrlK     = 0x0B
+	keyCtrlU     = 0x15
+	keyEscape    = 0x1B
+	keyDelete    = 0x7F
+)
+
+// lineEditor reads lines from a terminal in raw mode, with history, tab completion, and the usual keys for
+// moving around the line
+//
+// If the input isn't a terminal, it just reads lines. Output from other goroutines goes through
+// printAbove, so it doesn't mangle the line being edited.
+type lineEditor struct {
+	r        *bufio.Reader
+	w        io.Writer
+	prompt   string
+	raw      bool                         // the input is a terminal in raw mode
+	complete func(prefix string) []string // returns the possible lines starting with prefix
+	history  []string
+	mu       sync.Mutex // protects line, cursor, and reading, and serializes writes to w
+	line     []rune
+	cursor   int
+	reading  bool
+}
+
+// newLineEditor creates a lineEditor; raw says whether r is a terminal that's been put into raw mode
+func newLineEditor(r io.Reader, w io.Writer, prompt string, raw bool) *lineEditor {
+	return &lineEditor{
+		r:      bufio.NewReader(r),
+		w:      w,
+		prompt: prompt,
+		raw:    raw,
+	}
+}
+
+// readLine reads a line, returning io.EOF at the end of the input or on Ctrl-D
+func (e *lineEditor) readLine() (string, error) {
+	if !e.raw {
+		s, err := e.r.ReadString('\n')
+		if err != nil && s == "" {
+			return "", err
+		}
+		return strings.TrimRight(s, "\r\n"), nil
+	}
+
+	e.mu.Lock()
+	e.line = e.line[:0]
+	e.cursor = 0
+	e.reading = true
+	e.redraw()
+	e.mu.Unlock()
+
+	pos := len(e.history) // where we are in the history; len(history) is the new line
+	var edited []rune     // the new line, while we're looking at the history
+
+	for {
+		r, _, err := e.r.ReadRune()
+		if err != nil {
+			e.finish("\n")
+			return "", err
+		}
+		var seq string
+		if r == keyEscape {
+			seq = e.readEscape()
+		}
+
+		e.mu.Lock()
+		switch {
+		case r == '\r' || r == '\n':
+			line := string(e.line)
+			e.mu.Unlock()
+			e.finish("\n")
+			e.addHistory(line)
+			return line, nil
+		case r == keyCtrlC:
+			e.mu.Unlock()
+			e.finish("^C\n")
+			return "", errInterrupted
+		case r == keyCtrlD && len(e.line) == 0:
+			e.mu.Unlock()
+			e.finish("\n")
+			return "", io.EOF
+		case r == keyCtrlD || seq == "[3~":
+			if e.cursor < len(e.line) {
+				e.line = append(e.line[:e.cursor], e.line[e.cursor+1:]...)
+			}
+		case r == keyDelete || r == keyBackspace:
+			if e.cursor > 0 {
+				e.line = append(e.line[:e.cursor-1], e.line[e.cursor:]...)
+				e.cursor--
+			}
+		case r == keyCtrlA || seq == "[H" || seq == "OH":
+			e.cursor = 0
+		case r == keyCtrlE || seq == "[F" || seq == "OF":
+			e.cursor = len(e.line)
+		case r == keyCtrlU:
+			e.line = append(e.line[:0], e.line[e.cursor:]...)
+			e.cursor = 0
+		case r == keyCtrlK:
+			e.line = e.line[:e.cursor]
+		case r == keyTab:
+			e.tab()
+		case seq == "[C":
+			if e.cursor < len(e.line) {
+				e.cursor++
+			}
+		case seq == "[D":
+			if e.cursor > 0 {
+				e.cursor--
+			}
+		case seq == "[A" && pos > 0:
+			if pos == len(e.history) {
+				edited = append(edited[:0], e.line...)
+			}
+			pos--
+			e.setLine(e.history[pos])
+		case seq == "[B" && pos < len(e.history):
+			pos++
+			if pos == len(e.history) {
+				e.setLine(string(edited))
+			} else {
+				e.setLine(e.history[pos])
+			}
+		case r >= 0x20 && r != keyDelete && r != keyEscape:
+			e.line = append(e.line, 0)
+			copy(e.line[e.cursor+1:], e.line[e.cursor:])
+			e.line[e.cursor] = r
+			e.cursor++
+		}
+		e.redraw()
+		e.mu.Unlock()
+	}
+}
+
+// readEscape reads the rest of an escape sequence, like "[A" for the up arrow
+func (e *lineEditor) readEscape() string {
+	var seq []rune
+	for {
+		r, _, err := e.r.ReadRune()
+		if err != nil {
+			return string(seq)
+		}
+		seq = append(seq, r)
+		// sequences end with a letter or ~, except for the [ or O that starts them
+		if len(seq) > 1 && (r == '~' || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z')) {
+			return string(seq)
+		}
+		if len(seq) == 1 && r != '[' && r != 'O' {
+			return string(seq)
+		}
+	}
+}
+
+// finish ends the line being read with s
+func (e *lineEditor) finish(s string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.reading = false
+	fmt.Fprint(e.w, s)
+}
+
+// setLine replaces the line, with the cursor at the end; e.mu must be held
+func (e *lineEditor) setLine(s string) {
+	e.line = append(e.line[:0], []rune(s)...)
+	e.cursor = len(e.line)
+}
+
+// redraw draws the prompt and line, and puts the cursor in place; e.mu must be held
+func (e *lineEditor) redraw() {
+	fmt.Fprintf(e.w, "\r\x1b[K%s%s", e.prompt, string(e.line))
+	if back := len(e.line) - e.cursor; back > 0 {
+		fmt.Fprintf(e.w, "\x1b[%dD", back)
+	}
+}
+
+// tab completes the line up to the cursor as far as it can, listing the choices if there's more than one;
+// e.mu must be held
+func (e *lineEditor) tab() {
+	if e.complete == nil {
+		return
+	}
+	prefix := string(e.line[:e.cursor])
+	rest := string(e.line[e.cursor:])
+	choices := e.complete(prefix)
+	switch len(choices) {
+	case 0:
+		fmt.Fprint(e.w, "\a")
+		return
+	case 1:
+		completed := choices[0] + " "
+		e.setLine(completed + rest)
+		e.cursor = len([]rune(completed))
+		return
+	}
+
+	common := commonPrefix(choices)
+	if len(common) > len(prefix) {
+		e.setLine(common + rest)
+		e.cursor = len([]rune(common))
+		return
+	}
+	fmt.Fprintf(e.w, "\r\x1b[K%s\n", strings.Join(choices, "  "))
+}
+
+// commonPrefix returns the longest prefix shared by all of the strings
+func commonPrefix(s []string) string {
+	prefix := s[0]
+	for _, v := range s[1:] {
+		for !strings.HasPrefix(v, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// addHistory adds a line to the history, unless it's blank or the same as the last one
+func (e *lineEditor) addHistory(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > maxHistory {
+		e.history = e.history[len(e.history)-maxHistory:]
+	}
+}
+
+// printAbove prints a line of output, keeping the line being edited, if any, below it
+func (e *lineEditor) printAbove(s string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.raw || !e.reading {
+		fmt.Fprintln(e.w, s)
+		return
+	}
+	fmt.Fprintf(e.w, "\r\x1b[K%s\n", s)
+	e.redraw()
+}
+
+// loadHistory reads history from r, one line per entry
+func (e *lineEditor) loadHistory(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		e.addHistory(scanner.Text())
+	}
+}
+
+// saveHistory writes the history to w, one line per entry
+func (e *lineEditor) saveHistory(w io.Writer) error {
+	for _, line := range e.history {
+		_, err := fmt.Fprintln(w, line)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineEditorPlain(t *testing.T) {
+	e := newLineEditor(strings.NewReader("zoom in\r\ninq power"), &bytes.Buffer{}, "> ", false)
+
+	line, err := e.readLine()
+	assert.Nil(t, err)
+	assert.Equal(t, "zoom in", line)
+	line, err = e.readLine()
+	assert.Nil(t, err)
+	assert.Equal(t, "inq power", line)
+	_, err = e.readLine()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestLineEditorRaw(t *testing.T) {
+	input := strings.Join([]string{
+		"abc\r",
+		"xz\x1b[Dy\r",          // left arrow, insert
+		"\x1b[A\x1b[A\r",       // up twice
+		"hello\x7f\x7f\x01J\r", // backspace, Ctrl-A
+		"gone\x03",             // Ctrl-C
+		"\x04",                 // Ctrl-D on an empty line
+	}, "")
+	var out bytes.Buffer
+	e := newLineEditor(strings.NewReader(input), &out, "> ", true)
+
+	for _, want := range []string{"abc", "xyz", "abc", "Jhel"} {
+		line, err := e.readLine()
+		assert.Nil(t, err)
+		assert.Equal(t, want, line)
+	}
+	_, err := e.readLine()
+	assert.Equal(t, errInterrupted, err)
+	_, err = e.readLine()
+	assert.Equal(t, io.EOF, err)
+
+	assert.Equal(t, []string{"abc", "xyz", "abc", "Jhel"}, e.history)
+}
+
+func TestLineEditorComplete(t *testing.T) {
+	r := &repl{}
+	e := newLineEditor(strings.NewReader("zo\ti\t\rin\tpo\t\rzoom \t\r"), &bytes.Buffer{}, "> ", true)
+	e.complete = r.completions
+
+	line, err := e.readLine()
+	assert.Nil(t, err)
+	assert.Equal(t, "zoom in ", line)
+	line, err = e.readLine()
+	assert.Nil(t, err)
+	assert.Equal(t, "inq power ", line)
+	line, err = e.readLine()
+	assert.Nil(t, err)
+	assert.Equal(t, "zoom ", line, "more than one choice; nothing to add")
+}
+
+func TestHistoryFile(t *testing.T) {
+	e := newLineEditor(nil, nil, "> ", true)
+	e.loadHistory(strings.NewReader("zoom in\n\nzoom in\ninq zoom\n"))
+	assert.Equal(t, []string{"zoom in", "inq zoom"}, e.history)
+
+	var out bytes.Buffer
+	assert.Nil(t, e.saveHistory(&out))
+	assert.Equal(t, "zoom in\ninq zoom\n", out.String())
+}
@@ -0,0 +1,248 @@
+//  main.go - viscactl, a command-line tool for driving VISCA cameras
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// viscactl sends a single command or inquiry to a VISCA camera and prints the reply
+//
+// Usage
+//
+//  viscactl -c visca-ip://10.0.0.5:52381 preset recall 3
+//  viscactl -c /dev/ttyUSB0 -camera 2 zoom in
+//  viscactl -c visca-ip://10.0.0.5 -json inq pantilt
+//
+// With the repl command, it reads commands, or raw packets in hex, from the terminal and prints every packet
+// that comes back, annotated and decoded:
+//
+//  viscactl -c /dev/ttyUSB0 repl
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/josh23french/visca"
+	"github.com/rs/zerolog"
+)
+
+// Exit codes
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// options are the flags common to everything viscactl does
+type options struct {
+	conn    string
+	camera  int
+	timeout time.Duration
+	json    bool
+	verbose bool
+	model   *visca.Model   // converts pan and tilt to and from degrees, looked up from -model
+	logger  zerolog.Logger // where the library logs, built from -v
+}
+
+// loggerSetter is implemented by the Connections that can log somewhere other than zerolog's global logger
+type loggerSetter interface {
+	SetLogger(zerolog.Logger)
+}
+
+// run runs viscactl with the given arguments, returning the exit code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var opts options
+	var modelName string
+	fs := flag.NewFlagSet("viscactl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.conn, "c", os.Getenv("VISCA_CONNECTION"), "connection string, e.g. /dev/ttyUSB0, tcp://host:port or visca-ip://host (default $VISCA_CONNECTION)")
+	fs.IntVar(&opts.camera, "camera", 1, "camera address, 1-7")
+	fs.DurationVar(&opts.timeout, "timeout", 5*time.Second, "how long to wait for the camera to reply")
+	fs.BoolVar(&opts.json, "json", false, "print the result as JSON")
+	fs.BoolVar(&opts.verbose, "v", false, "log what's going on")
+	fs.StringVar(&modelName, "model", visca.DefaultModel.Name, "camera model, for converting pan and tilt to and from degrees: "+modelNames())
+	fs.Usage = func() {
+		usage(fs)
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	level := zerolog.WarnLevel
+	if opts.verbose {
+		level = zerolog.DebugLevel
+	}
+	opts.logger = zerolog.New(zerolog.ConsoleWriter{Out: stderr}).Level(level).With().Timestamp().Logger()
+
+	if fs.NArg() == 0 || opts.conn == "" {
+		fs.Usage()
+		return exitUsage
+	}
+	model, err := visca.LookupModel(modelName)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	opts.model = model
+	if fs.NArg() == 1 && strings.EqualFold(fs.Arg(0), "repl") {
+		return runREPL(opts, stdin, stdout)
+	}
+	cmd, cmdArgs, err := lookup(fs.Args())
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	msg, err := cmd.build(cmdArgs, opts.model)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v: %v\nusage: viscactl %v %v\n", cmd.name, err, cmd.name, cmd.args)
+		return exitUsage
+	}
+
+	ctrl, err := connect(opts)
+	if err != nil {
+		return report(stdout, stderr, opts, cmd, nil, err)
+	}
+	defer ctrl.Stop()
+	defer ctrl.RemoveCamera(opts.camera)
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+	defer cancel()
+	reply, err := ctrl.Do(ctx, opts.camera, msg)
+	return report(stdout, stderr, opts, cmd, &reply, err)
+}
+
+// connect starts a Controller with the camera on the connection given in the options
+func connect(opts options) (*visca.Controller, error) {
+	conn, err := newConnection(opts)
+	if err != nil {
+		return nil, err
+	}
+	ctrl := visca.NewController()
+	ctrl.SetLogger(opts.logger)
+	err = ctrl.Start()
+	if err != nil {
+		return nil, err
+	}
+	err = ctrl.AddCamera(opts.camera, conn)
+	if err != nil {
+		ctrl.Stop()
+		return nil, err
+	}
+	return ctrl, nil
+}
+
+// newConnection creates the Connection given in the options, logging to the options' logger if it can
+func newConnection(opts options) (visca.Connection, error) {
+	conn, err := visca.NewConnectionFromString(opts.conn)
+	if err != nil {
+		return nil, err
+	}
+	if l, ok := conn.(loggerSetter); ok {
+		l.SetLogger(opts.logger)
+	}
+	return conn, nil
+}
+
+// result is what's printed for -json
+type result struct {
+	Command string      `json:"command"`
+	Camera  int         `json:"camera"`
+	Socket  *uint8      `json:"socket,omitempty"`
+	Reply   string      `json:"reply,omitempty"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// report prints the outcome of a command and returns the exit code
+func report(stdout, stderr io.Writer, opts options, cmd *command, reply *visca.Reply, err error) int {
+	res := result{
+		Command: cmd.name,
+		Camera:  opts.camera,
+	}
+	if reply != nil && reply.Message != nil {
+		res.Socket = &reply.Socket
+		res.Reply = hex.EncodeToString(reply.Message)
+		if err == nil && cmd.decode != nil {
+			res.Result, err = cmd.decode(reply.Message, opts.model)
+		}
+	}
+	if err != nil {
+		res.Error = err.Error()
+	}
+
+	if opts.json {
+		enc := json.NewEncoder(stdout)
+		enc.Encode(res)
+	} else if err != nil {
+		fmt.Fprintf(stderr, "%v: %v\n", cmd.name, err)
+	} else if res.Result != nil {
+		fmt.Fprintln(stdout, formatResult(res.Result))
+	} else {
+		fmt.Fprintf(stdout, "ok (socket %d)\n", reply.Socket)
+	}
+
+	if err != nil {
+		return exitError
+	}
+	return exitOK
+}
+
+// formatResult formats a decoded inquiry for people
+func formatResult(v interface{}) string {
+	switch r := v.(type) {
+	case map[string]bool:
+		parts := make([]string, 0, len(r))
+		for k, on := range r {
+			state := "off"
+			if on {
+				state = "on"
+			}
+			parts = append(parts, k+": "+state)
+		}
+		return strings.Join(parts, " ")
+	case map[string]int:
+		parts := make([]string, 0, len(r))
+		for k, n := range r {
+			parts = append(parts, fmt.Sprintf("%v: %d", k, n))
+		}
+		return strings.Join(parts, " ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// usage prints the flags and commands
+func usage(fs *flag.FlagSet) {
+	w := fs.Output()
+	fmt.Fprintln(w, "usage: viscactl -c <connection> [flags] <command> [args]")
+	fmt.Fprintln(w, "       viscactl -c <connection> [flags] repl")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "flags:")
+	fs.PrintDefaults()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-40s %v\n", strings.TrimSpace(cmd.name+" "+cmd.args), cmd.help)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/josh23french/visca"
+	"github.com/josh23french/visca/simulator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuild(t *testing.T) {
+	var tests = []struct {
+		args []string
+		want visca.Message
+	}{
+		{[]string{"power", "on"}, visca.Message{0x01, 0x04, 0x00, 0x02}},
+		{[]string{"preset", "recall", "3"}, visca.Message{0x01, 0x04, 0x3F, 0x02, 0x03}},
+		{[]string{"zoom", "in"}, visca.Message{0x01, 0x04, 0x07, 0x02}},
+		{[]string{"zoom", "out", "5"}, visca.Message{0x01, 0x04, 0x07, 0x35}},
+		{[]string{"zoom", "to", "0x4000"}, visca.Message{0x01, 0x04, 0x47, 0x04, 0x00, 0x00, 0x00}},
+		{[]string{"focus", "near", "3"}, visca.Message{0x01, 0x04, 0x08, 0x33}},
+		{[]string{"focus", "to", "0x1000"}, visca.Message{0x01, 0x04, 0x48, 0x01, 0x00, 0x00, 0x00}},
+		{[]string{"focus", "manual"}, visca.Message{0x01, 0x04, 0x38, 0x03}},
+		{[]string{"zoom", "digital", "separate"}, visca.Message{0x01, 0x04, 0x36, 0x01}},
+		{[]string{"zoom", "digital", "to", "235"}, visca.Message{0x01, 0x04, 0x46, 0x00, 0x00, 0x0E, 0x0B}},
+		{[]string{"PanTilt", "Left"}, visca.Message{0x01, 0x06, 0x01, 0x0C, 0x0C, 0x01, 0x03}},
+		{[]string{"pantilt", "abs", "-170", "90"}, visca.Message{0x01, 0x06, 0x02, 0x17, 0x17, 0x0F, 0x06, 0x03, 0x05, 0x09, 0x05, 0x02, 0x0E, 0x0F}},
+		{[]string{"pantilt", "limit", "set", "UpRight", "90", "45"}, visca.Message{0x01, 0x06, 0x07, 0x00, 0x01, 0x00, 0x05, 0x02, 0x0E, 0x0F, 0x02, 0x09, 0x07, 0x08}},
+		{[]string{"pantilt", "limit", "clear", "downleft"}, visca.Message{0x01, 0x06, 0x07, 0x01, 0x00, 0x07, 0x0F, 0x0F, 0x0F, 0x0F, 0x07, 0x0F, 0x0F, 0x0F}},
+		{[]string{"inq", "zoom"}, visca.Message{0x09, 0x04, 0x47}},
+	}
+
+	for _, tt := range tests {
+		cmd, args, err := lookup(tt.args)
+		assert.Nil(t, err)
+		msg, err := cmd.build(args, visca.DefaultModel)
+		assert.Nil(t, err)
+		assert.Equal(t, tt.want, msg, "%v", tt.args)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	_, _, err := lookup([]string{"make", "coffee"})
+	assert.NotNil(t, err)
+
+	for _, args := range [][]string{
+		{"power", "on", "now"},
+		{"preset", "recall"},
+		{"preset", "recall", "256"},
+		{"zoom", "in", "8"},
+		{"zoom", "digital", "to", "256"},
+		{"pantilt", "abs", "north", "0"},
+		{"pantilt", "abs", "171", "0"},
+		{"pantilt", "rel", "0", "121"},
+		{"pantilt", "limit", "set", "up", "0", "0"},
+		{"pantilt", "limit", "clear"},
+	} {
+		cmd, rest, err := lookup(args)
+		assert.Nil(t, err)
+		_, err = cmd.build(rest, visca.DefaultModel)
+		assert.NotNil(t, err, "%v", args)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	cmd, _, _ := lookup([]string{"inq", "pantilt"})
+	v, err := cmd.decode(visca.Message{0x50, 0x0F, 0x06, 0x03, 0x05, 0x09, 0x05, 0x02, 0x0E, 0x0F}, visca.DefaultModel)
+	assert.Nil(t, err)
+	assert.Equal(t, panTilt{Pan: -170, Tilt: 90}, v)
+
+	cmd, _, _ = lookup([]string{"inq", "focus"})
+	v, err = cmd.decode(visca.Message{0x50, 0x0C, 0x00, 0x00, 0x00}, visca.DefaultModel)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"focus": 0xC000}, v)
+
+	_, err = cmd.decode(visca.Message{0x50, 0x02}, visca.DefaultModel)
+	assert.Equal(t, ErrBadReply, err)
+}
+
+func TestModel(t *testing.T) {
+	cmd, args, _ := lookup([]string{"pantilt", "abs", "-100", "25", "5"})
+	msg, err := cmd.build(args, visca.ModelEVID100)
+	assert.Nil(t, err)
+	assert.Equal(t, visca.Message{0x01, 0x06, 0x02, 0x05, 0x05, 0x0F, 0x0A, 0x06, 0x00, 0x00, 0x01, 0x06, 0x08}, msg)
+
+	cmd, args, _ = lookup([]string{"pantilt", "abs", "-170", "0"})
+	_, err = cmd.build(args, visca.ModelEVID100)
+	assert.True(t, errors.Is(err, ErrBadArg))
+
+	cmd, _, _ = lookup([]string{"inq", "pantilt"})
+	v, err := cmd.decode(visca.Message{0x50, 0x0F, 0x0A, 0x06, 0x00, 0x00, 0x01, 0x06, 0x08}, visca.ModelEVID100)
+	assert.Nil(t, err)
+	assert.Equal(t, panTilt{Pan: -100, Tilt: 25}, v)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-c", "tcp://127.0.0.1:1", "-model", "SRG-999", "inq", "power"}, nil, &stdout, &stderr)
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr.String(), "unknown camera model")
+}
+
+func TestRun(t *testing.T) {
+	cam := simulator.NewCamera()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+	go cam.ServeListener(ln)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-c", ln.Addr().String(), "pantilt", "abs", "10", "-5"}, nil, &stdout, &stderr)
+	assert.Equal(t, exitOK, code, stderr.String())
+	assert.Equal(t, "ok (socket 1)\n", stdout.String())
+
+	stdout.Reset()
+	code = run([]string{"-c", ln.Addr().String(), "-json", "inq", "pantilt"}, nil, &stdout, &stderr)
+	assert.Equal(t, exitOK, code, stderr.String())
+	var res struct {
+		Result panTilt
+		Reply  string
+	}
+	assert.Nil(t, json.Unmarshal(stdout.Bytes(), &res))
+	assert.Equal(t, panTilt{Pan: 10, Tilt: -5}, res.Result)
+	assert.Equal(t, "5000000903070f0b0604", res.Reply)
+
+	stdout.Reset()
+	code = run([]string{"-c", ln.Addr().String(), "inq", "power"}, nil, &stdout, &stderr)
+	assert.Equal(t, exitOK, code, stderr.String())
+	assert.Equal(t, "power: on\n", stdout.String())
+
+	code = run([]string{"-c", ln.Addr().String(), "zoom", "sideways"}, nil, &stdout, &stderr)
+	assert.Equal(t, exitUsage, code)
+	code = run([]string{"zoom", "in"}, nil, &stdout, &stderr)
+	assert.Equal(t, exitUsage, code)
+}
@@ -0,0 +1,399 @@
+//  repl.go - viscactl's interactive mode
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/josh23french/visca"
+	"github.com/josh23french/visca/dissect"
+)
+
+// replCommands are the REPL's own commands, on top of the ones in commands
+var replCommands = []string{"help", "camera", "quit"}
+
+// historyFile is where the REPL's history is kept, in the home directory
+const historyFile = ".viscactl_history"
+
+// repl is an interactive session with the camera(s) on a Connection
+//
+// It talks to the Connection directly rather than through a Controller, so every packet that arrives is
+// shown, even ones nothing was waiting for.
+type repl struct {
+	conn    visca.Connection
+	editor  *lineEditor
+	camera  int
+	model   *visca.Model
+	mu      sync.Mutex       // protects pairers
+	pairers [8]*visca.Pairer // what's been sent to each camera and not answered yet, as *outstanding
+}
+
+// outstanding is something that was sent and hasn't been answered yet
+type outstanding struct {
+	name   string // the command's name, or the raw bytes
+	msg    visca.Message
+	decode func(visca.Message, *visca.Model) (interface{}, error)
+}
+
+// runREPL runs the REPL until the input ends or it's told to quit
+func runREPL(opts options, stdin io.Reader, stdout io.Writer) int {
+	conn, err := newConnection(opts)
+	if err != nil {
+		fmt.Fprintln(stdout, err)
+		return exitError
+	}
+	queue := make(chan *visca.Packet)
+	conn.SetReceiveQueue(queue)
+	err = conn.Start()
+	if err != nil {
+		fmt.Fprintln(stdout, err)
+		return exitError
+	}
+	defer conn.Stop()
+
+	raw := false
+	if f, ok := stdin.(*os.File); ok {
+		restore, err := makeRaw(int(f.Fd()))
+		if err == nil {
+			defer restore()
+			raw = true
+		}
+	}
+
+	r := &repl{
+		conn:   conn,
+		editor: newLineEditor(stdin, stdout, "visca> ", raw),
+		camera: opts.camera,
+		model:  opts.model,
+	}
+	r.editor.complete = r.completions
+	if raw {
+		r.loadHistory()
+		defer r.saveHistory()
+		r.editor.printAbove(fmt.Sprintf("connected to %v; type help for the commands, Tab to complete them", opts.conn))
+	}
+
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go r.receive(queue, quit, done)
+
+	for {
+		line, err := r.editor.readLine()
+		if err == errInterrupted {
+			continue
+		}
+		if err != nil || !r.exec(strings.TrimSpace(line)) {
+			break
+		}
+	}
+	r.wait(opts.timeout)
+	close(quit)
+	<-done
+	return exitOK
+}
+
+// exec runs one line of input; it returns false if it's time to quit
+func (r *repl) exec(line string) bool {
+	if line == "" || strings.HasPrefix(line, "#") {
+		return true
+	}
+	if b, ok := parseHex(line); ok {
+		pkt, err := visca.PacketFromBytes(b)
+		if err != nil {
+			r.editor.printAbove(fmt.Sprintf("%v: % X", err, b))
+			return true
+		}
+		r.send(pkt, r.describe(pkt.Message, fmt.Sprintf("% X", b)))
+		return true
+	}
+
+	args := strings.Fields(line)
+	switch strings.ToLower(args[0]) {
+	case "quit", "exit":
+		return false
+	case "help":
+		r.help()
+		return true
+	case "camera":
+		if len(args) != 2 {
+			r.editor.printAbove(fmt.Sprintf("talking to camera %d", r.camera))
+			return true
+		}
+		n, err := parseInt(args[1], 1, 7)
+		if err != nil {
+			r.editor.printAbove(err.Error())
+			return true
+		}
+		r.camera = n
+		return true
+	}
+
+	cmd, cmdArgs, err := lookup(args)
+	if err != nil {
+		r.editor.printAbove(err.Error())
+		return true
+	}
+	msg, err := cmd.build(cmdArgs, r.model)
+	if err != nil {
+		r.editor.printAbove(fmt.Sprintf("%v: %v (usage: %v %v)", cmd.name, err, cmd.name, cmd.args))
+		return true
+	}
+	pkt, err := visca.NewPacket(0, r.camera, msg)
+	if err != nil {
+		r.editor.printAbove(err.Error())
+		return true
+	}
+	r.send(pkt, &outstanding{
+		name:   cmd.name,
+		msg:    msg,
+		decode: cmd.decode,
+	})
+	return true
+}
+
+// describe works out what a raw message is, so its reply can be decoded too
+func (r *repl) describe(msg visca.Message, name string) *outstanding {
+	o := &outstanding{
+		name: name,
+		msg:  msg,
+	}
+	for _, cmd := range commands {
+		if cmd.decode == nil {
+			continue
+		}
+		if known, err := cmd.build(nil, r.model); err == nil && bytes.Equal(known, msg) {
+			o.name = cmd.name
+			o.decode = cmd.decode
+		}
+	}
+	return o
+}
+
+// send sends a packet, keeping track of commands and inquiries so their replies can be annotated
+func (r *repl) send(pkt *visca.Packet, o *outstanding) {
+	typ := pkt.Message.Type()
+	tracked := typ == visca.MsgCommand || typ == visca.MsgInquiry
+	tracked = tracked && !pkt.IsBroadcast()
+	if tracked {
+		r.mu.Lock()
+		r.pairer(pkt.Destination()).Add(pkt.Message, o)
+		r.mu.Unlock()
+	}
+
+	r.editor.printAbove(fmt.Sprintf("-> % X  %v", pkt.Bytes(), annotate(pkt)))
+	err := r.conn.Send(pkt)
+	if err != nil {
+		r.editor.printAbove(fmt.Sprintf("error sending: %v", err))
+		if tracked {
+			r.mu.Lock()
+			r.pairer(pkt.Destination()).Remove(o)
+			r.mu.Unlock()
+		}
+	}
+}
+
+// pairer returns the Pairer for the given camera; r.mu must be held
+func (r *repl) pairer(camera int) *visca.Pairer {
+	if r.pairers[camera] == nil {
+		r.pairers[camera] = visca.NewPairer()
+	}
+	return r.pairers[camera]
+}
+
+// receive prints the packets that arrive until quit is closed; it closes done when it returns
+func (r *repl) receive(queue chan *visca.Packet, quit, done chan struct{}) {
+	defer close(done)
+	for {
+		select {
+		case pkt := <-queue:
+			r.editor.printAbove(fmt.Sprintf("<- % X  %v", pkt.Bytes(), r.annotateReply(pkt)))
+		case <-quit:
+			return
+		}
+	}
+}
+
+// annotate describes a packet: its type, addresses, socket and category
+func annotate(pkt *visca.Packet) string {
+	msg := pkt.Message
+	typ := msg.Type()
+	parts := []string{strings.TrimPrefix(typ.String(), "Msg")}
+	if pkt.IsBroadcast() {
+		parts = append(parts, fmt.Sprintf("from %d to all", pkt.Source()))
+	} else {
+		parts = append(parts, fmt.Sprintf("from %d to %d", pkt.Source(), pkt.Destination()))
+	}
+	switch typ {
+	case visca.MsgCommand, visca.MsgInquiry:
+		parts = append(parts, "category "+strings.TrimPrefix(msg.Category().String(), "Cat"))
+		if d := dissect.Message(msg); d.Known {
+			parts[len(parts)-1] += ": " + d.String()
+		}
+	case visca.MsgCancel, visca.MsgACK, visca.MsgCompletion, visca.MsgError:
+		parts = append(parts, fmt.Sprintf("socket %d", msg.Socket()))
+	}
+	if typ == visca.MsgError {
+		parts = append(parts, msg.Error().Error())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// annotateReply describes a received packet, pairing it with what it answers and decoding it if possible
+func (r *repl) annotateReply(pkt *visca.Packet) string {
+	s := annotate(pkt)
+	r.mu.Lock()
+	o, ok := r.pairer(pkt.Source()).Pair(pkt.Message).(*outstanding)
+	r.mu.Unlock()
+	if !ok {
+		return s
+	}
+	s += fmt.Sprintf(" (%v, category %v)", o.name, strings.TrimPrefix(o.msg.Category().String(), "Cat"))
+	if pkt.Message.Type() != visca.MsgCompletion || o.msg.Type() != visca.MsgInquiry {
+		return s
+	}
+	if o.decode != nil {
+		v, err := o.decode(pkt.Message, r.model)
+		if err != nil {
+			s += ": " + err.Error()
+		} else {
+			s += ": " + formatResult(v)
+		}
+		return s
+	}
+	// an inquiry viscactl doesn't have a command for, but the dissector might know
+	if d := dissect.Reply(pkt.Message, o.msg); d.Known && d.Request != "" {
+		parts := make([]string, 0, len(d.Fields))
+		for _, f := range d.Fields[1:] {
+			parts = append(parts, f.Name+": "+f.Text)
+		}
+		s += ": " + strings.Join(parts, " ")
+	}
+	return s
+}
+
+// wait gives the camera up to timeout to answer what's outstanding
+func (r *repl) wait(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		idle := true
+		r.mu.Lock()
+		for _, p := range r.pairers {
+			if p != nil && p.Len() > 0 {
+				idle = false
+			}
+		}
+		r.mu.Unlock()
+		if idle {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// completions returns the commands that start with prefix
+func (r *repl) completions(prefix string) []string {
+	prefix = strings.ToLower(strings.Join(strings.Fields(prefix), " ")) + trailingSpace(prefix)
+	var choices []string
+	for _, name := range replCommands {
+		if strings.HasPrefix(name, prefix) {
+			choices = append(choices, name)
+		}
+	}
+	for _, cmd := range commands {
+		if strings.HasPrefix(cmd.name, prefix) {
+			choices = append(choices, cmd.name)
+		}
+	}
+	sort.Strings(choices)
+	return choices
+}
+
+// trailingSpace returns " " if s ends with whitespace, so "zoom " only completes the zoom commands
+func trailingSpace(s string) string {
+	if strings.TrimRight(s, " \t") != s && strings.TrimSpace(s) != "" {
+		return " "
+	}
+	return ""
+}
+
+// help lists the commands
+func (r *repl) help() {
+	r.editor.printAbove("Type a command, or the bytes of a packet in hex, e.g. 81 09 04 00 FF")
+	r.editor.printAbove("")
+	for _, cmd := range commands {
+		r.editor.printAbove(fmt.Sprintf("  %-40s %v", strings.TrimSpace(cmd.name+" "+cmd.args), cmd.help))
+	}
+	r.editor.printAbove(fmt.Sprintf("  %-40s %v", "camera [n]", "show or change the camera (1-7) commands go to"))
+	r.editor.printAbove(fmt.Sprintf("  %-40s %v", "help", "show this"))
+	r.editor.printAbove(fmt.Sprintf("  %-40s %v", "quit", "leave"))
+}
+
+// loadHistory loads the history saved by an earlier session, if there is one
+func (r *repl) loadHistory() {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return
+	}
+	f, err := os.Open(filepath.Join(home, historyFile))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	r.editor.loadHistory(f)
+}
+
+// saveHistory saves the history for the next session
+func (r *repl) saveHistory() {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return
+	}
+	f, err := os.Create(filepath.Join(home, historyFile))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	r.editor.saveHistory(f)
+}
+
+// parseHex parses a line of hex bytes, either separated ("81 09 04 00 FF") or not ("81090400FF")
+func parseHex(line string) ([]byte, bool) {
+	s := strings.Join(strings.Fields(line), "")
+	s = strings.ReplaceAll(strings.ReplaceAll(s, "0x", ""), ",", "")
+	if len(s) < 2 || len(s)%2 != 0 {
+		return nil, false
+	}
+	for _, field := range strings.Fields(line) {
+		if len(strings.TrimPrefix(strings.Trim(field, ","), "0x"))%2 != 0 {
+			return nil, false
+		}
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
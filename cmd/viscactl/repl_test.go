@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/josh23french/visca"
+	"github.com/josh23french/visca/simulator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHex(t *testing.T) {
+	b, ok := parseHex("81 09 04 00 FF")
+	assert.True(t, ok)
+	assert.Equal(t, []byte{0x81, 0x09, 0x04, 0x00, 0xFF}, b)
+	b, ok = parseHex("8109 0400ff")
+	assert.True(t, ok)
+	assert.Equal(t, []byte{0x81, 0x09, 0x04, 0x00, 0xFF}, b)
+	b, ok = parseHex("0x81, 0x09, 0x04, 0x00, 0xFF")
+	assert.True(t, ok)
+	assert.Equal(t, []byte{0x81, 0x09, 0x04, 0x00, 0xFF}, b)
+
+	for _, line := range []string{"zoom in", "8 1 09", "help", "abc"} {
+		_, ok = parseHex(line)
+		assert.False(t, ok, line)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	pkt, _ := visca.PacketFromBytes([]byte{0x81, 0x01, 0x04, 0x07, 0x02, 0xFF})
+	assert.Equal(t, "Command, from 0 to 1, category Camera1: CAM_Zoom Tele(Standard)", annotate(pkt))
+	pkt, _ = visca.PacketFromBytes([]byte{0x90, 0x62, 0x41, 0xFF})
+	assert.Equal(t, "Error, from 1 to 0, socket 2, command not executable", annotate(pkt))
+	pkt, _ = visca.PacketFromBytes([]byte{0x88, 0x30, 0x02, 0xFF})
+	assert.Equal(t, "AddressSet, from 0 to all", annotate(pkt))
+}
+
+func TestAnnotateReply(t *testing.T) {
+	// viscactl has no command for CAM_AEModeInq, so the dissector decodes the reply
+	r := &repl{model: visca.DefaultModel}
+	r.pairer(1).Add(visca.Message{0x09, 0x04, 0x39}, r.describe(visca.Message{0x09, 0x04, 0x39}, "81 09 04 39 FF"))
+	pkt, _ := visca.PacketFromBytes([]byte{0x90, 0x50, 0x03, 0xFF})
+	assert.Equal(t, "Completion, from 1 to 0, socket 0 (81 09 04 39 FF, category Camera1): mode: Manual", r.annotateReply(pkt))
+}
+
+func TestREPL(t *testing.T) {
+	cam := simulator.NewCamera()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+	go cam.ServeListener(ln)
+
+	input := strings.Join([]string{
+		"inq power",
+		"81 09 04 47 FF",
+		"81 09 04 38 FF",
+		"zoom tele 5",
+		"zoom sideways",
+		"81 01 7E FF",
+		"camera 2",
+		"inq power",
+		"quit",
+	}, "\n")
+	var stdout bytes.Buffer
+	code := run([]string{"-c", ln.Addr().String(), "-timeout", "200ms", "repl"}, strings.NewReader(input), &stdout, &bytes.Buffer{})
+	assert.Equal(t, exitOK, code)
+
+	out := stdout.String()
+	for _, want := range []string{
+		"-> 81 09 04 00 FF  Inquiry, from 0 to 1, category Camera1: CAM_PowerInq\n",
+		"<- 90 50 02 FF  Completion, from 1 to 0, socket 0 (inq power, category Camera1): power: on\n",
+		"<- 90 50 00 00 00 00 FF  Completion, from 1 to 0, socket 0 (inq zoom, category Camera1): zoom: 0\n",
+		"<- 90 50 02 FF  Completion, from 1 to 0, socket 0 (inq focusmode, category Camera1): autofocus: on\n",
+		"-> 81 01 04 07 25 FF  Command, from 0 to 1, category Camera1: CAM_Zoom Tele(Variable) speed=5\n",
+		"<- 90 41 FF  ACK, from 1 to 0, socket 1 (zoom tele, category Camera1)\n",
+		"<- 90 51 FF  Completion, from 1 to 0, socket 1 (zoom tele, category Camera1)\n",
+		"unknown command: \"zoom sideways\"\n",
+		"<- 90 60 02 FF  Error, from 1 to 0, socket 0, syntax error (81 01 7E FF, category Display)\n",
+		"-> 82 09 04 00 FF  Inquiry, from 0 to 2, category Camera1: CAM_PowerInq\n",
+	} {
+		assert.Contains(t, out, want)
+	}
+	// camera 2 isn't there, so only camera 1's answer is decoded
+	assert.Equal(t, 1, strings.Count(out, "(inq power"))
+
+	assert.True(t, cam.State().Zoom > 0)
+}
@@ -0,0 +1,216 @@
+//  main.go - viscasniff, logs the VISCA traffic between a controller and a camera
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// viscasniff sits between a VISCA controller and a camera, passing everything through and logging each packet,
+// decoded, with the time and which way it was going
+//
+// Usage
+//
+//  viscasniff -listen :5678 -camera 10.0.0.5:5678
+//  viscasniff -pty -link /tmp/ttyVISCA -camera /dev/ttyUSB0
+//  viscasniff -controller /dev/ttyUSB1 -camera 'serial:///dev/ttyUSB0?baud=38400'
+//
+// With -listen, point the controller at viscasniff's address instead of the camera's. With -pty, viscasniff
+// makes a pseudo-terminal and prints its path; point the controller software at that (or at -link) instead of
+// the camera's serial port. With -controller, the controller is plugged into a serial port of its own.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/josh23french/visca"
+	"github.com/josh23french/visca/sniffer"
+)
+
+// Exit codes
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// Error constants
+var (
+	errNoPTY     = errors.New("pseudo-terminals aren't supported on this platform") // from openPTY
+	errNotStream = errors.New("can't pass bytes through this kind of connection")
+)
+
+func main() {
+	stop := make(chan struct{})
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		close(stop)
+	}()
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr, stop))
+}
+
+// options are viscasniff's flags
+type options struct {
+	listen     string
+	pty        bool
+	link       string
+	controller string
+	camera     string
+	json       bool
+}
+
+// run runs viscasniff with the given arguments until stop is closed or the connections end, returning the exit
+// code
+func run(args []string, stdout, stderr io.Writer, stop <-chan struct{}) int {
+	var opts options
+	fs := flag.NewFlagSet("viscasniff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.listen, "listen", "", "accept controllers over TCP on this address, e.g. :5678")
+	fs.BoolVar(&opts.pty, "pty", false, "make a pseudo-terminal for the controller software to use as its serial port")
+	fs.StringVar(&opts.link, "link", "", "with -pty, a symlink to make to the pseudo-terminal")
+	fs.StringVar(&opts.controller, "controller", "", "the serial port the controller is on, e.g. /dev/ttyUSB1 or serial:///dev/ttyUSB1?baud=38400")
+	fs.StringVar(&opts.camera, "camera", "", "the camera's connection string, as for viscactl: host:port, tcp://host:port, a serial port, or serial:///dev/ttyUSB0?baud=38400")
+	fs.BoolVar(&opts.json, "json", false, "log JSON, one packet per line")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: viscasniff (-listen <address> | -pty [-link <path>] | -controller <port>) -camera <camera> [-json]")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	modes := 0
+	for _, set := range []bool{opts.listen != "", opts.pty, opts.controller != ""} {
+		if set {
+			modes++
+		}
+	}
+	if modes != 1 || opts.camera == "" || fs.NArg() != 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	logger := sniffer.NewLogger(stdout, opts.json)
+	var err error
+	switch {
+	case opts.listen != "":
+		var ln net.Listener
+		ln, err = net.Listen("tcp", opts.listen)
+		if err != nil {
+			break
+		}
+		fmt.Fprintf(stderr, "listening for controllers on %v\n", ln.Addr())
+		go func() {
+			<-stop
+			ln.Close()
+		}()
+		serveTCP(ln, opts.camera, logger, stderr)
+	case opts.pty:
+		var p *pty
+		p, err = openPTY()
+		if err != nil {
+			break
+		}
+		fmt.Fprintf(stderr, "controller port: %v\n", p.path)
+		if opts.link != "" {
+			os.Remove(opts.link)
+			err = os.Symlink(p.path, opts.link)
+			if err != nil {
+				p.Close()
+				break
+			}
+			defer os.Remove(opts.link)
+		}
+		err = proxy(p, opts.camera, logger, stop)
+	default:
+		var port io.ReadWriteCloser
+		port, err = open(opts.controller)
+		if err != nil {
+			break
+		}
+		err = proxy(port, opts.camera, logger, stop)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	return exitOK
+}
+
+// serveTCP proxies each controller that connects to ln to a connection of its own to the camera, until ln is
+// closed
+func serveTCP(ln net.Listener, camera string, logger *sniffer.Logger, stderr io.Writer) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fmt.Fprintf(stderr, "controller %v connected\n", conn.RemoteAddr())
+			stop := make(chan struct{})
+			defer close(stop)
+			err := proxy(conn, camera, logger, stop)
+			if err != nil {
+				fmt.Fprintf(stderr, "controller %v: %v\n", conn.RemoteAddr(), err)
+			}
+			fmt.Fprintf(stderr, "controller %v disconnected\n", conn.RemoteAddr())
+		}()
+	}
+}
+
+// proxy connects to the camera and proxies the controller to it until either side closes or stop is closed
+func proxy(controller io.ReadWriteCloser, camera string, logger *sniffer.Logger, stop <-chan struct{}) error {
+	cam, err := open(camera)
+	if err != nil {
+		controller.Close()
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+			controller.Close()
+			cam.Close()
+		case <-done:
+		}
+	}()
+	return sniffer.Proxy(controller, cam, logger.Log)
+}
+
+// open opens a stream to the camera or controller at the given connection string, which is resolved the same
+// way as viscactl's, by visca.NewConnectionFromString
+func open(s string) (io.ReadWriteCloser, error) {
+	conn, err := visca.NewConnectionFromString(s)
+	if err != nil {
+		return nil, err
+	}
+	d, ok := conn.(visca.Dialer)
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", errNotStream, s)
+	}
+	return d.Dial()
+}
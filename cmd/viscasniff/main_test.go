@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/josh23french/visca"
+	"github.com/josh23french/visca/simulator"
+	"github.com/josh23french/visca/sniffer"
+	"github.com/stretchr/testify/assert"
+)
+
+// syncBuffer is a bytes.Buffer that's safe to write to from several goroutines
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestOpen(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+
+	// Resolved like any other connection string
+	for _, s := range []string{ln.Addr().String(), "tcp://" + ln.Addr().String()} {
+		stream, err := open(s)
+		assert.Nil(t, err, s)
+		if stream != nil {
+			stream.Close()
+		}
+	}
+
+	_, err = open("visca-ip://10.0.0.5")
+	assert.True(t, errors.Is(err, errNotStream))
+	_, err = open("bogus://10.0.0.5")
+	assert.True(t, errors.Is(err, visca.ErrUnknownScheme))
+}
+
+func TestServeTCP(t *testing.T) {
+	camLn, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer camLn.Close()
+	go simulator.NewCamera().ServeListener(camLn)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	var stdout, stderr syncBuffer
+	done := make(chan struct{})
+	go func() {
+		serveTCP(ln, "tcp://"+camLn.Addr().String(), sniffer.NewLogger(&stdout, false), &stderr)
+		close(done)
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	assert.Nil(t, err)
+	_, err = conn.Write([]byte{0x81, 0x09, 0x04, 0x00, 0xFF})
+	assert.Nil(t, err)
+	reply := make([]byte, 4)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = io.ReadFull(conn, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x90, 0x50, 0x02, 0xFF}, reply)
+	conn.Close()
+
+	ln.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("serveTCP didn't return when the listener was closed")
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	assert.Equal(t, 2, len(lines))
+	assert.Contains(t, lines[0], "controller->camera  81 09 04 00 FF")
+	assert.Contains(t, lines[0], "0->1 CAM_PowerInq")
+	assert.Contains(t, lines[1], "1->0 Completion CAM_PowerInq socket=0 power=On")
+	assert.Contains(t, stderr.String(), "connected")
+}
+
+func TestRunUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	for _, args := range [][]string{
+		{},
+		{"-camera", "10.0.0.5:5678"},
+		{"-listen", ":5678"},
+		{"-listen", ":5678", "-pty", "-camera", "10.0.0.5:5678"},
+	} {
+		assert.Equal(t, exitUsage, run(args, &stdout, &stderr, nil), "%v", args)
+	}
+}
+
+func TestRunCameraUnreachable(t *testing.T) {
+	// nothing's listening on the port once it's closed
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+
+	controller, _ := net.Pipe()
+	err = proxy(controller, addr, sniffer.NewLogger(ioutil.Discard, false), nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,100 @@
+//  pty_linux.go - pseudo-terminals for controller software to talk to
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// pty is the master side of a pseudo-terminal; the controller software opens the slave side, at path
+//
+// The slave is kept open too, so the master doesn't fail while the controller software isn't connected.
+type pty struct {
+	master *os.File
+	slave  *os.File
+	path   string
+}
+
+// openPTY makes a pseudo-terminal in raw mode, so bytes go through it untouched
+func openPTY() (*pty, error) {
+	// non-blocking, so os.File uses the poller and Close interrupts a Read
+	fd, err := unix.Open("/dev/ptmx", unix.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+	err = unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0)
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	n, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	p := &pty{
+		master: os.NewFile(uintptr(fd), "/dev/ptmx"),
+		path:   fmt.Sprintf("/dev/pts/%d", n),
+	}
+
+	p.slave, err = os.OpenFile(p.path, os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		p.master.Close()
+		return nil, err
+	}
+	err = makeRaw(int(p.slave.Fd()))
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// makeRaw turns off everything the terminal does to the bytes going through it, like cfmakeraw
+func makeRaw(fd int) error {
+	t, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return err
+	}
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB
+	t.Cflag |= unix.CS8
+	t.Cc[unix.VMIN] = 1
+	t.Cc[unix.VTIME] = 0
+	return unix.IoctlSetTermios(fd, unix.TCSETS, t)
+}
+
+// Read reads what the controller software wrote
+func (p *pty) Read(b []byte) (int, error) {
+	return p.master.Read(b)
+}
+
+// Write writes to the controller software
+func (p *pty) Write(b []byte) (int, error) {
+	return p.master.Write(b)
+}
+
+// Close closes both sides
+func (p *pty) Close() error {
+	p.slave.Close()
+	return p.master.Close()
+}
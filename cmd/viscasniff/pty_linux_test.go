@@ -0,0 +1,52 @@
+package main
+
+import (
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPTY(t *testing.T) {
+	p, err := openPTY()
+	if err != nil {
+		t.Skipf("no pseudo-terminals: %v", err)
+	}
+	defer p.Close()
+
+	// what the controller software would do
+	f, err := os.OpenFile(p.path, os.O_RDWR, 0)
+	assert.Nil(t, err)
+	defer f.Close()
+
+	// bytes that a terminal would normally mess with go through untouched
+	packet := []byte{0x81, 0x01, 0x0D, 0x0A, 0x03, 0x7F, 0xFF}
+	_, err = f.Write(packet)
+	assert.Nil(t, err)
+	got := make([]byte, len(packet))
+	_, err = io.ReadFull(p, got)
+	assert.Nil(t, err)
+	assert.Equal(t, packet, got)
+
+	_, err = p.Write(packet)
+	assert.Nil(t, err)
+	_, err = io.ReadFull(f, got)
+	assert.Nil(t, err)
+	assert.Equal(t, packet, got)
+
+	// closing interrupts a Read
+	done := make(chan struct{})
+	go func() {
+		p.Read(got)
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	p.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Close didn't interrupt Read")
+	}
+}
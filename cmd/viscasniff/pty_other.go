@@ -0,0 +1,45 @@
+//  pty_other.go - no pseudo-terminals
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+//go:build !linux
+// +build !linux
+
+package main
+
+// pty is a pseudo-terminal, which isn't supported here
+type pty struct {
+	path string
+}
+
+// openPTY returns errNoPTY
+func openPTY() (*pty, error) {
+	return nil, errNoPTY
+}
+
+// Read is never called
+func (p *pty) Read(b []byte) (int, error) {
+	return 0, errNoPTY
+}
+
+// Write is never called
+func (p *pty) Write(b []byte) (int, error) {
+	return 0, errNoPTY
+}
+
+// Close does nothing
+func (p *pty) Close() error {
+	return nil
+}
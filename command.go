@@ -59,30 +59,63 @@ func parseIntFromNibbles(b []byte) (int64, error) {
 	return n, nil
 }
 
+// EncodeNibbles encodes v, two's complement, into n nibbles, most significant first, the way positions and
+// other values are sent in VISCA messages
+func EncodeNibbles(v int, n int) []byte {
+	b := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		b[i] = byte(v & 0x0F)
+		v >>= 4
+	}
+	return b
+}
+
+// DecodeNibbles decodes a value from nibbles, most significant first, as two's complement if signed; it
+// returns false if any of the bytes isn't a nibble, or there are too many of them to fit in an int
+func DecodeNibbles(b []byte, signed bool) (int, bool) {
+	if len(b) > 7 {
+		return 0, false
+	}
+	v := 0
+	for _, n := range b {
+		if n > 0x0F {
+			return 0, false
+		}
+		v = v<<4 | int(n)
+	}
+	bits := uint(len(b) * 4)
+	if signed && bits > 0 && v >= 1<<(bits-1) {
+		v -= 1 << bits
+	}
+	return v, true
+}
+
+// decodePosition decodes a pan position into degrees; 5 nibbles are measured like an SRG-300 and 4 like a
+// PTZOptics camera
 func decodePosition(nibbles []byte) (float64, error) {
-	divisor := 14.4
+	model := ModelPTZOptics
 	if len(nibbles) == 5 {
-		divisor = 235.9
+		model = ModelSRG300
 	} else if len(nibbles) != 4 {
-		return 0, errors.New("invalid length")
+		return 0, ErrInvalidLength
 	}
 	d, err := parseIntFromNibbles(nibbles)
-	return float64(d) / divisor, err
+	return model.PanDegrees(int(d)), err
 }
 
+// decodeTilt decodes a tilt position into degrees, measured like an SRG-300
 func decodeTilt(nibbles []byte) (float64, error) {
-	divisor := 235.9
 	if len(nibbles) != 4 {
-		return 0, errors.New("invalid length")
+		return 0, ErrInvalidLength
 	}
 	d, err := parseIntFromNibbles(nibbles)
-	return float64(d) / divisor, err
+	return ModelSRG300.TiltDegrees(int(d)), err
 }
 
 func decodeZoom(nibbles []byte) (float64, error) {
 	// divisor := 6144.0
 	if len(nibbles) != 4 {
-		return 0, errors.New("invalid length")
+		return 0, ErrInvalidLength
 	}
 	d, err := parseIntFromNibbles(nibbles)
 	return 6105.543 + 4295.1494*math.Log1p(float64(d)), err
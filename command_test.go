@@ -117,3 +117,23 @@ func TestDecodeTilt(t *testing.T) {
 // 		assert.Equal(t, tt.want, d)
 // 	}
 // }
+
+func TestNibbles(t *testing.T) {
+	assert.Equal(t, []byte{0x0F, 0x06, 0x03, 0x05, 0x09}, EncodeNibbles(-40103, 5))
+	assert.Equal(t, []byte{0x05, 0x02, 0x0E, 0x0F}, EncodeNibbles(21231, 4))
+
+	v, ok := DecodeNibbles([]byte{0x0F, 0x06, 0x03, 0x05, 0x09}, true)
+	assert.True(t, ok)
+	assert.Equal(t, -40103, v)
+	v, ok = DecodeNibbles([]byte{0x0F, 0x0F, 0x0F, 0x0F}, false)
+	assert.True(t, ok)
+	assert.Equal(t, 0xFFFF, v)
+	v, ok = DecodeNibbles(nil, true)
+	assert.True(t, ok)
+	assert.Equal(t, 0, v)
+
+	_, ok = DecodeNibbles([]byte{0x10}, false)
+	assert.False(t, ok)
+	_, ok = DecodeNibbles(make([]byte, 8), false)
+	assert.False(t, ok)
+}
@@ -0,0 +1,292 @@
+package commands
+
+import "github.com/josh23french/visca"
+
+// Focus limits
+const (
+	MaxFocusSpeed    = 0x07
+	MaxFocusPosition = 0xFFFF // 4 nibbles; the camera's range depends on the model
+)
+
+// FocusParams are common to the variable speed focus commands
+type FocusParams struct {
+	speed uint8
+}
+
+// SetSpeed sets the speed, 0 (slowest) to 7 (fastest)
+func (p *FocusParams) SetSpeed(speed int) error {
+	if speed < 0 || speed > MaxFocusSpeed {
+		return ErrInvalidSpeed
+	}
+	p.speed = uint8(speed)
+	return nil
+}
+
+// Speed returns the speed
+func (p *FocusParams) Speed() int {
+	return int(p.speed)
+}
+
+// FocusStop stops focusing
+type FocusStop struct{}
+
+// Message returns the command as a Message
+func (c *FocusStop) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x08, 0x00}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *FocusStop) ParseCompletion(msg visca.Message) {}
+
+// FocusFarStandard focuses farther away at the camera's standard speed
+type FocusFarStandard struct{}
+
+// Message returns the command as a Message
+func (c *FocusFarStandard) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x08, 0x02}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *FocusFarStandard) ParseCompletion(msg visca.Message) {}
+
+// FocusNearStandard focuses nearer at the camera's standard speed
+type FocusNearStandard struct{}
+
+// Message returns the command as a Message
+func (c *FocusNearStandard) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x08, 0x03}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *FocusNearStandard) ParseCompletion(msg visca.Message) {}
+
+// FocusFarVariable focuses farther away at a given speed
+type FocusFarVariable struct {
+	FocusParams
+}
+
+// Message returns the command as a Message
+func (c *FocusFarVariable) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x08, 0x20 | c.speed}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *FocusFarVariable) ParseCompletion(msg visca.Message) {}
+
+// FocusNearVariable focuses nearer at a given speed
+type FocusNearVariable struct {
+	FocusParams
+}
+
+// Message returns the command as a Message
+func (c *FocusNearVariable) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x08, 0x30 | c.speed}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *FocusNearVariable) ParseCompletion(msg visca.Message) {}
+
+// FocusPositionParams are the position for the Direct and Near Limit commands
+type FocusPositionParams struct {
+	position uint16
+}
+
+// SetPosition sets the position
+func (p *FocusPositionParams) SetPosition(position int) error {
+	if position < 0 || position > MaxFocusPosition {
+		return ErrInvalidPosition
+	}
+	p.position = uint16(position)
+	return nil
+}
+
+// Position returns the position
+func (p *FocusPositionParams) Position() int {
+	return int(p.position)
+}
+
+// FocusDirect focuses at a position; the camera should be in manual focus
+type FocusDirect struct {
+	FocusPositionParams
+}
+
+// Message returns the command as a Message
+func (c *FocusDirect) Message() visca.Message {
+	return append([]byte{0x01, 0x04, 0x48}, visca.EncodeNibbles(int(c.position), 4)...)
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *FocusDirect) ParseCompletion(msg visca.Message) {}
+
+// FocusAuto turns autofocus on
+type FocusAuto struct{}
+
+// Message returns the command as a Message
+func (c *FocusAuto) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x38, 0x02}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *FocusAuto) ParseCompletion(msg visca.Message) {}
+
+// FocusManual turns autofocus off, for focusing with the other commands
+type FocusManual struct{}
+
+// Message returns the command as a Message
+func (c *FocusManual) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x38, 0x03}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *FocusManual) ParseCompletion(msg visca.Message) {}
+
+// FocusAutoManual toggles between auto and manual focus
+type FocusAutoManual struct{}
+
+// Message returns the command as a Message
+func (c *FocusAutoManual) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x38, 0x10}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *FocusAutoManual) ParseCompletion(msg visca.Message) {}
+
+// FocusOnePushTrigger focuses once, automatically, and then stays in manual focus
+type FocusOnePushTrigger struct{}
+
+// Message returns the command as a Message
+func (c *FocusOnePushTrigger) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x18, 0x01}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *FocusOnePushTrigger) ParseCompletion(msg visca.Message) {}
+
+// FocusInfinity focuses at infinity
+type FocusInfinity struct{}
+
+// Message returns the command as a Message
+func (c *FocusInfinity) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x18, 0x02}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *FocusInfinity) ParseCompletion(msg visca.Message) {}
+
+// FocusNearLimit sets how near autofocus will focus
+type FocusNearLimit struct {
+	FocusPositionParams
+}
+
+// Message returns the command as a Message
+func (c *FocusNearLimit) Message() visca.Message {
+	return append([]byte{0x01, 0x04, 0x28}, visca.EncodeNibbles(int(c.position), 4)...)
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *FocusNearLimit) ParseCompletion(msg visca.Message) {}
+
+// AFSensitivityNormal makes autofocus react quickly, for subjects that move
+type AFSensitivityNormal struct{}
+
+// Message returns the command as a Message
+func (c *AFSensitivityNormal) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x58, 0x02}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *AFSensitivityNormal) ParseCompletion(msg visca.Message) {}
+
+// AFSensitivityLow makes autofocus steadier, so it hunts less in poor light
+type AFSensitivityLow struct{}
+
+// Message returns the command as a Message
+func (c *AFSensitivityLow) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x58, 0x03}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *AFSensitivityLow) ParseCompletion(msg visca.Message) {}
+
+// AFModeNormal makes autofocus work all the time
+type AFModeNormal struct{}
+
+// Message returns the command as a Message
+func (c *AFModeNormal) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x57, 0x00}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *AFModeNormal) ParseCompletion(msg visca.Message) {}
+
+// AFModeInterval makes autofocus work at intervals
+type AFModeInterval struct{}
+
+// Message returns the command as a Message
+func (c *AFModeInterval) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x57, 0x01}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *AFModeInterval) ParseCompletion(msg visca.Message) {}
+
+// AFModeZoomTrigger makes autofocus work only after zooming
+type AFModeZoomTrigger struct{}
+
+// Message returns the command as a Message
+func (c *AFModeZoomTrigger) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x57, 0x02}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *AFModeZoomTrigger) ParseCompletion(msg visca.Message) {}
+
+// FocusPositionInquiry asks for the focus position
+type FocusPositionInquiry struct {
+	position int
+}
+
+// Message returns the inquiry as a Message
+func (c *FocusPositionInquiry) Message() visca.Message {
+	return []byte{0x09, 0x04, 0x48}
+}
+
+// ParseCompletion parses the reply, 50 0p 0q 0r 0s
+func (c *FocusPositionInquiry) ParseCompletion(msg visca.Message) error {
+	v, err := completionNibbles(msg, 4)
+	if err != nil {
+		return err
+	}
+	c.position = v
+	return nil
+}
+
+// Position returns the focus position from the reply
+func (c *FocusPositionInquiry) Position() int {
+	return c.position
+}
+
+// FocusModeInquiry asks whether the camera is in auto or manual focus
+type FocusModeInquiry struct {
+	auto bool
+}
+
+// Message returns the inquiry as a Message
+func (c *FocusModeInquiry) Message() visca.Message {
+	return []byte{0x09, 0x04, 0x38}
+}
+
+// ParseCompletion parses the reply, 50 02 for auto or 50 03 for manual
+func (c *FocusModeInquiry) ParseCompletion(msg visca.Message) error {
+	auto, err := completionOnOff(msg)
+	if err != nil {
+		return err
+	}
+	c.auto = auto
+	return nil
+}
+
+// Auto returns true if the reply said the camera is in auto focus
+func (c *FocusModeInquiry) Auto() bool {
+	return c.auto
+}
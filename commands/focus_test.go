@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/josh23french/visca"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFocusCommands(t *testing.T) {
+	var params FocusParams
+	assert.Nil(t, params.SetSpeed(7))
+	assert.Equal(t, 7, params.Speed())
+	assert.Equal(t, ErrInvalidSpeed, params.SetSpeed(8))
+
+	var position FocusPositionParams
+	assert.Nil(t, position.SetPosition(0xC000))
+	assert.Equal(t, 0xC000, position.Position())
+	assert.Equal(t, ErrInvalidPosition, position.SetPosition(-1))
+
+	var tests = []struct {
+		cmd interface {
+			Message() visca.Message
+		}
+		want visca.Message
+	}{
+		{&FocusStop{}, visca.Message{0x01, 0x04, 0x08, 0x00}},
+		{&FocusFarStandard{}, visca.Message{0x01, 0x04, 0x08, 0x02}},
+		{&FocusNearStandard{}, visca.Message{0x01, 0x04, 0x08, 0x03}},
+		{&FocusFarVariable{params}, visca.Message{0x01, 0x04, 0x08, 0x27}},
+		{&FocusNearVariable{params}, visca.Message{0x01, 0x04, 0x08, 0x37}},
+		{&FocusDirect{position}, visca.Message{0x01, 0x04, 0x48, 0x0C, 0x00, 0x00, 0x00}},
+		{&FocusAuto{}, visca.Message{0x01, 0x04, 0x38, 0x02}},
+		{&FocusManual{}, visca.Message{0x01, 0x04, 0x38, 0x03}},
+		{&FocusAutoManual{}, visca.Message{0x01, 0x04, 0x38, 0x10}},
+		{&FocusOnePushTrigger{}, visca.Message{0x01, 0x04, 0x18, 0x01}},
+		{&FocusInfinity{}, visca.Message{0x01, 0x04, 0x18, 0x02}},
+		{&FocusNearLimit{position}, visca.Message{0x01, 0x04, 0x28, 0x0C, 0x00, 0x00, 0x00}},
+		{&AFSensitivityNormal{}, visca.Message{0x01, 0x04, 0x58, 0x02}},
+		{&AFSensitivityLow{}, visca.Message{0x01, 0x04, 0x58, 0x03}},
+		{&AFModeNormal{}, visca.Message{0x01, 0x04, 0x57, 0x00}},
+		{&AFModeInterval{}, visca.Message{0x01, 0x04, 0x57, 0x01}},
+		{&AFModeZoomTrigger{}, visca.Message{0x01, 0x04, 0x57, 0x02}},
+		{&FocusPositionInquiry{}, visca.Message{0x09, 0x04, 0x48}},
+		{&FocusModeInquiry{}, visca.Message{0x09, 0x04, 0x38}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.cmd.Message(), "%T", tt.cmd)
+	}
+}
+
+func TestFocusInquiries(t *testing.T) {
+	pos := FocusPositionInquiry{}
+	assert.Nil(t, pos.ParseCompletion(visca.Message{0x50, 0x01, 0x00, 0x00, 0x00}))
+	assert.Equal(t, 0x1000, pos.Position())
+	assert.Equal(t, ErrInvalidReply, pos.ParseCompletion(visca.Message{0x50, 0x01}))
+
+	mode := FocusModeInquiry{}
+	assert.Nil(t, mode.ParseCompletion(visca.Message{0x50, 0x02}))
+	assert.True(t, mode.Auto())
+	assert.Nil(t, mode.ParseCompletion(visca.Message{0x50, 0x03}))
+	assert.False(t, mode.Auto())
+	assert.Equal(t, ErrInvalidReply, mode.ParseCompletion(visca.Message{0x41}))
+}
@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"errors"
+
+	"github.com/josh23french/visca"
+)
+
+// Error constants
+var (
+	ErrInvalidNumber = errors.New("invalid memory number")
+)
+
+// MaxMemoryNumber is the largest memory (preset) number that can be sent; how many a camera actually has
+// depends on the model
+const MaxMemoryNumber = 0xFF
+
+// Memory actions
+const (
+	memoryReset  = 0x00
+	memorySet    = 0x01
+	memoryRecall = 0x02
+)
+
+// MemoryParams are the memory (preset) number for the Memory commands
+type MemoryParams struct {
+	number uint8
+}
+
+// SetNumber sets the memory number
+func (p *MemoryParams) SetNumber(number int) error {
+	if number < 0 || number > MaxMemoryNumber {
+		return ErrInvalidNumber
+	}
+	p.number = uint8(number)
+	return nil
+}
+
+// Number returns the memory number
+func (p *MemoryParams) Number() int {
+	return int(p.number)
+}
+
+// MemoryReset clears a memory
+type MemoryReset struct {
+	MemoryParams
+}
+
+// Message returns the command as a Message
+func (c *MemoryReset) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x3F, memoryReset, c.number}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *MemoryReset) ParseCompletion(msg visca.Message) {}
+
+// MemorySet saves the camera's current position and settings in a memory
+type MemorySet struct {
+	MemoryParams
+}
+
+// Message returns the command as a Message
+func (c *MemorySet) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x3F, memorySet, c.number}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *MemorySet) ParseCompletion(msg visca.Message) {}
+
+// MemoryRecall moves the camera to the position, and restores the settings, saved in a memory
+type MemoryRecall struct {
+	MemoryParams
+}
+
+// Message returns the command as a Message
+func (c *MemoryRecall) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x3F, memoryRecall, c.number}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *MemoryRecall) ParseCompletion(msg visca.Message) {}
@@ -0,0 +1,32 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/josh23french/visca"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryCommands(t *testing.T) {
+	var params MemoryParams
+	assert.Nil(t, params.SetNumber(3))
+	assert.Equal(t, 3, params.Number())
+	assert.Equal(t, ErrInvalidNumber, params.SetNumber(MaxMemoryNumber+1))
+	assert.Equal(t, ErrInvalidNumber, params.SetNumber(-1))
+	assert.Equal(t, 3, params.Number())
+
+	var tests = []struct {
+		cmd interface {
+			Message() visca.Message
+		}
+		want visca.Message
+	}{
+		{&MemoryReset{params}, visca.Message{0x01, 0x04, 0x3F, 0x00, 0x03}},
+		{&MemorySet{params}, visca.Message{0x01, 0x04, 0x3F, 0x01, 0x03}},
+		{&MemoryRecall{params}, visca.Message{0x01, 0x04, 0x3F, 0x02, 0x03}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.cmd.Message(), "%T", tt.cmd)
+	}
+}
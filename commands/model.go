@@ -0,0 +1,21 @@
+package commands
+
+import "github.com/josh23french/visca"
+
+// ModelParams are the camera model for commands whose encoding depends on it
+type ModelParams struct {
+	model *visca.Model
+}
+
+// SetModel sets the camera model, which decides how degrees are converted and how many nibbles pan is sent in
+func (p *ModelParams) SetModel(model *visca.Model) {
+	p.model = model
+}
+
+// Model returns the camera model, visca.DefaultModel if it hasn't been set
+func (p *ModelParams) Model() *visca.Model {
+	if p.model == nil {
+		return visca.DefaultModel
+	}
+	return p.model
+}
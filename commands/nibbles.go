@@ -0,0 +1,23 @@
+package commands
+
+import "github.com/josh23french/visca"
+
+// completionNibbles decodes an inquiry's Completion that's n nibbles of unsigned value
+func completionNibbles(msg visca.Message, n int) (int, error) {
+	if len(msg) != n+1 || msg.Type() != visca.MsgCompletion {
+		return 0, ErrInvalidReply
+	}
+	v, ok := visca.DecodeNibbles(msg[1:], false)
+	if !ok {
+		return 0, ErrInvalidReply
+	}
+	return v, nil
+}
+
+// completionOnOff decodes an inquiry's Completion that's 02 for on or 03 for off
+func completionOnOff(msg visca.Message) (bool, error) {
+	if len(msg) != 2 || msg.Type() != visca.MsgCompletion || (msg[1] != 0x02 && msg[1] != 0x03) {
+		return false, ErrInvalidReply
+	}
+	return msg[1] == 0x02, nil
+}
@@ -6,6 +6,34 @@ import (
 	"github.com/josh23french/visca"
 )
 
+// Error constants
+var (
+	ErrInvalidSpeed    = errors.New("invalid speed")
+	ErrInvalidPosition = errors.New("invalid position")
+	ErrInvalidCorner   = errors.New("invalid corner")
+)
+
+// Position limits; pan is sent as up to 5 signed nibbles, depending on the model, and tilt as 4
+const (
+	MinPanPosition  = -0x80000
+	MaxPanPosition  = 0x7FFFF
+	MinTiltPosition = -0x8000
+	MaxTiltPosition = 0x7FFF
+)
+
+// Pan-tiltDrive directions
+const (
+	panLeft   = 0x01
+	panRight  = 0x02
+	panStop   = 0x03
+	tiltUp    = 0x01
+	tiltDown  = 0x02
+	tiltStop  = 0x03
+	driveMove = 0x01
+	driveAbs  = 0x02
+	driveRel  = 0x03
+)
+
 // PanTiltParams are common to several Pan/Tilt commands
 type PanTiltParams struct {
 	panSpeed  uint8
@@ -14,8 +42,8 @@ type PanTiltParams struct {
 
 // SetPanSpeed sets the panSpeed
 func (p *PanTiltParams) SetPanSpeed(speed int) error {
-	if speed < 0x01 || speed > 0x18 {
-		return errors.New("invalid speed")
+	if speed < 0x01 || speed > visca.MaxPanSpeed {
+		return ErrInvalidSpeed
 	}
 	p.panSpeed = uint8(speed)
 	return nil
@@ -23,8 +51,8 @@ func (p *PanTiltParams) SetPanSpeed(speed int) error {
 
 // SetTiltSpeed sets the tiltSpeed
 func (p *PanTiltParams) SetTiltSpeed(speed int) error {
-	if speed < 0x01 || speed > 0x18 {
-		return errors.New("invalid speed")
+	if speed < 0x01 || speed > visca.MaxTiltSpeed {
+		return ErrInvalidSpeed
 	}
 	p.tiltSpeed = uint8(speed)
 	return nil
@@ -40,6 +68,20 @@ func (p *PanTiltParams) TiltSpeed() int {
 	return int(p.tiltSpeed)
 }
 
+// drive builds a Pan-tiltDrive message moving in the given directions; speeds that haven't been set are
+// sent as 0x01, since some cameras reject 0x00 even for an axis that isn't moving
+func (p *PanTiltParams) drive(pan, tilt byte) visca.Message {
+	return []byte{0x01, 0x06, driveMove, orSlowest(p.panSpeed), orSlowest(p.tiltSpeed), pan, tilt}
+}
+
+// orSlowest returns the speed, or 0x01 if it hasn't been set
+func orSlowest(speed uint8) uint8 {
+	if speed == 0 {
+		return 0x01
+	}
+	return speed
+}
+
 // PanTiltUp tilts the camera up
 type PanTiltUp struct {
 	PanTiltParams
@@ -47,8 +89,367 @@ type PanTiltUp struct {
 
 // Message returns the command as a Message
 func (c *PanTiltUp) Message() visca.Message {
-	return []byte{0x01, 0x06, 0x01, c.panSpeed, c.tiltSpeed, 0x03, 0x01}
+	return c.drive(panStop, tiltUp)
 }
 
 // ParseCompletion does nothing, this is not an inquiry
 func (c *PanTiltUp) ParseCompletion(msg visca.Message) {}
+
+// PanTiltDown tilts the camera down
+type PanTiltDown struct {
+	PanTiltParams
+}
+
+// Message returns the command as a Message
+func (c *PanTiltDown) Message() visca.Message {
+	return c.drive(panStop, tiltDown)
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *PanTiltDown) ParseCompletion(msg visca.Message) {}
+
+// PanTiltLeft pans the camera left
+type PanTiltLeft struct {
+	PanTiltParams
+}
+
+// Message returns the command as a Message
+func (c *PanTiltLeft) Message() visca.Message {
+	return c.drive(panLeft, tiltStop)
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *PanTiltLeft) ParseCompletion(msg visca.Message) {}
+
+// PanTiltRight pans the camera right
+type PanTiltRight struct {
+	PanTiltParams
+}
+
+// Message returns the command as a Message
+func (c *PanTiltRight) Message() visca.Message {
+	return c.drive(panRight, tiltStop)
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *PanTiltRight) ParseCompletion(msg visca.Message) {}
+
+// PanTiltUpLeft pans the camera left while tilting it up
+type PanTiltUpLeft struct {
+	PanTiltParams
+}
+
+// Message returns the command as a Message
+func (c *PanTiltUpLeft) Message() visca.Message {
+	return c.drive(panLeft, tiltUp)
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *PanTiltUpLeft) ParseCompletion(msg visca.Message) {}
+
+// PanTiltUpRight pans the camera right while tilting it up
+type PanTiltUpRight struct {
+	PanTiltParams
+}
+
+// Message returns the command as a Message
+func (c *PanTiltUpRight) Message() visca.Message {
+	return c.drive(panRight, tiltUp)
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *PanTiltUpRight) ParseCompletion(msg visca.Message) {}
+
+// PanTiltDownLeft pans the camera left while tilting it down
+type PanTiltDownLeft struct {
+	PanTiltParams
+}
+
+// Message returns the command as a Message
+func (c *PanTiltDownLeft) Message() visca.Message {
+	return c.drive(panLeft, tiltDown)
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *PanTiltDownLeft) ParseCompletion(msg visca.Message) {}
+
+// PanTiltDownRight pans the camera right while tilting it down
+type PanTiltDownRight struct {
+	PanTiltParams
+}
+
+// Message returns the command as a Message
+func (c *PanTiltDownRight) Message() visca.Message {
+	return c.drive(panRight, tiltDown)
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *PanTiltDownRight) ParseCompletion(msg visca.Message) {}
+
+// PanTiltStop stops panning and tilting
+//
+// The speeds are sent, but cameras ignore them; without any set, it's sent like Controller.PanTiltStop.
+type PanTiltStop struct {
+	PanTiltParams
+}
+
+// Message returns the command as a Message
+func (c *PanTiltStop) Message() visca.Message {
+	return c.drive(panStop, tiltStop)
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *PanTiltStop) ParseCompletion(msg visca.Message) {}
+
+// PanTiltPositionParams are the position for the AbsolutePosition, RelativePosition and LimitSet commands
+//
+// Positions are in the camera's own units, which depend on the model; SetModel before SetDegrees to convert
+// from degrees for a model other than visca.DefaultModel.
+type PanTiltPositionParams struct {
+	ModelParams
+	pan  int
+	tilt int
+}
+
+// SetPosition sets the pan and tilt positions, checking pan fits in the nibbles the model sends it in
+func (p *PanTiltPositionParams) SetPosition(pan, tilt int) error {
+	if !fits(p.Model(), pan, tilt) {
+		return ErrInvalidPosition
+	}
+	p.pan = pan
+	p.tilt = tilt
+	return nil
+}
+
+// SetModel sets the camera model; it returns ErrInvalidPosition, and keeps the old model, if the position
+// doesn't fit in the nibbles the new model sends pan in
+func (p *PanTiltPositionParams) SetModel(model *visca.Model) error {
+	m := model
+	if m == nil {
+		m = visca.DefaultModel
+	}
+	if !fits(m, p.pan, p.tilt) {
+		return ErrInvalidPosition
+	}
+	p.ModelParams.SetModel(model)
+	return nil
+}
+
+// fits returns true if the model can send the position: pan as its number of signed nibbles and tilt as 4
+func fits(m *visca.Model, pan, tilt int) bool {
+	maxPan := 1<<(4*m.PanNibbles-1) - 1
+	return pan >= -maxPan-1 && pan <= maxPan && tilt >= MinTiltPosition && tilt <= MaxTiltPosition
+}
+
+// Pan returns the pan position
+func (p *PanTiltPositionParams) Pan() int {
+	return p.pan
+}
+
+// Tilt returns the tilt position
+func (p *PanTiltPositionParams) Tilt() int {
+	return p.tilt
+}
+
+// nibbles encodes the position as the model's nibbles of pan and 4 of tilt
+func (p *PanTiltPositionParams) nibbles() []byte {
+	return p.Model().EncodePosition(p.pan, p.tilt)
+}
+
+// PanTiltAbsolutePosition moves the camera to a position
+type PanTiltAbsolutePosition struct {
+	PanTiltParams
+	PanTiltPositionParams
+}
+
+// Message returns the command as a Message
+func (c *PanTiltAbsolutePosition) Message() visca.Message {
+	msg := []byte{0x01, 0x06, driveAbs, c.panSpeed, c.tiltSpeed}
+	return append(msg, c.nibbles()...)
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *PanTiltAbsolutePosition) ParseCompletion(msg visca.Message) {}
+
+// SetDegrees sets the position in degrees from home, checking the camera can get there
+func (c *PanTiltAbsolutePosition) SetDegrees(pan, tilt float64) error {
+	panPos, err := c.Model().PanPosition(pan)
+	if err != nil {
+		return err
+	}
+	tiltPos, err := c.Model().TiltPosition(tilt)
+	if err != nil {
+		return err
+	}
+	return c.SetPosition(panPos, tiltPos)
+}
+
+// PanTiltRelativePosition moves the camera by an amount from where it is
+type PanTiltRelativePosition struct {
+	PanTiltParams
+	PanTiltPositionParams
+}
+
+// Message returns the command as a Message
+func (c *PanTiltRelativePosition) Message() visca.Message {
+	msg := []byte{0x01, 0x06, driveRel, c.panSpeed, c.tiltSpeed}
+	return append(msg, c.nibbles()...)
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *PanTiltRelativePosition) ParseCompletion(msg visca.Message) {}
+
+// SetDegrees sets how many degrees to move by, checking it's no more than the camera's whole range
+func (c *PanTiltRelativePosition) SetDegrees(pan, tilt float64) error {
+	panPos, err := c.Model().PanOffset(pan)
+	if err != nil {
+		return err
+	}
+	tiltPos, err := c.Model().TiltOffset(tilt)
+	if err != nil {
+		return err
+	}
+	return c.SetPosition(panPos, tiltPos)
+}
+
+// PanTiltHome moves the camera to its home position
+type PanTiltHome struct{}
+
+// Message returns the command as a Message
+func (c *PanTiltHome) Message() visca.Message {
+	return []byte{0x01, 0x06, 0x04}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *PanTiltHome) ParseCompletion(msg visca.Message) {}
+
+// PanTiltReset recalibrates pan and tilt by moving the camera to its limits and back
+type PanTiltReset struct{}
+
+// Message returns the command as a Message
+func (c *PanTiltReset) Message() visca.Message {
+	return []byte{0x01, 0x06, 0x05}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *PanTiltReset) ParseCompletion(msg visca.Message) {}
+
+// Pan-tiltLimitSet corners
+const (
+	LimitDownLeft = 0x00
+	LimitUpRight  = 0x01
+)
+
+// PanTiltLimitParams are the corner for the LimitSet and LimitClear commands
+//
+// The limits are a box: the DownLeft corner sets how far left and down the camera can go, and the UpRight
+// corner how far right and up.
+type PanTiltLimitParams struct {
+	corner uint8
+}
+
+// SetCorner sets the corner, LimitDownLeft or LimitUpRight
+func (p *PanTiltLimitParams) SetCorner(corner int) error {
+	if corner != LimitDownLeft && corner != LimitUpRight {
+		return ErrInvalidCorner
+	}
+	p.corner = uint8(corner)
+	return nil
+}
+
+// Corner returns the corner
+func (p *PanTiltLimitParams) Corner() int {
+	return int(p.corner)
+}
+
+// PanTiltLimitSet limits how far the camera can move, at one corner
+type PanTiltLimitSet struct {
+	PanTiltLimitParams
+	PanTiltPositionParams
+}
+
+// Message returns the command as a Message
+func (c *PanTiltLimitSet) Message() visca.Message {
+	msg := []byte{0x01, 0x06, 0x07, 0x00, c.corner}
+	return append(msg, c.nibbles()...)
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *PanTiltLimitSet) ParseCompletion(msg visca.Message) {}
+
+// SetDegrees sets the corner's position in degrees from home, checking the camera can get there
+func (c *PanTiltLimitSet) SetDegrees(pan, tilt float64) error {
+	panPos, err := c.Model().PanPosition(pan)
+	if err != nil {
+		return err
+	}
+	tiltPos, err := c.Model().TiltPosition(tilt)
+	if err != nil {
+		return err
+	}
+	return c.SetPosition(panPos, tiltPos)
+}
+
+// PanTiltLimitClear removes the limit at one corner
+type PanTiltLimitClear struct {
+	PanTiltLimitParams
+	ModelParams
+}
+
+// Message returns the command as a Message
+func (c *PanTiltLimitClear) Message() visca.Message {
+	msg := []byte{0x01, 0x06, 0x07, 0x01, c.corner}
+	msg = append(msg, clearNibbles(c.Model().PanNibbles)...)
+	return append(msg, clearNibbles(4)...)
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *PanTiltLimitClear) ParseCompletion(msg visca.Message) {}
+
+// clearNibbles is the position LimitClear sends: 07 then 0F for the rest of the nibbles
+func clearNibbles(n int) []byte {
+	b := []byte{0x07}
+	for len(b) < n {
+		b = append(b, 0x0F)
+	}
+	return b
+}
+
+// PanTiltPositionInquiry asks for the pan and tilt position
+//
+// SetModel before parsing the reply to a camera other than visca.DefaultModel, since the model decides how
+// many nibbles pan is in and how the position converts to degrees.
+type PanTiltPositionInquiry struct {
+	ModelParams
+	pan  float64
+	tilt float64
+}
+
+// Message returns the inquiry as a Message
+func (c *PanTiltPositionInquiry) Message() visca.Message {
+	return []byte{0x09, 0x06, 0x12}
+}
+
+// ParseCompletion parses the reply, 50 followed by the model's nibbles of pan and 4 of tilt
+func (c *PanTiltPositionInquiry) ParseCompletion(msg visca.Message) error {
+	if len(msg) == 0 || msg.Type() != visca.MsgCompletion {
+		return ErrInvalidReply
+	}
+	pan, tilt, err := c.Model().DecodePosition(msg[1:])
+	if err != nil {
+		return ErrInvalidReply
+	}
+	c.pan = pan
+	c.tilt = tilt
+	return nil
+}
+
+// Pan returns the pan position from the reply, in degrees from home
+func (c *PanTiltPositionInquiry) Pan() float64 {
+	return c.pan
+}
+
+// Tilt returns the tilt position from the reply, in degrees from home
+func (c *PanTiltPositionInquiry) Tilt() float64 {
+	return c.tilt
+}
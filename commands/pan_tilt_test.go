@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/josh23french/visca"
@@ -25,5 +26,168 @@ func TestPanTiltParams(t *testing.T) {
 	err = cmd.SetTiltSpeed(0)
 	assert.NotNil(t, err)
 
+	// tilt is a step slower than pan
+	assert.Nil(t, cmd.SetPanSpeed(0x18))
+	assert.Equal(t, ErrInvalidSpeed, cmd.SetTiltSpeed(0x18))
+	assert.Nil(t, cmd.SetPanSpeed(0x13))
+
 	assert.Equal(t, visca.Message([]byte{0x01, 0x06, 0x01, 0x13, 0x15, 0x03, 0x01}), cmd.Message())
 }
+
+func TestPanTiltDrive(t *testing.T) {
+	var params PanTiltParams
+	assert.Nil(t, params.SetPanSpeed(0x18))
+	assert.Nil(t, params.SetTiltSpeed(0x14))
+
+	var tests = []struct {
+		cmd interface {
+			Message() visca.Message
+		}
+		want visca.Message
+	}{
+		{&PanTiltUp{params}, visca.Message{0x01, 0x06, 0x01, 0x18, 0x14, 0x03, 0x01}},
+		{&PanTiltDown{params}, visca.Message{0x01, 0x06, 0x01, 0x18, 0x14, 0x03, 0x02}},
+		{&PanTiltLeft{params}, visca.Message{0x01, 0x06, 0x01, 0x18, 0x14, 0x01, 0x03}},
+		{&PanTiltRight{params}, visca.Message{0x01, 0x06, 0x01, 0x18, 0x14, 0x02, 0x03}},
+		{&PanTiltUpLeft{params}, visca.Message{0x01, 0x06, 0x01, 0x18, 0x14, 0x01, 0x01}},
+		{&PanTiltUpRight{params}, visca.Message{0x01, 0x06, 0x01, 0x18, 0x14, 0x02, 0x01}},
+		{&PanTiltDownLeft{params}, visca.Message{0x01, 0x06, 0x01, 0x18, 0x14, 0x01, 0x02}},
+		{&PanTiltDownRight{params}, visca.Message{0x01, 0x06, 0x01, 0x18, 0x14, 0x02, 0x02}},
+		{&PanTiltStop{params}, visca.Message{0x01, 0x06, 0x01, 0x18, 0x14, 0x03, 0x03}},
+		{&PanTiltStop{}, visca.Message{0x01, 0x06, 0x01, 0x01, 0x01, 0x03, 0x03}},
+		{&PanTiltUp{}, visca.Message{0x01, 0x06, 0x01, 0x01, 0x01, 0x03, 0x01}},
+		{&PanTiltHome{}, visca.Message{0x01, 0x06, 0x04}},
+		{&PanTiltReset{}, visca.Message{0x01, 0x06, 0x05}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.cmd.Message(), "%T", tt.cmd)
+	}
+}
+
+func TestPanTiltPosition(t *testing.T) {
+	abs := PanTiltAbsolutePosition{}
+	assert.Nil(t, abs.SetPanSpeed(0x10))
+	assert.Nil(t, abs.SetTiltSpeed(0x08))
+	assert.Nil(t, abs.SetPosition(-40103, 21231))
+	assert.Equal(t, -40103, abs.Pan())
+	assert.Equal(t, 21231, abs.Tilt())
+	assert.Equal(t, visca.Message{
+		0x01, 0x06, 0x02, 0x10, 0x08,
+		0x0F, 0x06, 0x03, 0x05, 0x09,
+		0x05, 0x02, 0x0E, 0x0F,
+	}, abs.Message())
+
+	rel := PanTiltRelativePosition{}
+	assert.Nil(t, rel.SetPanSpeed(0x01))
+	assert.Nil(t, rel.SetTiltSpeed(0x01))
+	assert.Nil(t, rel.SetPosition(1, -1))
+	assert.Equal(t, visca.Message{
+		0x01, 0x06, 0x03, 0x01, 0x01,
+		0x00, 0x00, 0x00, 0x00, 0x01,
+		0x0F, 0x0F, 0x0F, 0x0F,
+	}, rel.Message())
+
+	assert.Equal(t, ErrInvalidPosition, rel.SetPosition(MaxPanPosition+1, 0))
+	assert.Equal(t, ErrInvalidPosition, rel.SetPosition(0, MinTiltPosition-1))
+	assert.Equal(t, 1, rel.Pan())
+
+	// 4-nibble models can't send what 5-nibble ones can
+	assert.Nil(t, rel.SetModel(visca.ModelEVID70))
+	assert.Nil(t, rel.SetPosition(-0x8000, 0x7FFF))
+	assert.Equal(t, ErrInvalidPosition, rel.SetPosition(0x8000, 0))
+	assert.Equal(t, ErrInvalidPosition, rel.SetPosition(-0x8001, 0))
+	assert.Equal(t, -0x8000, rel.Pan())
+
+	assert.Nil(t, abs.SetModel(nil))
+	assert.Equal(t, ErrInvalidPosition, abs.SetModel(visca.ModelEVID70))
+	assert.Equal(t, visca.DefaultModel, abs.Model())
+}
+
+func TestPanTiltPositionDegrees(t *testing.T) {
+	abs := PanTiltAbsolutePosition{}
+	assert.Nil(t, abs.SetPanSpeed(0x18))
+	assert.Nil(t, abs.SetTiltSpeed(0x17))
+	assert.Equal(t, visca.DefaultModel, abs.Model())
+	assert.Nil(t, abs.SetDegrees(-170, 90))
+	assert.Equal(t, -40103, abs.Pan())
+	assert.Equal(t, 21231, abs.Tilt())
+	assert.True(t, errors.Is(abs.SetDegrees(0, 91), visca.ErrOutOfRange))
+
+	// -40103 doesn't fit in 4 nibbles
+	assert.Equal(t, ErrInvalidPosition, abs.SetModel(visca.ModelPTZOptics))
+	assert.Nil(t, abs.SetPosition(0, 0))
+	assert.Nil(t, abs.SetModel(visca.ModelPTZOptics))
+	assert.Nil(t, abs.SetDegrees(45, -30))
+	assert.Equal(t, visca.Message{
+		0x01, 0x06, 0x02, 0x18, 0x17,
+		0x00, 0x02, 0x08, 0x08,
+		0x0F, 0x0E, 0x05, 0x00,
+	}, abs.Message())
+
+	rel := PanTiltRelativePosition{}
+	assert.Nil(t, rel.SetPanSpeed(0x01))
+	assert.Nil(t, rel.SetTiltSpeed(0x01))
+	assert.Nil(t, rel.SetModel(visca.ModelEVID100))
+	assert.Nil(t, rel.SetDegrees(-200, 50))
+	assert.Equal(t, visca.Message{
+		0x01, 0x06, 0x03, 0x01, 0x01,
+		0x0F, 0x04, 0x0C, 0x00,
+		0x00, 0x02, 0x0D, 0x00,
+	}, rel.Message())
+	assert.True(t, errors.Is(rel.SetDegrees(201, 0), visca.ErrOutOfRange))
+}
+
+func TestPanTiltLimits(t *testing.T) {
+	set := PanTiltLimitSet{}
+	assert.Nil(t, set.SetCorner(LimitUpRight))
+	assert.Equal(t, LimitUpRight, set.Corner())
+	assert.Nil(t, set.SetDegrees(90, 45))
+	assert.Equal(t, visca.Message{
+		0x01, 0x06, 0x07, 0x00, 0x01,
+		0x00, 0x05, 0x02, 0x0E, 0x0F,
+		0x02, 0x09, 0x07, 0x08,
+	}, set.Message())
+	assert.Equal(t, ErrInvalidCorner, set.SetCorner(2))
+
+	assert.Nil(t, set.SetModel(visca.ModelPTZOptics))
+	assert.Nil(t, set.SetCorner(LimitDownLeft))
+	assert.Nil(t, set.SetDegrees(-45, -30))
+	assert.Equal(t, visca.Message{
+		0x01, 0x06, 0x07, 0x00, 0x00,
+		0x0F, 0x0D, 0x07, 0x08,
+		0x0F, 0x0E, 0x05, 0x00,
+	}, set.Message())
+
+	clear := PanTiltLimitClear{}
+	assert.Nil(t, clear.SetCorner(LimitDownLeft))
+	assert.Equal(t, visca.Message{
+		0x01, 0x06, 0x07, 0x01, 0x00,
+		0x07, 0x0F, 0x0F, 0x0F, 0x0F,
+		0x07, 0x0F, 0x0F, 0x0F,
+	}, clear.Message())
+
+	clear.SetModel(visca.ModelEVID100)
+	assert.Nil(t, clear.SetCorner(LimitUpRight))
+	assert.Equal(t, visca.Message{
+		0x01, 0x06, 0x07, 0x01, 0x01,
+		0x07, 0x0F, 0x0F, 0x0F,
+		0x07, 0x0F, 0x0F, 0x0F,
+	}, clear.Message())
+}
+
+func TestPanTiltPositionInquiry(t *testing.T) {
+	inq := PanTiltPositionInquiry{}
+	assert.Equal(t, visca.Message{0x09, 0x06, 0x12}, inq.Message())
+	assert.Nil(t, inq.ParseCompletion(visca.Message{0x50, 0x0F, 0x06, 0x03, 0x05, 0x09, 0x05, 0x02, 0x0E, 0x0F}))
+	assert.InDelta(t, -170, inq.Pan(), 0.01)
+	assert.InDelta(t, 90, inq.Tilt(), 0.01)
+
+	// the default model's reply is a nibble too long for a 4-nibble model
+	inq.SetModel(visca.ModelEVID100)
+	assert.Equal(t, ErrInvalidReply, inq.ParseCompletion(visca.Message{0x50, 0x0F, 0x06, 0x03, 0x05, 0x09, 0x05, 0x02, 0x0E, 0x0F}))
+	assert.Nil(t, inq.ParseCompletion(visca.Message{0x50, 0x0F, 0x0A, 0x06, 0x00, 0x00, 0x01, 0x06, 0x08}))
+	assert.InDelta(t, -100, inq.Pan(), 0.01)
+	assert.InDelta(t, 25, inq.Tilt(), 0.01)
+	assert.Equal(t, ErrInvalidReply, inq.ParseCompletion(visca.Message{0x60, 0x02}))
+}
@@ -0,0 +1,50 @@
+package commands
+
+import "github.com/josh23french/visca"
+
+// PowerOn turns the camera on
+type PowerOn struct{}
+
+// Message returns the command as a Message
+func (c *PowerOn) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x00, 0x02}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *PowerOn) ParseCompletion(msg visca.Message) {}
+
+// PowerOff puts the camera in standby
+type PowerOff struct{}
+
+// Message returns the command as a Message
+func (c *PowerOff) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x00, 0x03}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *PowerOff) ParseCompletion(msg visca.Message) {}
+
+// PowerInquiry asks whether the camera is on
+type PowerInquiry struct {
+	on bool
+}
+
+// Message returns the inquiry as a Message
+func (c *PowerInquiry) Message() visca.Message {
+	return []byte{0x09, 0x04, 0x00}
+}
+
+// ParseCompletion parses the reply, 50 02 for on or 50 03 for standby
+func (c *PowerInquiry) ParseCompletion(msg visca.Message) error {
+	on, err := completionOnOff(msg)
+	if err != nil {
+		return err
+	}
+	c.on = on
+	return nil
+}
+
+// On returns true if the reply said the camera is on
+func (c *PowerInquiry) On() bool {
+	return c.on
+}
@@ -0,0 +1,24 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/josh23french/visca"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPowerCommands(t *testing.T) {
+	assert.Equal(t, visca.Message{0x01, 0x04, 0x00, 0x02}, (&PowerOn{}).Message())
+	assert.Equal(t, visca.Message{0x01, 0x04, 0x00, 0x03}, (&PowerOff{}).Message())
+	assert.Equal(t, visca.Message{0x09, 0x04, 0x00}, (&PowerInquiry{}).Message())
+}
+
+func TestPowerInquiry(t *testing.T) {
+	inq := PowerInquiry{}
+	assert.Nil(t, inq.ParseCompletion(visca.Message{0x50, 0x02}))
+	assert.True(t, inq.On())
+	assert.Nil(t, inq.ParseCompletion(visca.Message{0x50, 0x03}))
+	assert.False(t, inq.On())
+	assert.Equal(t, ErrInvalidReply, inq.ParseCompletion(visca.Message{0x50, 0x04}))
+	assert.Equal(t, ErrInvalidReply, inq.ParseCompletion(visca.Message{0x41}))
+}
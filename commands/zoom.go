@@ -0,0 +1,297 @@
+package commands
+
+import (
+	"errors"
+
+	"github.com/josh23french/visca"
+)
+
+// Error constants
+var (
+	ErrInvalidReply = errors.New("invalid reply")
+)
+
+// Zoom limits
+const (
+	MaxZoomSpeed     = 0x07
+	MaxZoomPosition  = 0xFFFF // 4 nibbles; how far a camera actually goes depends on the model
+	MaxDZoomPosition = 0xFF   // 2 nibbles
+)
+
+// ZoomParams are common to the variable speed zoom commands
+type ZoomParams struct {
+	speed uint8
+}
+
+// SetSpeed sets the speed, 0 (slowest) to 7 (fastest)
+func (p *ZoomParams) SetSpeed(speed int) error {
+	if speed < 0 || speed > MaxZoomSpeed {
+		return ErrInvalidSpeed
+	}
+	p.speed = uint8(speed)
+	return nil
+}
+
+// Speed returns the speed
+func (p *ZoomParams) Speed() int {
+	return int(p.speed)
+}
+
+// ZoomStop stops zooming
+type ZoomStop struct{}
+
+// Message returns the command as a Message
+func (c *ZoomStop) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x07, 0x00}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *ZoomStop) ParseCompletion(msg visca.Message) {}
+
+// ZoomTeleStandard zooms in at the camera's standard speed
+type ZoomTeleStandard struct{}
+
+// Message returns the command as a Message
+func (c *ZoomTeleStandard) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x07, 0x02}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *ZoomTeleStandard) ParseCompletion(msg visca.Message) {}
+
+// ZoomWideStandard zooms out at the camera's standard speed
+type ZoomWideStandard struct{}
+
+// Message returns the command as a Message
+func (c *ZoomWideStandard) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x07, 0x03}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *ZoomWideStandard) ParseCompletion(msg visca.Message) {}
+
+// ZoomTeleVariable zooms in at a given speed
+type ZoomTeleVariable struct {
+	ZoomParams
+}
+
+// Message returns the command as a Message
+func (c *ZoomTeleVariable) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x07, 0x20 | c.speed}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *ZoomTeleVariable) ParseCompletion(msg visca.Message) {}
+
+// ZoomWideVariable zooms out at a given speed
+type ZoomWideVariable struct {
+	ZoomParams
+}
+
+// Message returns the command as a Message
+func (c *ZoomWideVariable) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x07, 0x30 | c.speed}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *ZoomWideVariable) ParseCompletion(msg visca.Message) {}
+
+// ZoomDirect zooms to a position, 0 being full wide
+type ZoomDirect struct {
+	position uint16
+}
+
+// SetPosition sets the position
+func (c *ZoomDirect) SetPosition(position int) error {
+	if position < 0 || position > MaxZoomPosition {
+		return ErrInvalidPosition
+	}
+	c.position = uint16(position)
+	return nil
+}
+
+// Position returns the position
+func (c *ZoomDirect) Position() int {
+	return int(c.position)
+}
+
+// Message returns the command as a Message
+func (c *ZoomDirect) Message() visca.Message {
+	return append([]byte{0x01, 0x04, 0x47}, visca.EncodeNibbles(int(c.position), 4)...)
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *ZoomDirect) ParseCompletion(msg visca.Message) {}
+
+// DZoomOn turns digital zoom on, so zooming in carries on past the optical zoom's limit
+type DZoomOn struct{}
+
+// Message returns the command as a Message
+func (c *DZoomOn) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x06, 0x02}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *DZoomOn) ParseCompletion(msg visca.Message) {}
+
+// DZoomOff turns digital zoom off
+type DZoomOff struct{}
+
+// Message returns the command as a Message
+func (c *DZoomOff) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x06, 0x03}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *DZoomOff) ParseCompletion(msg visca.Message) {}
+
+// DZoomCombineMode makes the optical and digital zoom one range, driven by the zoom commands
+type DZoomCombineMode struct{}
+
+// Message returns the command as a Message
+func (c *DZoomCombineMode) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x36, 0x00}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *DZoomCombineMode) ParseCompletion(msg visca.Message) {}
+
+// DZoomSeparateMode controls the digital zoom separately, with DZoomDirect
+type DZoomSeparateMode struct{}
+
+// Message returns the command as a Message
+func (c *DZoomSeparateMode) Message() visca.Message {
+	return []byte{0x01, 0x04, 0x36, 0x01}
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *DZoomSeparateMode) ParseCompletion(msg visca.Message) {}
+
+// DZoomDirect sets the digital zoom, 0 being x1; the camera must be in separate mode
+type DZoomDirect struct {
+	position uint8
+}
+
+// SetPosition sets the position
+func (c *DZoomDirect) SetPosition(position int) error {
+	if position < 0 || position > MaxDZoomPosition {
+		return ErrInvalidPosition
+	}
+	c.position = uint8(position)
+	return nil
+}
+
+// Position returns the position
+func (c *DZoomDirect) Position() int {
+	return int(c.position)
+}
+
+// Message returns the command as a Message
+func (c *DZoomDirect) Message() visca.Message {
+	return append([]byte{0x01, 0x04, 0x46, 0x00, 0x00}, visca.EncodeNibbles(int(c.position), 2)...)
+}
+
+// ParseCompletion does nothing, this is not an inquiry
+func (c *DZoomDirect) ParseCompletion(msg visca.Message) {}
+
+// ZoomPositionInquiry asks for the zoom position
+type ZoomPositionInquiry struct {
+	position int
+}
+
+// Message returns the inquiry as a Message
+func (c *ZoomPositionInquiry) Message() visca.Message {
+	return []byte{0x09, 0x04, 0x47}
+}
+
+// ParseCompletion parses the reply, 50 0p 0q 0r 0s
+func (c *ZoomPositionInquiry) ParseCompletion(msg visca.Message) error {
+	v, err := completionNibbles(msg, 4)
+	if err != nil {
+		return err
+	}
+	c.position = v
+	return nil
+}
+
+// Position returns the zoom position from the reply
+func (c *ZoomPositionInquiry) Position() int {
+	return c.position
+}
+
+// DZoomModeInquiry asks whether digital zoom is on
+type DZoomModeInquiry struct {
+	on bool
+}
+
+// Message returns the inquiry as a Message
+func (c *DZoomModeInquiry) Message() visca.Message {
+	return []byte{0x09, 0x04, 0x06}
+}
+
+// ParseCompletion parses the reply, 50 02 for on or 50 03 for off
+func (c *DZoomModeInquiry) ParseCompletion(msg visca.Message) error {
+	on, err := completionOnOff(msg)
+	if err != nil {
+		return err
+	}
+	c.on = on
+	return nil
+}
+
+// On returns true if the reply said digital zoom is on
+func (c *DZoomModeInquiry) On() bool {
+	return c.on
+}
+
+// DZoomCSModeInquiry asks whether digital zoom is in combine or separate mode
+type DZoomCSModeInquiry struct {
+	separate bool
+}
+
+// Message returns the inquiry as a Message
+func (c *DZoomCSModeInquiry) Message() visca.Message {
+	return []byte{0x09, 0x04, 0x36}
+}
+
+// ParseCompletion parses the reply, 50 00 for combine or 50 01 for separate
+func (c *DZoomCSModeInquiry) ParseCompletion(msg visca.Message) error {
+	if len(msg) != 2 || msg.Type() != visca.MsgCompletion || msg[1] > 0x01 {
+		return ErrInvalidReply
+	}
+	c.separate = msg[1] == 0x01
+	return nil
+}
+
+// Separate returns true if the reply said digital zoom is in separate mode
+func (c *DZoomCSModeInquiry) Separate() bool {
+	return c.separate
+}
+
+// DZoomPositionInquiry asks for the digital zoom position
+type DZoomPositionInquiry struct {
+	position int
+}
+
+// Message returns the inquiry as a Message
+func (c *DZoomPositionInquiry) Message() visca.Message {
+	return []byte{0x09, 0x04, 0x46}
+}
+
+// ParseCompletion parses the reply, 50 00 00 0p 0q
+func (c *DZoomPositionInquiry) ParseCompletion(msg visca.Message) error {
+	v, err := completionNibbles(msg, 4)
+	if err != nil {
+		return err
+	}
+	if v > MaxDZoomPosition {
+		return ErrInvalidReply
+	}
+	c.position = v
+	return nil
+}
+
+// Position returns the digital zoom position from the reply
+func (c *DZoomPositionInquiry) Position() int {
+	return c.position
+}
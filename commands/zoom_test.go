@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/josh23french/visca"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZoomCommands(t *testing.T) {
+	var params ZoomParams
+	assert.Nil(t, params.SetSpeed(5))
+	assert.Equal(t, 5, params.Speed())
+	assert.Equal(t, ErrInvalidSpeed, params.SetSpeed(8))
+	assert.Equal(t, ErrInvalidSpeed, params.SetSpeed(-1))
+
+	direct := ZoomDirect{}
+	assert.Nil(t, direct.SetPosition(0x4000))
+	assert.Equal(t, 0x4000, direct.Position())
+	assert.Equal(t, ErrInvalidPosition, direct.SetPosition(0x10000))
+
+	dDirect := DZoomDirect{}
+	assert.Nil(t, dDirect.SetPosition(0xEB))
+	assert.Equal(t, 0xEB, dDirect.Position())
+	assert.Equal(t, ErrInvalidPosition, dDirect.SetPosition(0x100))
+
+	var tests = []struct {
+		cmd interface {
+			Message() visca.Message
+		}
+		want visca.Message
+	}{
+		{&ZoomStop{}, visca.Message{0x01, 0x04, 0x07, 0x00}},
+		{&ZoomTeleStandard{}, visca.Message{0x01, 0x04, 0x07, 0x02}},
+		{&ZoomWideStandard{}, visca.Message{0x01, 0x04, 0x07, 0x03}},
+		{&ZoomTeleVariable{params}, visca.Message{0x01, 0x04, 0x07, 0x25}},
+		{&ZoomWideVariable{params}, visca.Message{0x01, 0x04, 0x07, 0x35}},
+		{&direct, visca.Message{0x01, 0x04, 0x47, 0x04, 0x00, 0x00, 0x00}},
+		{&DZoomOn{}, visca.Message{0x01, 0x04, 0x06, 0x02}},
+		{&DZoomOff{}, visca.Message{0x01, 0x04, 0x06, 0x03}},
+		{&DZoomCombineMode{}, visca.Message{0x01, 0x04, 0x36, 0x00}},
+		{&DZoomSeparateMode{}, visca.Message{0x01, 0x04, 0x36, 0x01}},
+		{&dDirect, visca.Message{0x01, 0x04, 0x46, 0x00, 0x00, 0x0E, 0x0B}},
+		{&ZoomPositionInquiry{}, visca.Message{0x09, 0x04, 0x47}},
+		{&DZoomModeInquiry{}, visca.Message{0x09, 0x04, 0x06}},
+		{&DZoomCSModeInquiry{}, visca.Message{0x09, 0x04, 0x36}},
+		{&DZoomPositionInquiry{}, visca.Message{0x09, 0x04, 0x46}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.cmd.Message(), "%T", tt.cmd)
+	}
+}
+
+func TestZoomInquiries(t *testing.T) {
+	pos := ZoomPositionInquiry{}
+	assert.Nil(t, pos.ParseCompletion(visca.Message{0x50, 0x02, 0x0A, 0x0B, 0x0C}))
+	assert.Equal(t, 0x2ABC, pos.Position())
+	assert.Equal(t, ErrInvalidReply, pos.ParseCompletion(visca.Message{0x50, 0x02, 0x0A, 0x0B}))
+	assert.Equal(t, ErrInvalidReply, pos.ParseCompletion(visca.Message{0x50, 0x02, 0x0A, 0x0B, 0x1C}))
+	assert.Equal(t, ErrInvalidReply, pos.ParseCompletion(visca.Message{0x60, 0x02, 0x0A, 0x0B, 0x0C}))
+	assert.Equal(t, 0x2ABC, pos.Position())
+
+	mode := DZoomModeInquiry{}
+	assert.Nil(t, mode.ParseCompletion(visca.Message{0x50, 0x02}))
+	assert.True(t, mode.On())
+	assert.Nil(t, mode.ParseCompletion(visca.Message{0x50, 0x03}))
+	assert.False(t, mode.On())
+	assert.Equal(t, ErrInvalidReply, mode.ParseCompletion(visca.Message{0x50, 0x04}))
+
+	cs := DZoomCSModeInquiry{}
+	assert.Nil(t, cs.ParseCompletion(visca.Message{0x50, 0x01}))
+	assert.True(t, cs.Separate())
+	assert.Nil(t, cs.ParseCompletion(visca.Message{0x50, 0x00}))
+	assert.False(t, cs.Separate())
+	assert.Equal(t, ErrInvalidReply, cs.ParseCompletion(visca.Message{0x50, 0x02}))
+
+	dpos := DZoomPositionInquiry{}
+	assert.Nil(t, dpos.ParseCompletion(visca.Message{0x50, 0x00, 0x00, 0x0E, 0x0B}))
+	assert.Equal(t, 0xEB, dpos.Position())
+	assert.Equal(t, ErrInvalidReply, dpos.ParseCompletion(visca.Message{0x50, 0x00, 0x01, 0x0E, 0x0B}))
+}
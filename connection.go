@@ -18,25 +18,110 @@ package visca
 
 import (
 	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
 	"os"
+	"sort"
 	"strings"
+	"sync"
 )
 
 // Connection implements a VISCA connection
 type Connection interface {
-	Start() error
-	Stop()
-	Send(*Packet) error
-	SetReceiveQueue(chan *Packet)
+	Start() error                 // Start the Connection; returns error if it failed to be started
+	Stop()                        // Stop the Connection, can have no error
+	Send(*Packet) error           // Send a packet out the Connection, may be queued
+	SetReceiveQueue(chan *Packet) // Tell the Connection where to send received packets
+}
+
+// Dialer is implemented by Connections that run over a byte stream, like serial ports and TCP connections
+//
+// Dial opens a stream to the camera without starting the Connection. It's for tools like viscasniff that
+// pass the bytes through untouched, but want to find the camera the same way as everything else.
+type Dialer interface {
+	Dial() (io.ReadWriteCloser, error)
 }
 
 // Error constants
 var (
-	ErrNotStarted           = errors.New("connection not started")
-	ErrIncompletePacketSent = errors.New("incomplete packet sent")
+	ErrNotStarted               = errors.New("connection not started")
+	ErrIncompletePacketSent     = errors.New("incomplete packet sent")
+	ErrConnectionPathNotCharDev = errors.New("connection path is not a character device")
+	ErrConnectionPathInvalid    = errors.New("connection path invalid")
+	ErrUnknownScheme            = errors.New("unknown connection scheme")
+	ErrSchemeRegistered         = errors.New("connection scheme already registered")
+	ErrInvalidScheme            = errors.New("invalid connection scheme")
 )
 
+// SchemeFactory creates a Connection from a parsed connection string
+type SchemeFactory func(*url.URL) (Connection, error)
+
+var (
+	schemesMu sync.RWMutex
+	// schemes maps connection string schemes to the factories that handle them
+	schemes = map[string]SchemeFactory{
+		"tcp":      networkScheme("tcp"),
+		"udp":      networkScheme("udp"),
+		"unix":     networkScheme("unix"),
+		"serial":   serialScheme,
+		"visca-ip": ipScheme,
+		"replay":   replayScheme,
+	}
+)
+
+// RegisterScheme makes a kind of Connection available to NewConnectionFromString under the given URL scheme
+//
+// It's meant to be called from an init function, like database/sql drivers. Registering a scheme twice,
+// including one of the built-in ones, is an error.
+//
+// Example
+//
+//  func init() {
+//    visca.RegisterScheme("mock", func(u *url.URL) (visca.Connection, error) {
+//      return newMockConnection(u.Host), nil
+//    })
+//  }
+func RegisterScheme(name string, factory func(*url.URL) (Connection, error)) error {
+	name = strings.ToLower(name)
+	u, err := url.Parse(name + "://")
+	if err != nil || u.Scheme != name || factory == nil {
+		return fmt.Errorf("%w: %q", ErrInvalidScheme, name)
+	}
+
+	schemesMu.Lock()
+	defer schemesMu.Unlock()
+	if _, ok := schemes[name]; ok {
+		return fmt.Errorf("%w: %q", ErrSchemeRegistered, name)
+	}
+	schemes[name] = factory
+	return nil
+}
+
+// Schemes returns the registered URL schemes
+func Schemes() []string {
+	schemesMu.RLock()
+	defer schemesMu.RUnlock()
+	names := make([]string, 0, len(schemes))
+	for name := range schemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // NewConnectionFromString creates a Connection from a string
+//
+// The string is either a path to a serial device, a host:port to connect to over TCP, or a URL whose
+// scheme picks the kind of Connection; more can be added with RegisterScheme:
+//
+//  tcp://host:port
+//  udp://host:port
+//  unix:///path/to/socket
+//  serial:///dev/ttyUSB0?baud=38400&parity=none&stop=1
+//  visca-ip://host[:port]
+//  replay:///path/to/recording.jsonl
 func NewConnectionFromString(connString string) (Connection, error) {
 	if info, err := os.Stat(connString); err == nil {
 		// connString is a path to a file...
@@ -46,18 +131,61 @@ func NewConnectionFromString(connString string) (Connection, error) {
 			return NewSerialConnection(connString)
 		}
 
-		return nil, errors.New("connection string is a file, but is not a character device")
+		return nil, ErrConnectionPathNotCharDev
+	}
+
+	if !strings.Contains(connString, "://") {
+		// a bare host:port
+		if _, _, err := net.SplitHostPort(connString); err != nil {
+			return nil, ErrConnectionPathInvalid
+		}
+		return NewNetworkConnection("tcp", connString)
+	}
+
+	u, err := url.Parse(connString)
+	if err != nil {
+		return nil, ErrConnectionPathInvalid
+	}
+	schemesMu.RLock()
+	factory, ok := schemes[u.Scheme]
+	schemesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownScheme, u.Scheme)
+	}
+	return factory(u)
+}
+
+// networkScheme returns a factory for NetworkConnections using the given protocol
+func networkScheme(proto string) SchemeFactory {
+	return func(u *url.URL) (Connection, error) {
+		address := u.Host
+		if proto == "unix" {
+			address = u.Host + u.Path // unix:///tmp/visca.sock as well as unix://visca.sock
+		}
+		if address == "" {
+			return nil, ErrConnectionPathInvalid
+		}
+		return NewNetworkConnection(proto, address)
 	}
-	if strings.HasPrefix(connString, "udp://") {
-		hostPort := strings.TrimPrefix(connString, "udp://")
-		return NewNetworkConnection("udp", hostPort)
+}
+
+// serialScheme creates a SerialConnection from a serial:// URL, with options in the query
+func serialScheme(u *url.URL) (Connection, error) {
+	device := u.Host + u.Path // serial://COM3 as well as serial:///dev/ttyUSB0
+	if device == "" {
+		return nil, ErrConnectionPathInvalid
 	}
-	if strings.HasPrefix(connString, "unix://") {
-		path := strings.TrimPrefix(connString, "unix://")
-		return NewNetworkConnection("unix", path)
+	mode, err := ParseSerialMode(u.Query())
+	if err != nil {
+		return nil, err
 	}
+	return NewSerialConnectionWithMode(device, mode)
+}
 
-	// Default is a TCP conn
-	hostPort := strings.TrimPrefix(connString, "tcp://")
-	return NewNetworkConnection("tcp", hostPort)
+// ipScheme creates an IPConnection from a visca-ip:// URL
+func ipScheme(u *url.URL) (Connection, error) {
+	if u.Host == "" {
+		return nil, ErrConnectionPathInvalid
+	}
+	return NewIPConnection(u.Host)
 }
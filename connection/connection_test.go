@@ -50,3 +50,19 @@ func TestUDPFromString(t *testing.T) {
 	}
 	assert.NotNil(t, conn, "conn should not be nil")
 }
+
+func TestVISCAIPFromString(t *testing.T) {
+	conn, err := FromString("visca-ip://127.0.0.1")
+	assert.Nil(t, err)
+	assert.NotNil(t, conn, "conn should not be nil")
+}
+
+func TestSerialFromString(t *testing.T) {
+	conn, err := FromString("serial:///dev/ttyUSB0?baud=115200")
+	assert.Nil(t, err)
+	assert.Equal(t, "/dev/ttyUSB0", conn.(*Serial).Device())
+	assert.Equal(t, 115200, conn.(*Serial).Mode().BaudRate)
+
+	_, err = FromString("serial:///dev/ttyUSB0?parity=maybe")
+	assert.NotNil(t, err)
+}
@@ -18,96 +18,18 @@ package connection
 
 import (
 	"github.com/josh23french/visca"
-	"github.com/rs/zerolog/log"
 	"go.bug.st/serial"
 )
 
 // Serial implements the Iface interface for serial VISCA connections
-type Serial struct {
-	device       string
-	port         *serial.Port
-	scanner      *visca.Scanner
-	receiveQueue chan *visca.Packet
-	quit         chan struct{}
-}
+type Serial = visca.SerialConnection
 
-// NewSerial creates a new SerialIface
+// NewSerial creates a new SerialIface with the default 9600 8N1 mode
 func NewSerial(device string) (*Serial, error) {
-	return &Serial{
-		device:       device,
-		port:         nil,
-		scanner:      nil,
-		receiveQueue: nil,
-		quit:         make(chan struct{}),
-	}, nil
-}
-
-// Start the interface
-func (i *Serial) Start() error {
-	log.Info().Msgf("Opening serial interface %v...", i.device)
-	mode := &serial.Mode{
-		BaudRate: 9600,
-		DataBits: 8,
-		Parity:   serial.NoParity,
-		StopBits: 1,
-	}
-	port, err := serial.Open(i.device, mode)
-	if err != nil {
-		return err
-	}
-	log.Info().Msgf("Opened serial interface %v", i.device)
-	i.port = &port
-
-	i.quit = make(chan struct{})
-
-	i.scanner = visca.NewScanner(*i.port)
-
-	go i.scanner.Scan(i.receiveQueue, i.quit)
-	log.Info().Msgf("Started read loop from serial interface %v", i.device)
-
-	return nil
-}
-
-// Stop the interface
-func (i *Serial) Stop() {
-	if i.port == nil {
-		log.Warn().Msg("Never Started")
-		return
-	}
-
-	// Stop the receive goroutine first
-	close(i.quit)
-
-	// Then close the port
-	port := *i.port
-	err := port.Close()
-	if err != nil {
-		log.Warn().Err(err).Msgf("Error stopping serial interface %v", i.device)
-	}
-}
-
-// Send a packet
-func (i *Serial) Send(pkt *visca.Packet) error {
-	if i.port == nil {
-		log.Warn().Msg("not started")
-		return ErrIfaceNotStarted
-	}
-	port := *i.port
-	log.Debug().Msgf("Sending packet %v via %v", pkt, i.device)
-	written, err := port.Write(pkt.Bytes())
-	if err != nil {
-		return err
-	}
-
-	if written != len(pkt.Bytes()) {
-		return ErrIncompletePacketSent
-	}
-
-	return nil
+	return visca.NewSerialConnection(device)
 }
 
-// SetReceiveQueue for received packets
-func (i *Serial) SetReceiveQueue(q chan *visca.Packet) {
-	i.receiveQueue = q
-	return
+// NewSerialWithMode creates a new SerialIface with the given baud rate, parity, etc.
+func NewSerialWithMode(device string, mode *serial.Mode) (*Serial, error) {
+	return visca.NewSerialConnectionWithMode(device, mode)
 }
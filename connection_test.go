@@ -1,7 +1,9 @@
 package visca
 
 import (
+	"errors"
 	"net"
+	"net/url"
 	"strings"
 	"testing"
 
@@ -45,3 +47,36 @@ func TestUDPConnectionFromString(t *testing.T) {
 	}
 	assert.NotNil(t, conn, "conn should not be nil")
 }
+
+func TestUnknownSchemeFromString(t *testing.T) {
+	_, err := NewConnectionFromString("nope://127.0.0.1:5678")
+	assert.True(t, errors.Is(err, ErrUnknownScheme))
+
+	_, err = NewConnectionFromString("not a host")
+	assert.Equal(t, ErrConnectionPathInvalid, err)
+}
+
+func TestRegisterScheme(t *testing.T) {
+	var got *url.URL
+	err := RegisterScheme("test-null", func(u *url.URL) (Connection, error) {
+		got = u
+		return &NullConnection{}, nil
+	})
+	assert.Nil(t, err)
+	assert.Contains(t, Schemes(), "test-null")
+
+	conn, err := NewConnectionFromString("test-null://somewhere?x=1")
+	assert.Nil(t, err)
+	assert.IsType(t, &NullConnection{}, conn)
+	assert.Equal(t, "somewhere", got.Host)
+	assert.Equal(t, "1", got.Query().Get("x"))
+
+	err = RegisterScheme("test-null", func(u *url.URL) (Connection, error) { return nil, nil })
+	assert.True(t, errors.Is(err, ErrSchemeRegistered))
+	err = RegisterScheme("tcp", func(u *url.URL) (Connection, error) { return nil, nil })
+	assert.True(t, errors.Is(err, ErrSchemeRegistered))
+	err = RegisterScheme("no spaces", func(u *url.URL) (Connection, error) { return nil, nil })
+	assert.True(t, errors.Is(err, ErrInvalidScheme))
+	err = RegisterScheme("nil", nil)
+	assert.True(t, errors.Is(err, ErrInvalidScheme))
+}
@@ -17,8 +17,11 @@
 package visca
 
 import (
+	"context"
 	"errors"
+	"sync"
 
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
@@ -26,6 +29,12 @@ import (
 var (
 	ErrNoCameraConnection  = errors.New("no camera connection")
 	ErrInvalidCameraNumber = errors.New("invalid camera number")
+	ErrNotARequest         = errors.New("message is not a command or inquiry")
+	ErrControllerStopped   = errors.New("controller stopped")
+	ErrCommandFailed       = errors.New("camera replied with an error")
+	ErrInvalidSpeed        = errors.New("invalid speed")
+	ErrInvalidReply        = errors.New("invalid reply to inquiry")
+	ErrNoReply             = errors.New("camera didn't reply")
 )
 
 // Controller represents a high-level VISCA PTZ controller
@@ -38,64 +47,75 @@ var (
 //    // Do something
 //  }
 type Controller struct {
+	mu           sync.RWMutex // protects connections, models, limits, bus, and camera
 	connections  []Connection
+	models       []*Model
+	limits       []*SoftLimits
+	trackers     []*tracker
+	bus          *Bus
 	camera       int
-	sendQueue    []Packet
 	receiveQueue chan *Packet
+	renumerate   chan struct{}
+	events       *eventHub
+	logger       zerolog.Logger
 	quit         chan struct{}
+	wg           sync.WaitGroup // the Start goroutines
 }
 
 // NewController creates a new controller with no cameras
 func NewController() *Controller {
+	trackers := make([]*tracker, 9) // one for each camera, plus one for broadcasts
+	for i := range trackers {
+		trackers[i] = newTracker()
+	}
 	return &Controller{
-		connections:  make([]Connection, 8), // 7 connections total; 0 is not used
-		camera:       1,                     // starts with camera 1 selected
-		sendQueue:    make([]Packet, 0),     // stores commands/inquiries/cancels to be sent
-		receiveQueue: make(chan *Packet),    // channel of incoming packets
-		quit:         make(chan struct{}),   // used to stop the processReceiveQueue goroutine
+		connections:  make([]Connection, 8),  // 7 connections total; 0 is not used
+		models:       make([]*Model, 8),      // each camera's model; nil is DefaultModel
+		limits:       make([]*SoftLimits, 8), // each camera's soft limits; nil has none
+		trackers:     trackers,               // outstanding commands/inquiries for each camera
+		camera:       1,                      // starts with camera 1 selected
+		receiveQueue: make(chan *Packet),     // channel of incoming packets
+		renumerate:   make(chan struct{}, 1), // signals the bus needs to be enumerated again
+		events:       newEventHub(),          // subscribers to Events
+		logger:       log.Logger,             // zerolog's global logger until SetLogger
+		quit:         make(chan struct{}),    // used to stop the processReceiveQueue goroutine
 	}
 }
 
+// SetLogger sets where the Controller logs; it must be called before Start
+func (c *Controller) SetLogger(logger zerolog.Logger) {
+	c.logger = logger
+	c.events.logger = logger
+}
+
 // Start the Controller
 func (c *Controller) Start() error {
+	c.wg.Add(2)
 	go c.processReceiveQueue()
+	go c.processNetworkChanges()
 	return nil
 }
 
-// Stop the Controller
+// Stop the Controller, waiting for it to finish with the packet it's handling
 func (c *Controller) Stop() {
 	close(c.quit)
+	c.wg.Wait()
 }
 
 // processReceiveQueue processes packets from the receiveQueue
 func (c *Controller) processReceiveQueue() {
+	defer c.wg.Done()
 loop:
 	for {
 		select {
 		case <-c.quit:
 			break loop
-		default:
-			pkt := <-c.receiveQueue
-			// we're only interested in packets for 0 (controller) or 8 (broadcast, which includes the controller)
-			if pkt.destination != 0 && pkt.destination != 8 {
-				log.Debug().Msg("ignoring packet not for us")
-				continue
-			}
-			switch pkt.Message.Type() {
-			case MsgACK:
-				// peek the most recent command from the send queue and send something on the ack channel
-				// socket := pkt.Message.Socket()
-				// c.sendQueue[socket].pop()
-				continue
-			case MsgCompletion, MsgError:
-				// pop the most recent command from the correct buffer and send the packet on the completion channel
-				continue
-			default:
-				// shouldn't get any other message types to the controller...
-				// but there's nothing we can do with them but log them
-				log.Warn().Msgf("got %v message from %v", pkt.Message.Type(), pkt.Source())
-				continue
+		case pkt, ok := <-c.receiveQueue:
+			if !ok {
+				// a connection closed the queue out from under us; nothing more will arrive
+				return
 			}
+			c.handlePacket(pkt)
 		}
 	}
 	if c.receiveQueue != nil {
@@ -103,11 +123,56 @@ loop:
 	}
 }
 
+// handlePacket dispatches a single received packet
+func (c *Controller) handlePacket(pkt *Packet) {
+	// we're only interested in packets for 0 (controller) or 8 (broadcast, which includes the controller)
+	if pkt.destination != 0 && pkt.destination != BroadcastAddress {
+		c.logger.Debug().Msg("ignoring packet not for us")
+		return
+	}
+	switch pkt.Message.Type() {
+	case MsgACK, MsgCompletion, MsgError:
+		// pair it with the command or inquiry that's waiting for it
+		t := c.trackers[pkt.Source()]
+		req := t.handle(pkt)
+		if req == nil {
+			c.logger.Warn().Msgf("got %v for socket %v from %v, but nothing was waiting for it", pkt.Message.Type(), pkt.Message.Socket(), pkt.Source())
+			return
+		}
+		if pkt.Message.Type() == MsgACK && t.shouldCancel(req) {
+			// whoever sent it gave up before it was ACKed
+			err := c.Cancel(pkt.Source(), req.socket)
+			if err != nil {
+				c.logger.Warn().Err(err).Msgf("error canceling socket %v on camera %v", req.socket, pkt.Source())
+			}
+		}
+	case MsgNetworkChange:
+		// a camera was added to or removed from the chain; don't block the receive queue waiting on it
+		c.logger.Info().Msgf("network change reported by %v", pkt.Source())
+		select {
+		case c.renumerate <- struct{}{}:
+		default:
+			// already going to do it
+		}
+	case MsgAddressSet, MsgCommand:
+		// our own broadcasts come back to us once they've made it around the chain
+		if !pkt.IsBroadcast() || c.trackers[BroadcastAddress].next(pkt) == nil {
+			c.logger.Warn().Msgf("got %v message from %v", pkt.Message.Type(), pkt.Source())
+		}
+	default:
+		// shouldn't get any other message types to the controller...
+		// but there's nothing we can do with them but log them
+		c.logger.Warn().Msgf("got %v message from %v", pkt.Message.Type(), pkt.Source())
+	}
+}
+
 // AddCamera adds a camera to the controller
 func (c *Controller) AddCamera(num int, camera Connection) error {
 	if num > 7 || num <= 0 {
 		return ErrInvalidCameraNumber
 	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.connections[num] = camera
 	camera.SetReceiveQueue(c.receiveQueue)
 	camera.Start()
@@ -119,6 +184,8 @@ func (c *Controller) RemoveCamera(num int) error {
 	if num > 7 || num <= 0 {
 		return ErrInvalidCameraNumber
 	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if c.connections[num] == nil {
 		return ErrNoCameraConnection
 	}
@@ -129,20 +196,156 @@ func (c *Controller) RemoveCamera(num int) error {
 
 // SetCamera selects the camera the controller is currently working on
 func (c *Controller) SetCamera(num int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.camera = num
 }
 
+// SetModel sets the model of the given camera, which PanTiltAbsolute, PanTiltRelative and PanTiltPosition
+// convert degrees for; nil goes back to DefaultModel
+func (c *Controller) SetModel(num int, model *Model) error {
+	if num > 7 || num <= 0 {
+		return ErrInvalidCameraNumber
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.models[num] = model
+	return nil
+}
+
+// Model returns the model of the given camera
+func (c *Controller) Model(num int) *Model {
+	if num > 7 || num <= 0 {
+		return DefaultModel
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.models[num] == nil {
+		return DefaultModel
+	}
+	return c.models[num]
+}
+
+// currentCamera returns the current camera and its model
+func (c *Controller) currentCamera() (int, *Model) {
+	c.mu.RLock()
+	camera := c.camera
+	c.mu.RUnlock()
+	return camera, c.Model(camera)
+}
+
+// Reply is a camera's answer to a command or inquiry
+type Reply struct {
+	Camera  int     // the camera that replied
+	Socket  uint8   // the socket the command executed in; always 0 for inquiries
+	Message Message // the Completion or Error message, including any inquiry data
+}
+
+// Do sends a command or inquiry to the given camera and waits for the reply
+//
+// For commands, Do waits for the ACK and then for the Completion or Error. If the camera replies with an
+// Error, the Reply is returned along with a *ReplyError. If ctx is done first, its error is returned and
+// the command is canceled on the camera, as soon as the camera has ACKed it. If the ACK (or an inquiry's
+// reply) is lost, ErrNoReply is returned once a later request or reply finds it stale.
+func (c *Controller) Do(ctx context.Context, camera int, msg Message) (Reply, error) {
+	if len(msg) == 0 || (msg.Type() != MsgCommand && msg.Type() != MsgInquiry) {
+		return Reply{}, ErrNotARequest
+	}
+	req, err := c.send(camera, msg)
+	if err != nil {
+		return Reply{}, err
+	}
+
+	select {
+	case <-req.done:
+	case <-ctx.Done():
+		// the camera may still reply, so the request stays tracked to keep the sockets lined up
+		// and the CommandCanceled error goes to the right place; if it never does, it expires
+		if socket, ok := c.trackers[camera].cancelOnACK(req); ok {
+			err := c.Cancel(camera, socket)
+			if err != nil {
+				c.logger.Warn().Err(err).Msgf("error canceling socket %v on camera %v", socket, camera)
+			}
+		}
+		return Reply{}, ctx.Err()
+	case <-c.quit:
+		return Reply{}, ErrControllerStopped
+	}
+	if req.err != nil {
+		return Reply{}, req.err
+	}
+
+	reply := Reply{
+		Camera:  req.reply.Source(),
+		Socket:  req.reply.Message.Socket(),
+		Message: req.reply.Message,
+	}
+	if reply.Message.Type() == MsgError {
+		replyErr, err := ParseError(reply.Message)
+		if err != nil {
+			return reply, ErrCommandFailed
+		}
+		return reply, replyErr
+	}
+	return reply, nil
+}
+
+// Cancel cancels the command executing in the given socket of the given camera
+//
+// The command's pending Do call, if any, gets a *ReplyError with CommandCanceled.
+func (c *Controller) Cancel(camera int, socket uint8) error {
+	msg, err := NewCancelMessage(socket)
+	if err != nil {
+		return err
+	}
+	_, err = c.send(camera, msg)
+	return err
+}
+
 // sendMessage crafts a packet from the given Message and sends it to the current Camera
 func (c *Controller) sendMessage(msg Message) error {
-	conn := c.connections[c.camera]
+	c.mu.RLock()
+	camera := c.camera
+	c.mu.RUnlock()
+	_, err := c.send(camera, msg)
+	return err
+}
+
+// send crafts a packet from the given Message and sends it to the given camera
+//
+// Commands and inquiries are tracked until the camera replies to them; the returned request is nil for
+// any other type of message.
+func (c *Controller) send(camera int, msg Message) (*request, error) {
+	if camera > 7 || camera <= 0 {
+		return nil, ErrInvalidCameraNumber
+	}
+	c.mu.RLock()
+	conn := c.connections[camera]
+	c.mu.RUnlock()
 	if conn == nil {
-		return ErrNoCameraConnection
+		return nil, ErrNoCameraConnection
 	}
-	pkt, err := NewPacket(0, c.camera, msg)
+	pkt, err := NewPacket(0, camera, msg)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	var req *request
+	switch msg.Type() {
+	case MsgCommand, MsgInquiry:
+		// track it before it's sent, so we can't miss a fast reply
+		req = newRequest(msg)
+		c.trackers[camera].add(req)
+	}
+
+	err = conn.Send(pkt)
+	if err != nil {
+		if req != nil {
+			c.trackers[camera].remove(req)
+		}
+		return nil, err
 	}
-	return conn.Send(pkt)
+	return req, nil
 }
 
 //
@@ -150,58 +353,318 @@ func (c *Controller) sendMessage(msg Message) error {
 //
 
 // PresetReset resets the preset on the current camera
-func (c *Controller) PresetReset(num uint8) {
-	c.sendMessage([]byte{0x01, 0x04, 0x3F, 0x00, num})
+func (c *Controller) PresetReset(num uint8) error {
+	return c.sendMessage([]byte{0x01, 0x04, 0x3F, 0x00, num})
 }
 
 // PresetSet sets the preset on the current camera
-func (c *Controller) PresetSet(num uint8) {
-	c.sendMessage([]byte{0x01, 0x04, 0x3F, 0x01, num})
+func (c *Controller) PresetSet(num uint8) error {
+	return c.sendMessage([]byte{0x01, 0x04, 0x3F, 0x01, num})
 }
 
 // PresetRecall recalls the preset on the current camera
-func (c *Controller) PresetRecall(num uint8) {
-	c.sendMessage([]byte{0x01, 0x04, 0x3F, 0x02, num})
+func (c *Controller) PresetRecall(num uint8) error {
+	return c.sendMessage([]byte{0x01, 0x04, 0x3F, 0x02, num})
 }
 
 //
 // Command Set: Pan Tilt Drive
 //
 
-// PanTilt is the high-level PT control
-func (c *Controller) PanTilt(pan int, tilt int) {
+// Fastest Pan-tiltDrive speeds
+const (
+	MaxPanSpeed  = 0x18
+	MaxTiltSpeed = 0x17
+)
+
+// PanTilt is the high-level PT control, e.g. for a joystick
+//
+// pan and tilt are speeds: negative pans left or tilts down, positive pans right or tilts up, and 0 holds
+// that axis still. They're clamped to the fastest speeds, 0x18 for pan and 0x17 for tilt. If both are 0,
+// the camera stops.
+func (c *Controller) PanTilt(pan int, tilt int) error {
 	if pan == 0 && tilt == 0 {
-		c.PanTiltStop()
-		return
+		return c.PanTiltStop()
 	}
-	c.sendMessage([]byte{0x01, 0x04, 0x07, 0x00})
+	panSpeed, panDir := driveSpeed(pan, MaxPanSpeed, 0x01, 0x02)
+	tiltSpeed, tiltDir := driveSpeed(tilt, MaxTiltSpeed, 0x02, 0x01)
+	return c.sendMessage([]byte{0x01, 0x06, 0x01, panSpeed, tiltSpeed, panDir, tiltDir})
+}
+
+// driveSpeed turns a signed speed into the speed and direction for Pan-tiltDrive
+func driveSpeed(v int, max int, negative, positive byte) (speed byte, direction byte) {
+	switch {
+	case v < 0:
+		v, direction = -v, negative
+	case v > 0:
+		direction = positive
+	default:
+		return 0x01, 0x03
+	}
+	if v > max {
+		v = max
+	}
+	return byte(v), direction
+}
+
+// PanTiltSpeeds are how fast to pan and tilt to a position
+type PanTiltSpeeds struct {
+	Pan  int // 1 to 0x18
+	Tilt int // 1 to 0x17
+}
+
+// bytes checks the speeds and returns them as they're sent
+func (s PanTiltSpeeds) bytes() (byte, byte, error) {
+	if s.Pan < 1 || s.Pan > MaxPanSpeed || s.Tilt < 1 || s.Tilt > MaxTiltSpeed {
+		return 0, 0, ErrInvalidSpeed
+	}
+	return byte(s.Pan), byte(s.Tilt), nil
+}
+
+// PanTiltAbsolute moves the current camera to a position, in degrees from home
+//
+// Positive pan is right and positive tilt is up. The angles are converted for the camera's model, see
+// SetModel, and an error wrapping ErrOutOfRange is returned if the camera can't get there. If the camera has
+// SoftLimits, the position is checked against them too.
+func (c *Controller) PanTiltAbsolute(pan, tilt float64, speeds PanTiltSpeeds) error {
+	panSpeed, tiltSpeed, err := speeds.bytes()
+	if err != nil {
+		return err
+	}
+	camera, model := c.currentCamera()
+	panPos, err := model.PanPosition(pan)
+	if err != nil {
+		return err
+	}
+	tiltPos, err := model.TiltPosition(tilt)
+	if err != nil {
+		return err
+	}
+	panPos, tiltPos, err = c.limitAbsolute(camera, model, panPos, tiltPos)
+	if err != nil {
+		return err
+	}
+	return c.panTiltPosition(camera, model, 0x02, panSpeed, tiltSpeed, panPos, tiltPos)
+}
+
+// PanTiltRelative moves the current camera by some degrees from where it is
+//
+// Positive pan is right and positive tilt is up. The angles are converted for the camera's model, see
+// SetModel, and an error wrapping ErrOutOfRange is returned if they're more than its whole range. If the
+// camera has SoftLimits, it's asked where it is, and where it would end up is checked against them.
+func (c *Controller) PanTiltRelative(pan, tilt float64, speeds PanTiltSpeeds) error {
+	panSpeed, tiltSpeed, err := speeds.bytes()
+	if err != nil {
+		return err
+	}
+	camera, model := c.currentCamera()
+	panPos, err := model.PanOffset(pan)
+	if err != nil {
+		return err
+	}
+	tiltPos, err := model.TiltOffset(tilt)
+	if err != nil {
+		return err
+	}
+	panPos, tiltPos, err = c.limitRelative(camera, model, panPos, tiltPos)
+	if err != nil {
+		return err
+	}
+	return c.panTiltPosition(camera, model, 0x03, panSpeed, tiltSpeed, panPos, tiltPos)
+}
+
+// panTiltPosition sends AbsolutePosition (02) or RelativePosition (03)
+func (c *Controller) panTiltPosition(camera int, model *Model, kind, panSpeed, tiltSpeed byte, pan, tilt int) error {
+	msg := Message{0x01, 0x06, kind, panSpeed, tiltSpeed}
+	_, err := c.send(camera, append(msg, model.EncodePosition(pan, tilt)...))
+	return err
+}
+
+// PanTiltPosition asks the current camera where it's pointing, in degrees from home
+func (c *Controller) PanTiltPosition(ctx context.Context) (pan, tilt float64, err error) {
+	camera, model := c.currentCamera()
+	reply, err := c.Do(ctx, camera, Message{0x09, 0x06, 0x12})
+	if err != nil {
+		return 0, 0, err
+	}
+	return model.DecodePosition(reply.Message[1:])
 }
 
 // PanTiltStop stops all PT movement
-func (c *Controller) PanTiltStop() {
-	c.sendMessage([]byte{0x01, 0x04, 0x07, 0x00})
+func (c *Controller) PanTiltStop() error {
+	return c.sendMessage([]byte{0x01, 0x06, 0x01, 0x01, 0x01, 0x03, 0x03})
 }
 
 //
 // Command Set: Zoom
 //
 
+// Zoom limits
+const (
+	maxZoomSpeed    = 0x07
+	maxZoomPosition = 0xFFFF
+)
+
 // ZoomStop stops zoom movement
-func (c *Controller) ZoomStop() {
-	c.sendMessage([]byte{0x01, 0x04, 0x07, 0x00})
+func (c *Controller) ZoomStop() error {
+	return c.sendMessage([]byte{0x01, 0x04, 0x07, 0x00})
 }
 
-// ZoomIn (re)starts a zoom in
-func (c *Controller) ZoomIn() {
-	c.sendMessage([]byte{0x01, 0x04, 0x07, 0x02})
+// ZoomIn (re)starts a zoom in at the camera's standard speed
+func (c *Controller) ZoomIn() error {
+	return c.sendMessage([]byte{0x01, 0x04, 0x07, 0x02})
 }
 
-// ZoomOut (re)starts a zoom out
-func (c *Controller) ZoomOut() {
-	c.sendMessage([]byte{0x01, 0x04, 0x07, 0x03})
+// ZoomOut (re)starts a zoom out at the camera's standard speed
+func (c *Controller) ZoomOut() error {
+	return c.sendMessage([]byte{0x01, 0x04, 0x07, 0x03})
+}
+
+// ZoomInSpeed (re)starts a zoom in at the given speed, 0 (slowest) to 7 (fastest)
+func (c *Controller) ZoomInSpeed(speed int) error {
+	if speed < 0 || speed > maxZoomSpeed {
+		return ErrInvalidSpeed
+	}
+	return c.sendMessage([]byte{0x01, 0x04, 0x07, 0x20 | byte(speed)})
+}
+
+// ZoomOutSpeed (re)starts a zoom out at the given speed, 0 (slowest) to 7 (fastest)
+func (c *Controller) ZoomOutSpeed(speed int) error {
+	if speed < 0 || speed > maxZoomSpeed {
+		return ErrInvalidSpeed
+	}
+	return c.sendMessage([]byte{0x01, 0x04, 0x07, 0x30 | byte(speed)})
+}
+
+// ZoomTo changes zoom to a specific position, 0 being full wide
+//
+// How far a camera zooms in depends on the model; 0x4000 is full tele for most Sony cameras, and positions
+// past that are digital zoom.
+func (c *Controller) ZoomTo(value int) error {
+	if value < 0 || value > maxZoomPosition {
+		return ErrOutOfRange
+	}
+	return c.sendMessage(append(Message{0x01, 0x04, 0x47}, EncodeNibbles(value, 4)...))
+}
+
+// DigitalZoom turns digital zoom on or off
+func (c *Controller) DigitalZoom(on bool) error {
+	if on {
+		return c.sendMessage([]byte{0x01, 0x04, 0x06, 0x02})
+	}
+	return c.sendMessage([]byte{0x01, 0x04, 0x06, 0x03})
 }
 
-// ZoomTo changes zoom to a specific value
-func (c *Controller) ZoomTo(value int) {
-	c.sendMessage([]byte{0x01, 0x04, 0x47})
+// ZoomPosition asks the current camera for its zoom position
+func (c *Controller) ZoomPosition(ctx context.Context) (int, error) {
+	camera, _ := c.currentCamera()
+	reply, err := c.Do(ctx, camera, Message{0x09, 0x04, 0x47})
+	if err != nil {
+		return 0, err
+	}
+	if len(reply.Message) != 5 {
+		return 0, ErrInvalidReply
+	}
+	position, ok := DecodeNibbles(reply.Message[1:], false)
+	if !ok {
+		return 0, ErrInvalidReply
+	}
+	return position, nil
+}
+
+//
+// Command Set: Focus
+//
+
+// Focus limits
+const (
+	maxFocusSpeed    = 0x07
+	maxFocusPosition = 0xFFFF
+)
+
+// FocusStop stops focus movement
+func (c *Controller) FocusStop() error {
+	return c.sendMessage([]byte{0x01, 0x04, 0x08, 0x00})
+}
+
+// FocusFar (re)starts focusing farther away at the camera's standard speed
+func (c *Controller) FocusFar() error {
+	return c.sendMessage([]byte{0x01, 0x04, 0x08, 0x02})
+}
+
+// FocusNear (re)starts focusing nearer at the camera's standard speed
+func (c *Controller) FocusNear() error {
+	return c.sendMessage([]byte{0x01, 0x04, 0x08, 0x03})
+}
+
+// FocusFarSpeed (re)starts focusing farther away at the given speed, 0 (slowest) to 7 (fastest)
+func (c *Controller) FocusFarSpeed(speed int) error {
+	if speed < 0 || speed > maxFocusSpeed {
+		return ErrInvalidSpeed
+	}
+	return c.sendMessage([]byte{0x01, 0x04, 0x08, 0x20 | byte(speed)})
+}
+
+// FocusNearSpeed (re)starts focusing nearer at the given speed, 0 (slowest) to 7 (fastest)
+func (c *Controller) FocusNearSpeed(speed int) error {
+	if speed < 0 || speed > maxFocusSpeed {
+		return ErrInvalidSpeed
+	}
+	return c.sendMessage([]byte{0x01, 0x04, 0x08, 0x30 | byte(speed)})
+}
+
+// FocusTo focuses at a specific position; the camera should be in manual focus
+func (c *Controller) FocusTo(value int) error {
+	if value < 0 || value > maxFocusPosition {
+		return ErrOutOfRange
+	}
+	return c.sendMessage(append(Message{0x01, 0x04, 0x48}, EncodeNibbles(value, 4)...))
+}
+
+// AutoFocus switches between auto focus and manual focus
+func (c *Controller) AutoFocus(on bool) error {
+	if on {
+		return c.sendMessage([]byte{0x01, 0x04, 0x38, 0x02})
+	}
+	return c.sendMessage([]byte{0x01, 0x04, 0x38, 0x03})
+}
+
+// FocusOnePush focuses once, automatically, and leaves the focus there
+func (c *Controller) FocusOnePush() error {
+	return c.sendMessage([]byte{0x01, 0x04, 0x18, 0x01})
+}
+
+// FocusInfinity focuses at infinity
+func (c *Controller) FocusInfinity() error {
+	return c.sendMessage([]byte{0x01, 0x04, 0x18, 0x02})
+}
+
+// FocusPosition asks the current camera for its focus position
+func (c *Controller) FocusPosition(ctx context.Context) (int, error) {
+	camera, _ := c.currentCamera()
+	reply, err := c.Do(ctx, camera, Message{0x09, 0x04, 0x48})
+	if err != nil {
+		return 0, err
+	}
+	if len(reply.Message) != 5 {
+		return 0, ErrInvalidReply
+	}
+	position, ok := DecodeNibbles(reply.Message[1:], false)
+	if !ok {
+		return 0, ErrInvalidReply
+	}
+	return position, nil
+}
+
+// AutoFocusOn asks the current camera whether it's in auto focus
+func (c *Controller) AutoFocusOn(ctx context.Context) (bool, error) {
+	camera, _ := c.currentCamera()
+	reply, err := c.Do(ctx, camera, Message{0x09, 0x04, 0x38})
+	if err != nil {
+		return false, err
+	}
+	if len(reply.Message) != 2 || (reply.Message[1] != 0x02 && reply.Message[1] != 0x03) {
+		return false, ErrInvalidReply
+	}
+	return reply.Message[1] == 0x02, nil
 }
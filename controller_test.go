@@ -1,7 +1,10 @@
 package visca
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -142,3 +145,449 @@ func TestSendMessage(t *testing.T) {
 	assert.Nil(t, err)
 	conn.AssertExpectations(t)
 }
+
+func TestControllerTracksReplies(t *testing.T) {
+	ctrl := NewController()
+	ctrl.Start()
+	defer ctrl.Stop()
+
+	conn := &MockConnection{}
+	conn.On("SetReceiveQueue", mock.Anything).Return()
+	conn.On("Start").Return(nil)
+	conn.On("Send", mock.Anything).Return(nil)
+	ctrl.AddCamera(1, conn)
+
+	req, err := ctrl.send(1, Message{0x01, 0x04, 0x3F, 0x02, 0x03})
+	assert.Nil(t, err)
+	assert.NotNil(t, req)
+
+	ctrl.receiveQueue <- reply(t, 0x90, 0x42, 0xFF)
+	<-req.acked
+	assert.Equal(t, uint8(2), req.socket)
+
+	// Completions from other cameras don't count
+	ctrl.receiveQueue <- reply(t, 0xA0, 0x52, 0xFF)
+	ctrl.receiveQueue <- reply(t, 0x90, 0x52, 0xFF)
+	<-req.done
+	assert.Equal(t, 1, req.reply.Source())
+	assert.Equal(t, MsgCompletion, req.reply.Message.Type())
+}
+
+func TestControllerSendFailure(t *testing.T) {
+	ctrl := NewController()
+
+	conn := &MockConnection{}
+	conn.On("SetReceiveQueue", mock.Anything).Return()
+	conn.On("Start").Return(nil)
+	conn.On("Send", mock.Anything).Return(ErrNotStarted)
+	ctrl.AddCamera(1, conn)
+
+	req, err := ctrl.send(1, Message{0x01, 0x04, 0x07, 0x02})
+	assert.Equal(t, ErrNotStarted, err)
+	assert.Nil(t, req)
+	assert.Equal(t, 0, ctrl.trackers[1].pairer.Len())
+
+	_, err = ctrl.send(9, Message{0x01, 0x04, 0x07, 0x02})
+	assert.Equal(t, ErrInvalidCameraNumber, err)
+}
+
+func TestControllerDo(t *testing.T) {
+	ctrl := NewController()
+	ctrl.Start()
+	defer ctrl.Stop()
+
+	conn := &MockConnection{}
+	conn.On("SetReceiveQueue", mock.Anything).Return()
+	conn.On("Start").Return(nil)
+	ctrl.AddCamera(2, conn)
+
+	// Command: ACK then Completion
+	conn.On("Send", &Packet{0, 2, Message{0x01, 0x04, 0x3F, 0x02, 0x05}}).Run(func(mock.Arguments) {
+		go func() {
+			ctrl.receiveQueue <- reply(t, 0xA0, 0x41, 0xFF)
+			ctrl.receiveQueue <- reply(t, 0xA0, 0x51, 0xFF)
+		}()
+	}).Return(nil).Once()
+	res, err := ctrl.Do(context.Background(), 2, Message{0x01, 0x04, 0x3F, 0x02, 0x05})
+	assert.Nil(t, err)
+	assert.Equal(t, Reply{Camera: 2, Socket: 1, Message: Message{0x51}}, res)
+
+	// Inquiry: Completion with data
+	conn.On("Send", &Packet{0, 2, Message{0x09, 0x04, 0x47}}).Run(func(mock.Arguments) {
+		go func() {
+			ctrl.receiveQueue <- reply(t, 0xA0, 0x50, 0x01, 0x02, 0x03, 0x04, 0xFF)
+		}()
+	}).Return(nil).Once()
+	res, err = ctrl.Do(context.Background(), 2, Message{0x09, 0x04, 0x47})
+	assert.Nil(t, err)
+	assert.Equal(t, Message{0x50, 0x01, 0x02, 0x03, 0x04}, res.Message)
+
+	// Error
+	conn.On("Send", &Packet{0, 2, Message{0x01, 0x04, 0x07, 0x02}}).Run(func(mock.Arguments) {
+		go func() {
+			ctrl.receiveQueue <- reply(t, 0xA0, 0x41, 0xFF)
+			ctrl.receiveQueue <- reply(t, 0xA0, 0x61, 0x41, 0xFF)
+		}()
+	}).Return(nil).Once()
+	res, err = ctrl.Do(context.Background(), 2, Message{0x01, 0x04, 0x07, 0x02})
+	assert.True(t, errors.Is(err, ErrCommandFailed))
+	assert.True(t, errors.Is(err, CommandNotExecutable))
+	assert.Equal(t, &ReplyError{Socket: 1, Code: CommandNotExecutable}, err)
+	assert.Equal(t, MsgError, res.Message.Type())
+
+	conn.AssertExpectations(t)
+}
+
+func TestControllerDoErrors(t *testing.T) {
+	ctrl := NewController()
+	ctrl.Start()
+	defer ctrl.Stop()
+
+	conn := &MockConnection{}
+	conn.On("SetReceiveQueue", mock.Anything).Return()
+	conn.On("Start").Return(nil)
+	conn.On("Send", mock.Anything).Return(nil)
+	ctrl.AddCamera(1, conn)
+
+	_, err := ctrl.Do(context.Background(), 1, Message{0x41})
+	assert.Equal(t, ErrNotARequest, err)
+
+	_, err = ctrl.Do(context.Background(), 3, Message{0x01, 0x04, 0x07, 0x02})
+	assert.Equal(t, ErrNoCameraConnection, err)
+
+	// The camera never answers
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = ctrl.Do(ctx, 1, Message{0x01, 0x04, 0x07, 0x02})
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestControllerCancel(t *testing.T) {
+	ctrl := NewController()
+	ctrl.Start()
+	defer ctrl.Stop()
+
+	conn := &MockConnection{}
+	conn.On("SetReceiveQueue", mock.Anything).Return()
+	conn.On("Start").Return(nil)
+	ctrl.AddCamera(1, conn)
+
+	err := ctrl.Cancel(1, 3)
+	assert.NotNil(t, err)
+
+	// A Do waiting on the socket gets the CommandCanceled error
+	move := Message{0x01, 0x06, 0x02, 0x18, 0x14, 0x00, 0x09, 0x09, 0x00, 0x00, 0x00, 0x00, 0x00}
+	acked := make(chan struct{})
+	conn.On("Send", &Packet{0, 1, move}).Run(func(mock.Arguments) {
+		go func() {
+			ctrl.receiveQueue <- reply(t, 0x90, 0x42, 0xFF)
+			close(acked)
+		}()
+	}).Return(nil).Once()
+	conn.On("Send", &Packet{0, 1, Message{0x22}}).Run(func(mock.Arguments) {
+		go func() {
+			ctrl.receiveQueue <- reply(t, 0x90, 0x62, 0x04, 0xFF)
+		}()
+	}).Return(nil).Once()
+
+	go func() {
+		<-acked
+		ctrl.Cancel(1, 2)
+	}()
+	_, err = ctrl.Do(context.Background(), 1, move)
+	assert.Equal(t, &ReplyError{Socket: 2, Code: CommandCanceled}, err)
+	assert.Nil(t, ctrl.trackers[1].inSocket(2))
+	conn.AssertExpectations(t)
+}
+
+func TestControllerDoCancelsOnContext(t *testing.T) {
+	ctrl := NewController()
+	ctrl.Start()
+	defer ctrl.Stop()
+
+	conn := &MockConnection{}
+	conn.On("SetReceiveQueue", mock.Anything).Return()
+	conn.On("Start").Return(nil)
+	ctrl.AddCamera(1, conn)
+
+	// Already ACKed when the context is canceled
+	canceled := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	conn.On("Send", &Packet{0, 1, Message{0x01, 0x04, 0x07, 0x02}}).Run(func(mock.Arguments) {
+		go func() {
+			ctrl.receiveQueue <- reply(t, 0x90, 0x41, 0xFF)
+			// make sure the ACK has been handled before giving up
+			ctrl.receiveQueue <- reply(t, 0x81, 0x01, 0xFF)
+			cancel()
+		}()
+	}).Return(nil).Once()
+	conn.On("Send", &Packet{0, 1, Message{0x21}}).Run(func(mock.Arguments) {
+		close(canceled)
+	}).Return(nil).Once()
+	_, err := ctrl.Do(ctx, 1, Message{0x01, 0x04, 0x07, 0x02})
+	assert.Equal(t, context.Canceled, err)
+	<-canceled
+
+	// The CommandCanceled error is matched to the original command
+	ctrl.receiveQueue <- reply(t, 0x90, 0x61, 0x04, 0xFF)
+	ctrl.receiveQueue <- reply(t, 0x81, 0x01, 0xFF) // make sure it was handled
+	assert.Nil(t, ctrl.trackers[1].inSocket(1))
+
+	// Not yet ACKed when the context is canceled; the cancel goes out with the ACK
+	canceled = make(chan struct{})
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	conn.On("Send", &Packet{0, 1, Message{0x01, 0x04, 0x07, 0x03}}).Return(nil).Once()
+	conn.On("Send", &Packet{0, 1, Message{0x22}}).Run(func(mock.Arguments) {
+		close(canceled)
+	}).Return(nil).Once()
+	_, err = ctrl.Do(ctx, 1, Message{0x01, 0x04, 0x07, 0x03})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	ctrl.receiveQueue <- reply(t, 0x90, 0x42, 0xFF)
+	<-canceled
+	conn.AssertExpectations(t)
+}
+
+func TestControllerDoLostACK(t *testing.T) {
+	ctrl := NewController()
+	ctrl.Start()
+	defer ctrl.Stop()
+	ctrl.trackers[1].timeout = 10 * time.Millisecond
+
+	conn := &MockConnection{}
+	conn.On("SetReceiveQueue", mock.Anything).Return()
+	conn.On("Start").Return(nil)
+	ctrl.AddCamera(1, conn)
+
+	// The first command's ACK never arrives
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	conn.On("Send", &Packet{0, 1, Message{0x01, 0x04, 0x07, 0x02}}).Return(nil).Once()
+	_, err := ctrl.Do(ctx, 1, Message{0x01, 0x04, 0x07, 0x02})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	time.Sleep(20 * time.Millisecond)
+
+	// The next command's ACK is its own, so it isn't canceled
+	conn.On("Send", &Packet{0, 1, Message{0x01, 0x04, 0x07, 0x03}}).Run(func(mock.Arguments) {
+		go func() {
+			ctrl.receiveQueue <- reply(t, 0x90, 0x41, 0xFF)
+			ctrl.receiveQueue <- reply(t, 0x90, 0x51, 0xFF)
+		}()
+	}).Return(nil).Once()
+	res, err := ctrl.Do(context.Background(), 1, Message{0x01, 0x04, 0x07, 0x03})
+	assert.Nil(t, err)
+	assert.Equal(t, Reply{Camera: 1, Socket: 1, Message: Message{0x51}}, res)
+	conn.AssertExpectations(t)
+}
+
+func TestControllerSendMessageLostACK(t *testing.T) {
+	ctrl := NewController()
+	ctrl.Start()
+	defer ctrl.Stop()
+	ctrl.trackers[1].timeout = 10 * time.Millisecond
+
+	conn := &MockConnection{}
+	conn.On("SetReceiveQueue", mock.Anything).Return()
+	conn.On("Start").Return(nil)
+	ctrl.AddCamera(1, conn)
+
+	// Nobody waits for ZoomIn, and its ACK never arrives
+	conn.On("Send", &Packet{0, 1, Message{0x01, 0x04, 0x07, 0x02}}).Return(nil).Once()
+	assert.Nil(t, ctrl.ZoomIn())
+	time.Sleep(20 * time.Millisecond)
+
+	// The next command still gets its own replies
+	conn.On("Send", &Packet{0, 1, Message{0x01, 0x04, 0x07, 0x03}}).Run(func(mock.Arguments) {
+		go func() {
+			ctrl.receiveQueue <- reply(t, 0x90, 0x41, 0xFF)
+			ctrl.receiveQueue <- reply(t, 0x90, 0x51, 0xFF)
+		}()
+	}).Return(nil).Once()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	res, err := ctrl.Do(ctx, 1, Message{0x01, 0x04, 0x07, 0x03})
+	assert.Nil(t, err)
+	assert.Equal(t, Reply{Camera: 1, Socket: 1, Message: Message{0x51}}, res)
+	conn.AssertExpectations(t)
+}
+
+func TestControllerPanTilt(t *testing.T) {
+	ctrl := NewController()
+
+	conn := &MockConnection{}
+	conn.On("SetReceiveQueue", mock.Anything).Return()
+	conn.On("Start").Return(nil)
+	ctrl.AddCamera(1, conn)
+
+	var tests = []struct {
+		pan, tilt int
+		want      Message
+	}{
+		{-5, 3, Message{0x01, 0x06, 0x01, 0x05, 0x03, 0x01, 0x01}},
+		{100, 0, Message{0x01, 0x06, 0x01, 0x18, 0x01, 0x02, 0x03}},
+		{0, -100, Message{0x01, 0x06, 0x01, 0x01, 0x17, 0x03, 0x02}},
+		{7, -7, Message{0x01, 0x06, 0x01, 0x07, 0x07, 0x02, 0x02}},
+		{0, 0, Message{0x01, 0x06, 0x01, 0x01, 0x01, 0x03, 0x03}},
+	}
+	for _, tt := range tests {
+		conn.On("Send", &Packet{source: 0, destination: 1, Message: tt.want}).Return(nil).Once()
+		assert.Nil(t, ctrl.PanTilt(tt.pan, tt.tilt), "%d, %d", tt.pan, tt.tilt)
+	}
+
+	conn.On("Send", &Packet{source: 0, destination: 1, Message: Message{0x01, 0x06, 0x01, 0x01, 0x01, 0x03, 0x03}}).Return(nil).Once()
+	assert.Nil(t, ctrl.PanTiltStop())
+	conn.AssertExpectations(t)
+}
+
+func TestControllerPanTiltPositions(t *testing.T) {
+	ctrl := NewController()
+	ctrl.Start()
+	defer ctrl.Stop()
+
+	conn := &MockConnection{}
+	conn.On("SetReceiveQueue", mock.Anything).Return()
+	conn.On("Start").Return(nil)
+	ctrl.AddCamera(1, conn)
+	ctrl.AddCamera(2, conn)
+	assert.Nil(t, ctrl.SetModel(2, ModelPTZOptics))
+	assert.Equal(t, ErrInvalidCameraNumber, ctrl.SetModel(8, ModelPTZOptics))
+	assert.Equal(t, DefaultModel, ctrl.Model(1))
+	assert.Equal(t, ModelPTZOptics, ctrl.Model(2))
+
+	speeds := PanTiltSpeeds{Pan: 0x18, Tilt: 0x14}
+	conn.On("Send", &Packet{0, 1, Message{0x01, 0x06, 0x02, 0x18, 0x14, 0x0F, 0x06, 0x03, 0x05, 0x09, 0x05, 0x02, 0x0E, 0x0F}}).Return(nil).Once()
+	assert.Nil(t, ctrl.PanTiltAbsolute(-170, 90, speeds))
+	conn.On("Send", &Packet{0, 1, Message{0x01, 0x06, 0x03, 0x18, 0x14, 0x00, 0x00, 0x09, 0x03, 0x07, 0x0F, 0x0B, 0x06, 0x04}}).Return(nil).Once()
+	assert.Nil(t, ctrl.PanTiltRelative(10, -5, speeds))
+
+	ctrl.SetCamera(2)
+	// before any commands, which the tracker would match the Completion to
+	conn.On("Send", &Packet{0, 2, Message{0x09, 0x06, 0x12}}).Run(func(mock.Arguments) {
+		go func() {
+			ctrl.receiveQueue <- reply(t, 0xA0, 0x50, 0x00, 0x02, 0x08, 0x08, 0x0F, 0x0E, 0x05, 0x00, 0xFF)
+		}()
+	}).Return(nil).Once()
+	pan, tilt, err := ctrl.PanTiltPosition(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 45.0, pan)
+	assert.Equal(t, -30.0, tilt)
+
+	conn.On("Send", &Packet{0, 2, Message{0x01, 0x06, 0x02, 0x18, 0x14, 0x00, 0x02, 0x08, 0x08, 0x0F, 0x0E, 0x05, 0x00}}).Return(nil).Once()
+	assert.Nil(t, ctrl.PanTiltAbsolute(45, -30, speeds))
+
+	assert.True(t, errors.Is(ctrl.PanTiltAbsolute(171, 0, speeds), ErrOutOfRange))
+	assert.True(t, errors.Is(ctrl.PanTiltAbsolute(0, -31, speeds), ErrOutOfRange))
+	assert.True(t, errors.Is(ctrl.PanTiltRelative(341, 0, speeds), ErrOutOfRange))
+	assert.Equal(t, ErrInvalidSpeed, ctrl.PanTiltAbsolute(0, 0, PanTiltSpeeds{Pan: 0x18, Tilt: 0x18}))
+	assert.Equal(t, ErrInvalidSpeed, ctrl.PanTiltRelative(0, 0, PanTiltSpeeds{Pan: 0, Tilt: 1}))
+
+	conn.AssertExpectations(t)
+}
+
+func TestControllerZoom(t *testing.T) {
+	ctrl := NewController()
+	ctrl.Start()
+	defer ctrl.Stop()
+
+	conn := &MockConnection{}
+	conn.On("SetReceiveQueue", mock.Anything).Return()
+	conn.On("Start").Return(nil)
+	ctrl.AddCamera(1, conn)
+
+	var tests = []struct {
+		do   func() error
+		want Message
+	}{
+		{ctrl.ZoomStop, Message{0x01, 0x04, 0x07, 0x00}},
+		{ctrl.ZoomIn, Message{0x01, 0x04, 0x07, 0x02}},
+		{ctrl.ZoomOut, Message{0x01, 0x04, 0x07, 0x03}},
+		{func() error { return ctrl.ZoomInSpeed(7) }, Message{0x01, 0x04, 0x07, 0x27}},
+		{func() error { return ctrl.ZoomOutSpeed(0) }, Message{0x01, 0x04, 0x07, 0x30}},
+		{func() error { return ctrl.ZoomTo(0x4000) }, Message{0x01, 0x04, 0x47, 0x04, 0x00, 0x00, 0x00}},
+		{func() error { return ctrl.DigitalZoom(true) }, Message{0x01, 0x04, 0x06, 0x02}},
+		{func() error { return ctrl.DigitalZoom(false) }, Message{0x01, 0x04, 0x06, 0x03}},
+	}
+	for _, tt := range tests {
+		conn.On("Send", &Packet{0, 1, tt.want}).Return(nil).Once()
+		assert.Nil(t, tt.do(), "% X", tt.want)
+	}
+
+	assert.Equal(t, ErrInvalidSpeed, ctrl.ZoomInSpeed(8))
+	assert.Equal(t, ErrInvalidSpeed, ctrl.ZoomOutSpeed(-1))
+	assert.Equal(t, ErrOutOfRange, ctrl.ZoomTo(0x10000))
+	assert.Equal(t, ErrOutOfRange, ctrl.ZoomTo(-1))
+	conn.AssertExpectations(t)
+
+	// a new controller, so the commands above, which were never answered, don't get the Completion
+	ctrl2 := NewController()
+	ctrl2.Start()
+	defer ctrl2.Stop()
+	conn2 := &MockConnection{}
+	conn2.On("SetReceiveQueue", mock.Anything).Return()
+	conn2.On("Start").Return(nil)
+	ctrl2.AddCamera(1, conn2)
+	conn2.On("Send", &Packet{0, 1, Message{0x09, 0x04, 0x47}}).Run(func(mock.Arguments) {
+		go func() {
+			ctrl2.receiveQueue <- reply(t, 0x90, 0x50, 0x04, 0x00, 0x00, 0x00, 0xFF)
+		}()
+	}).Return(nil).Once()
+	position, err := ctrl2.ZoomPosition(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 0x4000, position)
+	conn2.AssertExpectations(t)
+}
+
+func TestControllerFocus(t *testing.T) {
+	ctrl := NewController()
+	ctrl.Start()
+	defer ctrl.Stop()
+
+	conn := &MockConnection{}
+	conn.On("SetReceiveQueue", mock.Anything).Return()
+	conn.On("Start").Return(nil)
+	ctrl.AddCamera(1, conn)
+
+	// inquiries first, so the commands below, which are never answered, don't get their Completions
+	conn.On("Send", &Packet{0, 1, Message{0x09, 0x04, 0x48}}).Run(func(mock.Arguments) {
+		go func() {
+			ctrl.receiveQueue <- reply(t, 0x90, 0x50, 0x0C, 0x00, 0x00, 0x00, 0xFF)
+		}()
+	}).Return(nil).Once()
+	position, err := ctrl.FocusPosition(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 0xC000, position)
+
+	conn.On("Send", &Packet{0, 1, Message{0x09, 0x04, 0x38}}).Run(func(mock.Arguments) {
+		go func() {
+			ctrl.receiveQueue <- reply(t, 0x90, 0x50, 0x03, 0xFF)
+		}()
+	}).Return(nil).Once()
+	auto, err := ctrl.AutoFocusOn(context.Background())
+	assert.Nil(t, err)
+	assert.False(t, auto)
+
+	var tests = []struct {
+		do   func() error
+		want Message
+	}{
+		{ctrl.FocusStop, Message{0x01, 0x04, 0x08, 0x00}},
+		{ctrl.FocusFar, Message{0x01, 0x04, 0x08, 0x02}},
+		{ctrl.FocusNear, Message{0x01, 0x04, 0x08, 0x03}},
+		{func() error { return ctrl.FocusFarSpeed(7) }, Message{0x01, 0x04, 0x08, 0x27}},
+		{func() error { return ctrl.FocusNearSpeed(3) }, Message{0x01, 0x04, 0x08, 0x33}},
+		{func() error { return ctrl.FocusTo(0x1234) }, Message{0x01, 0x04, 0x48, 0x01, 0x02, 0x03, 0x04}},
+		{func() error { return ctrl.AutoFocus(true) }, Message{0x01, 0x04, 0x38, 0x02}},
+		{func() error { return ctrl.AutoFocus(false) }, Message{0x01, 0x04, 0x38, 0x03}},
+		{ctrl.FocusOnePush, Message{0x01, 0x04, 0x18, 0x01}},
+		{ctrl.FocusInfinity, Message{0x01, 0x04, 0x18, 0x02}},
+	}
+	for _, tt := range tests {
+		conn.On("Send", &Packet{0, 1, tt.want}).Return(nil).Once()
+		assert.Nil(t, tt.do(), "% X", tt.want)
+	}
+
+	assert.Equal(t, ErrInvalidSpeed, ctrl.FocusFarSpeed(8))
+	assert.Equal(t, ErrInvalidSpeed, ctrl.FocusNearSpeed(-1))
+	assert.Equal(t, ErrOutOfRange, ctrl.FocusTo(0x10000))
+	conn.AssertExpectations(t)
+}
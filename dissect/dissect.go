@@ -0,0 +1,186 @@
+//  dissect.go - describes VISCA messages for people and tools
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package dissect decodes VISCA messages into structured descriptions
+//
+// Commands and inquiries are looked up in a table of the Sony command list, so
+//
+//  dissect.Message(visca.Message{0x01, 0x04, 0x47, 0x01, 0x02, 0x03, 0x04}).String()
+//
+// is "CAM_Zoom Direct position=0x1234". Replies to inquiries can only be decoded knowing the inquiry, so
+// use Reply for those. Descriptions marshal to JSON for logs and tools.
+package dissect
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/josh23french/visca"
+)
+
+// Description is a decoded message
+type Description struct {
+	Type    string  `json:"type"`              // the MessageType, without the Msg, e.g. "Command"
+	Name    string  `json:"name"`              // e.g. "CAM_Zoom Direct", or "Completion" for a reply
+	Request string  `json:"request,omitempty"` // for a reply to an inquiry, the inquiry's name
+	From    *int    `json:"from,omitempty"`    // the source address, if dissected from a Packet
+	To      *int    `json:"to,omitempty"`      // the destination address, if dissected from a Packet
+	Fields  []Field `json:"fields,omitempty"`
+	Known   bool    `json:"known"`          // whether the message was recognized
+	Data    string  `json:"data,omitempty"` // the undecoded bytes of a message that wasn't recognized
+	Bytes   string  `json:"bytes"`          // the whole message in hex
+}
+
+// Field is a parameter of a message
+type Field struct {
+	Name  string `json:"name"`
+	Value int64  `json:"value"`
+	Text  string `json:"text"` // the value formatted for people, e.g. "0x1234" or "On"
+}
+
+// String formats the description on one line, e.g. "CAM_Zoom Direct position=0x1234"
+func (d *Description) String() string {
+	parts := []string{d.Name}
+	if d.Request != "" {
+		parts = append(parts, d.Request)
+	}
+	for _, f := range d.Fields {
+		parts = append(parts, f.Name+"="+f.Text)
+	}
+	if d.Data != "" {
+		parts = append(parts, "data="+d.Data)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Field returns the named field, or nil if there isn't one
+func (d *Description) Field(name string) *Field {
+	for i := range d.Fields {
+		if d.Fields[i].Name == name {
+			return &d.Fields[i]
+		}
+	}
+	return nil
+}
+
+// Message describes a message on its own
+//
+// A Completion that answers an inquiry is described without its contents; use Reply to decode those.
+func Message(msg visca.Message) *Description {
+	return Reply(msg, nil)
+}
+
+// Reply describes a reply to the given request, decoding the contents of a Completion if request is a known
+// inquiry
+//
+// Anything other than a Completion is described as it would be by Message.
+func Reply(msg, request visca.Message) *Description {
+	d := &Description{
+		Bytes: hexString(msg),
+	}
+	if len(msg) == 0 {
+		d.Type = strings.TrimPrefix(visca.MsgInvalid.String(), "Msg")
+		d.Name = "Empty"
+		return d
+	}
+	typ := msg.Type()
+	d.Type = strings.TrimPrefix(typ.String(), "Msg")
+
+	switch typ {
+	case visca.MsgCommand, visca.MsgInquiry:
+		e, fields := lookup(messages, msg)
+		if e == nil {
+			d.Name = "Unknown " + d.Type
+			d.Data = hexString(msg[1:])
+			return d
+		}
+		d.Name = e.name
+		d.Fields = fields
+		d.Known = true
+	case visca.MsgAddressSet:
+		d.Name = "AddressSet"
+		d.Known = len(msg) == 2
+		if d.Known {
+			d.Fields = []Field{decimalField("address", int64(msg[1]))}
+		}
+	case visca.MsgNetworkChange:
+		d.Name = "NetworkChange"
+		d.Known = len(msg) == 1
+	case visca.MsgCancel, visca.MsgACK:
+		d.Name = d.Type
+		d.Known = len(msg) == 1
+		d.Fields = []Field{socketField(msg)}
+	case visca.MsgError:
+		d.Name = d.Type
+		d.Known = len(msg) == 2
+		d.Fields = []Field{socketField(msg)}
+		if len(msg) >= 2 {
+			code := msg.Error()
+			d.Fields = append(d.Fields, Field{Name: "error", Value: int64(code), Text: code.Error()})
+		}
+	case visca.MsgCompletion:
+		d.Name = d.Type
+		d.Fields = []Field{socketField(msg)}
+		d.Known = true
+		if len(msg) == 1 {
+			return d
+		}
+		inq, _ := lookup(messages, request)
+		if inq == nil || request.Type() != visca.MsgInquiry {
+			d.Data = hexString(msg[1:])
+			return d
+		}
+		d.Request = inq.name
+		e, fields := lookup(inq.replies, msg[1:])
+		if e == nil {
+			d.Known = false
+			d.Data = hexString(msg[1:])
+			return d
+		}
+		d.Fields = append(d.Fields, fields...)
+	default:
+		d.Name = "Invalid"
+		d.Data = hexString(msg)
+	}
+	return d
+}
+
+// Packet describes the message in a packet, with its addresses; request is as for Reply
+func Packet(pkt *visca.Packet, request visca.Message) *Description {
+	d := Reply(pkt.Message, request)
+	from, to := pkt.Source(), pkt.Destination()
+	d.From, d.To = &from, &to
+	return d
+}
+
+// socketField makes the field for a reply's or Cancel's socket
+func socketField(msg visca.Message) Field {
+	return decimalField("socket", int64(msg.Socket()))
+}
+
+// decimalField makes a field shown in decimal
+func decimalField(name string, v int64) Field {
+	return Field{Name: name, Value: v, Text: fmt.Sprint(v)}
+}
+
+// hexString formats bytes like they're written in the VISCA documentation, e.g. "01 04 47"
+func hexString(b []byte) string {
+	parts := make([]string, len(b))
+	for i, v := range b {
+		parts[i] = fmt.Sprintf("%02X", v)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,118 @@
+package dissect
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/josh23french/visca"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessage(t *testing.T) {
+	var tests = []struct {
+		msg  visca.Message
+		want string
+	}{
+		{visca.Message{0x01, 0x04, 0x47, 0x01, 0x02, 0x03, 0x04}, "CAM_Zoom Direct position=0x1234"},
+		{visca.Message{0x01, 0x04, 0x07, 0x25}, "CAM_Zoom Tele(Variable) speed=5"},
+		{visca.Message{0x01, 0x04, 0x00, 0x02}, "CAM_Power On"},
+		{visca.Message{0x01, 0x04, 0x35, 0x04}, "CAM_WB mode=ATW"},
+		{visca.Message{0x01, 0x04, 0x3F, 0x02, 0x0A}, "CAM_Memory Recall preset=10"},
+		{visca.Message{0x01, 0x00, 0x01}, "IF_Clear"},
+		{visca.Message{0x01, 0x06, 0x01, 0x0C, 0x0A, 0x01, 0x02}, "Pan-tiltDrive DownLeft panSpeed=12 tiltSpeed=10"},
+		{
+			visca.Message{0x01, 0x06, 0x02, 0x18, 0x14, 0x0F, 0x06, 0x03, 0x05, 0x09, 0x05, 0x02, 0x0E, 0x0F},
+			"Pan-tiltDrive AbsolutePosition panSpeed=24 tiltSpeed=20 pan=-40103 tilt=21231",
+		},
+		{
+			visca.Message{0x01, 0x06, 0x03, 0x01, 0x01, 0x0F, 0x0F, 0x0F, 0x0F, 0x00, 0x00, 0x00, 0x01},
+			"Pan-tiltDrive RelativePosition panSpeed=1 tiltSpeed=1 pan=-1 tilt=1",
+		},
+		{
+			visca.Message{0x01, 0x06, 0x07, 0x01, 0x01, 0x07, 0x0F, 0x0F, 0x0F, 0x07, 0x0F, 0x0F, 0x0F},
+			"Pan-tiltLimitClear corner=UpRight",
+		},
+		{visca.Message{0x09, 0x04, 0x47}, "CAM_ZoomPosInq"},
+		{visca.Message{0x09, 0x04, 0x24, 0x72}, "CAM_RegisterValueInq register=0x72"},
+		{visca.Message{0x01, 0x04, 0x99}, "Unknown Command data=04 99"},
+		{visca.Message{0x41}, "ACK socket=1"},
+		{visca.Message{0x52}, "Completion socket=2"},
+		{visca.Message{0x50, 0x02}, "Completion socket=0 data=02"},
+		{visca.Message{0x62, 0x41}, "Error socket=2 error=command not executable"},
+		{visca.Message{0x21}, "Cancel socket=1"},
+		{visca.Message{0x30, 0x02}, "AddressSet address=2"},
+		{visca.Message{0x38}, "NetworkChange"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Message(tt.msg).String(), "% X", []byte(tt.msg))
+	}
+
+	assert.False(t, Message(visca.Message{0x01, 0x04, 0x99}).Known)
+	assert.True(t, Message(visca.Message{0x01, 0x04, 0x07, 0x00}).Known)
+	assert.Equal(t, "Empty", Message(nil).Name)
+}
+
+func TestReply(t *testing.T) {
+	var tests = []struct {
+		msg, request visca.Message
+		want         string
+	}{
+		{visca.Message{0x50, 0x02}, visca.Message{0x09, 0x04, 0x00}, "Completion CAM_PowerInq socket=0 power=On"},
+		{visca.Message{0x50, 0x01, 0x02, 0x03, 0x04}, visca.Message{0x09, 0x04, 0x47}, "Completion CAM_ZoomPosInq socket=0 position=0x1234"},
+		{
+			visca.Message{0x50, 0x00, 0x00, 0x00, 0x09, 0x03, 0x0F, 0x0F, 0x0F, 0x0B},
+			visca.Message{0x09, 0x06, 0x12},
+			"Completion Pan-tiltPosInq socket=0 pan=147 tilt=-5",
+		},
+		{
+			visca.Message{0x50, 0x00, 0x01, 0x05, 0x11, 0x01, 0x00, 0x02},
+			visca.Message{0x09, 0x00, 0x02},
+			"Completion CAM_VersionInq socket=0 vendor=0x0001 model=0x0511 rom=0x0100 sockets=2",
+		},
+		{visca.Message{0x50, 0x0E}, visca.Message{0x09, 0x04, 0x00}, "Completion CAM_PowerInq socket=0 power=0x0E"},
+		{visca.Message{0x50, 0x02, 0x03}, visca.Message{0x09, 0x04, 0x00}, "Completion CAM_PowerInq socket=0 data=02 03"},
+		{visca.Message{0x51}, visca.Message{0x01, 0x04, 0x07, 0x00}, "Completion socket=1"},
+		{visca.Message{0x60, 0x02}, visca.Message{0x09, 0x04, 0x00}, "Error socket=0 error=syntax error"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Reply(tt.msg, tt.request).String(), "% X", []byte(tt.msg))
+	}
+
+	d := Reply(visca.Message{0x50, 0x02, 0x03}, visca.Message{0x09, 0x04, 0x00})
+	assert.False(t, d.Known)
+}
+
+func TestPacket(t *testing.T) {
+	pkt, err := visca.PacketFromBytes([]byte{0x81, 0x01, 0x04, 0x47, 0x01, 0x02, 0x03, 0x04, 0xFF})
+	assert.Nil(t, err)
+	d := Packet(pkt, nil)
+
+	b, err := json.Marshal(d)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{
+		"type": "Command",
+		"name": "CAM_Zoom Direct",
+		"from": 0,
+		"to": 1,
+		"fields": [{"name": "position", "value": 4660, "text": "0x1234"}],
+		"known": true,
+		"bytes": "01 04 47 01 02 03 04"
+	}`, string(b))
+
+	assert.Equal(t, int64(0x1234), d.Field("position").Value)
+	assert.Nil(t, d.Field("speed"))
+}
+
+func TestTable(t *testing.T) {
+	// every command and inquiry should be reachable, i.e. not shadowed by an earlier pattern
+	for i := range messages {
+		msg := make([]byte, len(messages[i].nibbles)/2)
+		for j, n := range messages[i].nibbles {
+			msg[j/2] |= n.value << (4 * uint(1-j%2))
+		}
+		e, _ := lookup(messages, msg)
+		assert.Equal(t, messages[i].name, e.name, messages[i].pattern)
+	}
+}
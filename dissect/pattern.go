@@ -0,0 +1,174 @@
+//  pattern.go - matching messages against the patterns in the command list
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package dissect
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// entry is a message in the command list
+//
+// The pattern is written the way Sony's documentation writes it, without the header and terminator: two
+// characters per byte, where a digit or capital letter is a fixed nibble and a lowercase letter is a
+// parameter nibble, e.g. "01 04 47 0p 0q 0r 0s". A letter can be repeated, e.g. "0y 0y 0y 0y", and a field's
+// value is made of the nibbles of its letters, in order.
+type entry struct {
+	pattern string
+	name    string
+	fields  []field
+	replies []entry // for an inquiry, the Completions it can get, without the 50
+
+	nibbles []nibble // the compiled pattern
+}
+
+// nibble is one nibble of a compiled pattern
+type nibble struct {
+	letter byte // the parameter's letter, or 0 for a fixed nibble
+	value  byte // the fixed nibble
+}
+
+// format is how a field's value is shown
+type format int
+
+const (
+	formatHex     format = iota // 0x1234
+	formatDecimal               // 4660
+	formatSigned                // two's complement, e.g. -170
+)
+
+// field is a parameter of an entry
+type field struct {
+	name    string
+	letters string // the letters whose nibbles make up the value, most significant first
+	format  format
+	values  map[int64]string // names for the values, if they have them
+}
+
+// hex is a field shown in hex
+func hex(name, letters string) field {
+	return field{name: name, letters: letters, format: formatHex}
+}
+
+// dec is a field shown in decimal
+func dec(name, letters string) field {
+	return field{name: name, letters: letters, format: formatDecimal}
+}
+
+// signed is a field that's two's complement, shown in decimal
+func signed(name, letters string) field {
+	return field{name: name, letters: letters, format: formatSigned}
+}
+
+// enum is a field whose values have names
+func enum(name, letters string, values map[int64]string) field {
+	return field{name: name, letters: letters, format: formatHex, values: values}
+}
+
+// compile parses the patterns of the entries and their replies, panicking on a bad one
+func compile(entries []entry) {
+	for i := range entries {
+		e := &entries[i]
+		for _, b := range strings.Fields(e.pattern) {
+			if len(b) != 2 {
+				panic(fmt.Sprintf("dissect: bad byte %q in pattern %q", b, e.pattern))
+			}
+			for _, c := range []byte(b) {
+				if c >= 'a' && c <= 'z' {
+					e.nibbles = append(e.nibbles, nibble{letter: c})
+					continue
+				}
+				v, err := strconv.ParseUint(string(c), 16, 8)
+				if err != nil {
+					panic(fmt.Sprintf("dissect: bad nibble %q in pattern %q", c, e.pattern))
+				}
+				e.nibbles = append(e.nibbles, nibble{value: byte(v)})
+			}
+		}
+		for _, f := range e.fields {
+			for _, l := range []byte(f.letters) {
+				if !strings.ContainsRune(e.pattern, rune(l)) {
+					panic(fmt.Sprintf("dissect: field %q uses %q, which isn't in pattern %q", f.name, l, e.pattern))
+				}
+			}
+		}
+		compile(e.replies)
+	}
+}
+
+// match returns the parameter nibbles of msg, by letter, or false if it doesn't match the entry
+func (e *entry) match(msg []byte) (map[byte][]byte, bool) {
+	if len(msg)*2 != len(e.nibbles) {
+		return nil, false
+	}
+	params := make(map[byte][]byte)
+	for i, n := range e.nibbles {
+		v := msg[i/2] & 0x0F
+		if i%2 == 0 {
+			v = msg[i/2] >> 4
+		}
+		if n.letter != 0 {
+			params[n.letter] = append(params[n.letter], v)
+		} else if v != n.value {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// lookup finds the first entry that msg matches, returning it with the values of its fields
+func lookup(entries []entry, msg []byte) (*entry, []Field) {
+	for i := range entries {
+		params, ok := entries[i].match(msg)
+		if !ok {
+			continue
+		}
+		fields := make([]Field, len(entries[i].fields))
+		for j, f := range entries[i].fields {
+			fields[j] = f.decode(params)
+		}
+		return &entries[i], fields
+	}
+	return nil, nil
+}
+
+// decode works out the field's value from the parameter nibbles
+func (f *field) decode(params map[byte][]byte) Field {
+	var v int64
+	bits := uint(0)
+	for _, l := range []byte(f.letters) {
+		for _, n := range params[l] {
+			v = v<<4 | int64(n)
+			bits += 4
+		}
+	}
+	if f.format == formatSigned && bits > 0 && v >= 1<<(bits-1) {
+		v -= 1 << bits
+	}
+
+	text := ""
+	switch {
+	case f.values != nil && f.values[v] != "":
+		text = f.values[v]
+	case f.format == formatHex:
+		text = fmt.Sprintf("0x%0*X", bits/4, v)
+	default:
+		text = strconv.FormatInt(v, 10)
+	}
+	return Field{Name: f.name, Value: v, Text: text}
+}
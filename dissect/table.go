@@ -0,0 +1,292 @@
+//  table.go - the Sony VISCA command list
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package dissect
+
+// Names for common values
+var (
+	onOff = map[int64]string{0x02: "On", 0x03: "Off"}
+
+	power = map[int64]string{0x02: "On", 0x03: "Off (Standby)", 0x04: "Internal power circuit error"}
+
+	focusMode = map[int64]string{0x02: "Auto Focus", 0x03: "Manual Focus"}
+
+	afSensitivity = map[int64]string{0x02: "Normal", 0x03: "Low"}
+
+	afMode = map[int64]string{0x00: "Normal AF", 0x01: "Interval AF", 0x02: "Zoom Trigger AF"}
+
+	dZoomMode = map[int64]string{0x00: "Combine", 0x01: "Separate"}
+
+	irCorrection = map[int64]string{0x00: "Standard", 0x01: "IR Light"}
+
+	whiteBalance = map[int64]string{
+		0x00: "Auto", 0x01: "Indoor", 0x02: "Outdoor", 0x03: "One Push WB", 0x04: "ATW", 0x05: "Manual",
+		0x06: "Outdoor Auto", 0x07: "Sodium Lamp Auto", 0x08: "Sodium Lamp", 0x09: "Sodium Lamp Outdoor Auto",
+	}
+
+	exposure = map[int64]string{
+		0x00: "Full Auto", 0x03: "Manual", 0x0A: "Shutter Priority", 0x0B: "Iris Priority", 0x0D: "Bright",
+	}
+
+	pictureEffect = map[int64]string{0x00: "Off", 0x02: "Neg.Art", 0x04: "B&W"}
+
+	limitCorner = map[int64]string{0x00: "DownLeft", 0x01: "UpRight"}
+)
+
+// messages are the commands and inquiries, without the header and terminator
+//
+// They're matched in order, so where two patterns can match the same message, the more specific one goes first.
+var messages = []entry{
+	// Interface
+	{pattern: "01 00 01", name: "IF_Clear"},
+	{pattern: "09 00 02", name: "CAM_VersionInq", replies: []entry{
+		{pattern: "ab cd mn pq rs tu vw", fields: []field{hex("vendor", "abcd"), hex("model", "mnpq"), hex("rom", "rstu"), dec("sockets", "vw")}},
+	}},
+
+	// Power
+	{pattern: "01 04 00 02", name: "CAM_Power On"},
+	{pattern: "01 04 00 03", name: "CAM_Power Off"},
+
+	// Zoom
+	{pattern: "01 04 07 00", name: "CAM_Zoom Stop"},
+	{pattern: "01 04 07 02", name: "CAM_Zoom Tele(Standard)"},
+	{pattern: "01 04 07 03", name: "CAM_Zoom Wide(Standard)"},
+	{pattern: "01 04 07 2p", name: "CAM_Zoom Tele(Variable)", fields: []field{dec("speed", "p")}},
+	{pattern: "01 04 07 3p", name: "CAM_Zoom Wide(Variable)", fields: []field{dec("speed", "p")}},
+	{pattern: "01 04 47 0p 0q 0r 0s", name: "CAM_Zoom Direct", fields: []field{hex("position", "pqrs")}},
+	{pattern: "01 04 47 0p 0q 0r 0s 0t 0u 0v 0w", name: "CAM_ZoomFocus Direct", fields: []field{hex("zoom", "pqrs"), hex("focus", "tuvw")}},
+
+	// Digital zoom
+	{pattern: "01 04 06 02", name: "CAM_DZoom On"},
+	{pattern: "01 04 06 03", name: "CAM_DZoom Off"},
+	{pattern: "01 04 36 00", name: "CAM_DZoom Combine Mode"},
+	{pattern: "01 04 36 01", name: "CAM_DZoom Separate Mode"},
+	{pattern: "01 04 06 00", name: "CAM_DZoom Stop"},
+	{pattern: "01 04 06 2p", name: "CAM_DZoom Tele(Variable)", fields: []field{dec("speed", "p")}},
+	{pattern: "01 04 06 3p", name: "CAM_DZoom Wide(Variable)", fields: []field{dec("speed", "p")}},
+	{pattern: "01 04 06 10", name: "CAM_DZoom x1/Max"},
+	{pattern: "01 04 46 00 00 0p 0q", name: "CAM_DZoom Direct", fields: []field{hex("position", "pq")}},
+
+	// Focus
+	{pattern: "01 04 08 00", name: "CAM_Focus Stop"},
+	{pattern: "01 04 08 02", name: "CAM_Focus Far(Standard)"},
+	{pattern: "01 04 08 03", name: "CAM_Focus Near(Standard)"},
+	{pattern: "01 04 08 2p", name: "CAM_Focus Far(Variable)", fields: []field{dec("speed", "p")}},
+	{pattern: "01 04 08 3p", name: "CAM_Focus Near(Variable)", fields: []field{dec("speed", "p")}},
+	{pattern: "01 04 48 0p 0q 0r 0s", name: "CAM_Focus Direct", fields: []field{hex("position", "pqrs")}},
+	{pattern: "01 04 38 02", name: "CAM_Focus Auto Focus"},
+	{pattern: "01 04 38 03", name: "CAM_Focus Manual Focus"},
+	{pattern: "01 04 38 10", name: "CAM_Focus Auto/Manual"},
+	{pattern: "01 04 18 01", name: "CAM_Focus One Push Trigger"},
+	{pattern: "01 04 18 02", name: "CAM_Focus Infinity"},
+	{pattern: "01 04 28 0p 0q 0r 0s", name: "CAM_Focus Near Limit", fields: []field{hex("position", "pqrs")}},
+	{pattern: "01 04 58 02", name: "CAM_AFSensitivity Normal"},
+	{pattern: "01 04 58 03", name: "CAM_AFSensitivity Low"},
+	{pattern: "01 04 57 00", name: "CAM_AFMode Normal AF"},
+	{pattern: "01 04 57 01", name: "CAM_AFMode Interval AF"},
+	{pattern: "01 04 57 02", name: "CAM_AFMode Zoom Trigger AF"},
+	{pattern: "01 04 27 0p 0q 0r 0s", name: "CAM_AFMode Active/Interval Time", fields: []field{dec("movement", "pq"), dec("interval", "rs")}},
+	{pattern: "01 04 11 00", name: "CAM_IRCorrection Standard"},
+	{pattern: "01 04 11 01", name: "CAM_IRCorrection IR Light"},
+
+	// Initialize
+	{pattern: "01 04 19 01", name: "CAM_Initialize Lens"},
+	{pattern: "01 04 19 03", name: "CAM_Initialize Camera"},
+
+	// White balance
+	{pattern: "01 04 35 0p", name: "CAM_WB", fields: []field{enum("mode", "p", whiteBalance)}},
+	{pattern: "01 04 10 05", name: "CAM_WB One Push Trigger"},
+	{pattern: "01 04 03 00", name: "CAM_RGain Reset"},
+	{pattern: "01 04 03 02", name: "CAM_RGain Up"},
+	{pattern: "01 04 03 03", name: "CAM_RGain Down"},
+	{pattern: "01 04 43 00 00 0p 0q", name: "CAM_RGain Direct", fields: []field{hex("gain", "pq")}},
+	{pattern: "01 04 04 00", name: "CAM_BGain Reset"},
+	{pattern: "01 04 04 02", name: "CAM_BGain Up"},
+	{pattern: "01 04 04 03", name: "CAM_BGain Down"},
+	{pattern: "01 04 44 00 00 0p 0q", name: "CAM_BGain Direct", fields: []field{hex("gain", "pq")}},
+
+	// Exposure
+	{pattern: "01 04 39 0p", name: "CAM_AE", fields: []field{enum("mode", "p", exposure)}},
+	{pattern: "01 04 5A 02", name: "CAM_AutoSlowShutter On"},
+	{pattern: "01 04 5A 03", name: "CAM_AutoSlowShutter Off"},
+	{pattern: "01 04 0A 00", name: "CAM_Shutter Reset"},
+	{pattern: "01 04 0A 02", name: "CAM_Shutter Up"},
+	{pattern: "01 04 0A 03", name: "CAM_Shutter Down"},
+	{pattern: "01 04 4A 00 00 0p 0q", name: "CAM_Shutter Direct", fields: []field{hex("position", "pq")}},
+	{pattern: "01 04 0B 00", name: "CAM_Iris Reset"},
+	{pattern: "01 04 0B 02", name: "CAM_Iris Up"},
+	{pattern: "01 04 0B 03", name: "CAM_Iris Down"},
+	{pattern: "01 04 4B 00 00 0p 0q", name: "CAM_Iris Direct", fields: []field{hex("position", "pq")}},
+	{pattern: "01 04 0C 00", name: "CAM_Gain Reset"},
+	{pattern: "01 04 0C 02", name: "CAM_Gain Up"},
+	{pattern: "01 04 0C 03", name: "CAM_Gain Down"},
+	{pattern: "01 04 4C 00 00 0p 0q", name: "CAM_Gain Direct", fields: []field{hex("position", "pq")}},
+	{pattern: "01 04 2C 0p", name: "CAM_Gain Limit", fields: []field{hex("limit", "p")}},
+	{pattern: "01 04 0D 00", name: "CAM_Bright Reset"},
+	{pattern: "01 04 0D 02", name: "CAM_Bright Up"},
+	{pattern: "01 04 0D 03", name: "CAM_Bright Down"},
+	{pattern: "01 04 4D 00 00 0p 0q", name: "CAM_Bright Direct", fields: []field{hex("position", "pq")}},
+	{pattern: "01 04 3E 02", name: "CAM_ExpComp On"},
+	{pattern: "01 04 3E 03", name: "CAM_ExpComp Off"},
+	{pattern: "01 04 0E 00", name: "CAM_ExpComp Reset"},
+	{pattern: "01 04 0E 02", name: "CAM_ExpComp Up"},
+	{pattern: "01 04 0E 03", name: "CAM_ExpComp Down"},
+	{pattern: "01 04 4E 00 00 0p 0q", name: "CAM_ExpComp Direct", fields: []field{hex("position", "pq")}},
+	{pattern: "01 04 33 02", name: "CAM_BackLight On"},
+	{pattern: "01 04 33 03", name: "CAM_BackLight Off"},
+	{pattern: "01 04 3D 02", name: "CAM_WD On"},
+	{pattern: "01 04 3D 03", name: "CAM_WD Off"},
+
+	// Picture
+	{pattern: "01 04 02 00", name: "CAM_Aperture Reset"},
+	{pattern: "01 04 02 02", name: "CAM_Aperture Up"},
+	{pattern: "01 04 02 03", name: "CAM_Aperture Down"},
+	{pattern: "01 04 42 00 00 0p 0q", name: "CAM_Aperture Direct", fields: []field{hex("gain", "pq")}},
+	{pattern: "01 04 52 02", name: "CAM_HR On"},
+	{pattern: "01 04 52 03", name: "CAM_HR Off"},
+	{pattern: "01 04 53 0p", name: "CAM_NR", fields: []field{dec("level", "p")}},
+	{pattern: "01 04 5B 0p", name: "CAM_Gamma", fields: []field{dec("gamma", "p")}},
+	{pattern: "01 04 49 00 00 00 0p", name: "CAM_ColorGain Direct", fields: []field{hex("gain", "p")}},
+	{pattern: "01 04 4F 00 00 00 0p", name: "CAM_ColorHue Direct", fields: []field{hex("hue", "p")}},
+	{pattern: "01 04 61 02", name: "CAM_LR_Reverse On"},
+	{pattern: "01 04 61 03", name: "CAM_LR_Reverse Off"},
+	{pattern: "01 04 62 02", name: "CAM_Freeze On"},
+	{pattern: "01 04 62 03", name: "CAM_Freeze Off"},
+	{pattern: "01 04 63 0p", name: "CAM_PictureEffect", fields: []field{enum("effect", "p", pictureEffect)}},
+	{pattern: "01 04 66 02", name: "CAM_PictureFlip On"},
+	{pattern: "01 04 66 03", name: "CAM_PictureFlip Off"},
+	{pattern: "01 04 01 02", name: "CAM_ICR On"},
+	{pattern: "01 04 01 03", name: "CAM_ICR Off"},
+	{pattern: "01 04 51 02", name: "CAM_AutoICR On"},
+	{pattern: "01 04 51 03", name: "CAM_AutoICR Off"},
+
+	// Presets
+	{pattern: "01 04 3F 00 pq", name: "CAM_Memory Reset", fields: []field{dec("preset", "pq")}},
+	{pattern: "01 04 3F 01 pq", name: "CAM_Memory Set", fields: []field{dec("preset", "pq")}},
+	{pattern: "01 04 3F 02 pq", name: "CAM_Memory Recall", fields: []field{dec("preset", "pq")}},
+
+	// Registers
+	{pattern: "01 04 24 mn 0p 0q", name: "CAM_RegisterValue", fields: []field{hex("register", "mn"), hex("value", "pq")}},
+
+	// System
+	{pattern: "01 06 06 02", name: "SYS_Menu On"},
+	{pattern: "01 06 06 03", name: "SYS_Menu Off"},
+	{pattern: "01 06 06 10", name: "SYS_Menu On/Off"},
+	{pattern: "01 7E 01 0A 00 02", name: "CAM_Tally On"},
+	{pattern: "01 7E 01 0A 00 03", name: "CAM_Tally Off"},
+
+	// Pan-tilt
+	{pattern: "01 06 01 vv ww 03 01", name: "Pan-tiltDrive Up", fields: panTiltSpeeds},
+	{pattern: "01 06 01 vv ww 03 02", name: "Pan-tiltDrive Down", fields: panTiltSpeeds},
+	{pattern: "01 06 01 vv ww 01 03", name: "Pan-tiltDrive Left", fields: panTiltSpeeds},
+	{pattern: "01 06 01 vv ww 02 03", name: "Pan-tiltDrive Right", fields: panTiltSpeeds},
+	{pattern: "01 06 01 vv ww 01 01", name: "Pan-tiltDrive UpLeft", fields: panTiltSpeeds},
+	{pattern: "01 06 01 vv ww 02 01", name: "Pan-tiltDrive UpRight", fields: panTiltSpeeds},
+	{pattern: "01 06 01 vv ww 01 02", name: "Pan-tiltDrive DownLeft", fields: panTiltSpeeds},
+	{pattern: "01 06 01 vv ww 02 02", name: "Pan-tiltDrive DownRight", fields: panTiltSpeeds},
+	{pattern: "01 06 01 vv ww 03 03", name: "Pan-tiltDrive Stop", fields: panTiltSpeeds},
+	{pattern: "01 06 02 vv ww 0y 0y 0y 0y 0y 0z 0z 0z 0z", name: "Pan-tiltDrive AbsolutePosition", fields: panTiltPosition},
+	{pattern: "01 06 02 vv ww 0y 0y 0y 0y 0z 0z 0z 0z", name: "Pan-tiltDrive AbsolutePosition", fields: panTiltPosition},
+	{pattern: "01 06 03 vv ww 0y 0y 0y 0y 0y 0z 0z 0z 0z", name: "Pan-tiltDrive RelativePosition", fields: panTiltPosition},
+	{pattern: "01 06 03 vv ww 0y 0y 0y 0y 0z 0z 0z 0z", name: "Pan-tiltDrive RelativePosition", fields: panTiltPosition},
+	{pattern: "01 06 04", name: "Pan-tiltDrive Home"},
+	{pattern: "01 06 05", name: "Pan-tiltDrive Reset"},
+	{pattern: "01 06 07 01 0w 07 0F 0F 0F 0F 07 0F 0F 0F", name: "Pan-tiltLimitClear", fields: []field{enum("corner", "w", limitCorner)}},
+	{pattern: "01 06 07 01 0w 07 0F 0F 0F 07 0F 0F 0F", name: "Pan-tiltLimitClear", fields: []field{enum("corner", "w", limitCorner)}},
+	{pattern: "01 06 07 00 0w 0y 0y 0y 0y 0y 0z 0z 0z 0z", name: "Pan-tiltLimitSet", fields: panTiltLimit},
+	{pattern: "01 06 07 00 0w 0y 0y 0y 0y 0z 0z 0z 0z", name: "Pan-tiltLimitSet", fields: panTiltLimit},
+	{pattern: "01 06 44 02", name: "Pan-tiltSlow On"},
+	{pattern: "01 06 44 03", name: "Pan-tiltSlow Off"},
+
+	// Camera inquiries
+	{pattern: "09 04 00", name: "CAM_PowerInq", replies: values("power", power)},
+	{pattern: "09 04 06", name: "CAM_DZoomModeInq", replies: values("dzoom", onOff)},
+	{pattern: "09 04 36", name: "CAM_DZoomC/SModeInq", replies: values("mode", dZoomMode)},
+	{pattern: "09 04 47", name: "CAM_ZoomPosInq", replies: position("position")},
+	{pattern: "09 04 46", name: "CAM_DZoomPosInq", replies: position("position")},
+	{pattern: "09 04 38", name: "CAM_FocusModeInq", replies: values("mode", focusMode)},
+	{pattern: "09 04 48", name: "CAM_FocusPosInq", replies: position("position")},
+	{pattern: "09 04 28", name: "CAM_FocusNearLimitInq", replies: position("position")},
+	{pattern: "09 04 58", name: "CAM_AFSensitivityInq", replies: values("sensitivity", afSensitivity)},
+	{pattern: "09 04 57", name: "CAM_AFModeInq", replies: values("mode", afMode)},
+	{pattern: "09 04 27", name: "CAM_AFTimeSettingInq", replies: []entry{
+		{pattern: "0p 0q 0r 0s", fields: []field{dec("movement", "pq"), dec("interval", "rs")}},
+	}},
+	{pattern: "09 04 11", name: "CAM_IRCorrectionInq", replies: values("mode", irCorrection)},
+	{pattern: "09 04 35", name: "CAM_WBModeInq", replies: values("mode", whiteBalance)},
+	{pattern: "09 04 43", name: "CAM_RGainInq", replies: position("gain")},
+	{pattern: "09 04 44", name: "CAM_BGainInq", replies: position("gain")},
+	{pattern: "09 04 39", name: "CAM_AEModeInq", replies: values("mode", exposure)},
+	{pattern: "09 04 5A", name: "CAM_SlowShutterModeInq", replies: values("slowshutter", onOff)},
+	{pattern: "09 04 4A", name: "CAM_ShutterPosInq", replies: position("position")},
+	{pattern: "09 04 4B", name: "CAM_IrisPosInq", replies: position("position")},
+	{pattern: "09 04 4C", name: "CAM_GainPosInq", replies: position("position")},
+	{pattern: "09 04 4D", name: "CAM_BrightPosInq", replies: position("position")},
+	{pattern: "09 04 3E", name: "CAM_ExpCompModeInq", replies: values("expcomp", onOff)},
+	{pattern: "09 04 4E", name: "CAM_ExpCompPosInq", replies: position("position")},
+	{pattern: "09 04 33", name: "CAM_BackLightModeInq", replies: values("backlight", onOff)},
+	{pattern: "09 04 3D", name: "CAM_WDModeInq", replies: values("wd", onOff)},
+	{pattern: "09 04 42", name: "CAM_ApertureInq", replies: position("gain")},
+	{pattern: "09 04 52", name: "CAM_HRModeInq", replies: values("hr", onOff)},
+	{pattern: "09 04 53", name: "CAM_NRInq", replies: []entry{{pattern: "0p", fields: []field{dec("level", "p")}}}},
+	{pattern: "09 04 5B", name: "CAM_GammaInq", replies: []entry{{pattern: "0p", fields: []field{dec("gamma", "p")}}}},
+	{pattern: "09 04 49", name: "CAM_ColorGainInq", replies: position("gain")},
+	{pattern: "09 04 4F", name: "CAM_ColorHueInq", replies: position("hue")},
+	{pattern: "09 04 61", name: "CAM_LR_ReverseModeInq", replies: values("reverse", onOff)},
+	{pattern: "09 04 62", name: "CAM_FreezeModeInq", replies: values("freeze", onOff)},
+	{pattern: "09 04 63", name: "CAM_PictureEffectModeInq", replies: values("effect", pictureEffect)},
+	{pattern: "09 04 66", name: "CAM_PictureFlipModeInq", replies: values("flip", onOff)},
+	{pattern: "09 04 01", name: "CAM_ICRModeInq", replies: values("icr", onOff)},
+	{pattern: "09 04 51", name: "CAM_AutoICRModeInq", replies: values("autoicr", onOff)},
+	{pattern: "09 04 3F", name: "CAM_MemoryInq", replies: []entry{{pattern: "pq", fields: []field{dec("preset", "pq")}}}},
+	{pattern: "09 04 24 mn", name: "CAM_RegisterValueInq", fields: []field{hex("register", "mn")}, replies: []entry{
+		{pattern: "0p 0q", fields: []field{hex("value", "pq")}},
+	}},
+	{pattern: "09 7E 01 0A", name: "CAM_TallyInq", replies: values("tally", onOff)},
+
+	// Pan-tilt inquiries
+	{pattern: "09 06 06", name: "SYS_MenuModeInq", replies: values("menu", onOff)},
+	{pattern: "09 06 10", name: "Pan-tiltModeInq", replies: []entry{{pattern: "pq rs", fields: []field{hex("status", "pqrs")}}}},
+	{pattern: "09 06 11", name: "Pan-tiltMaxSpeedInq", replies: []entry{
+		{pattern: "ww zz", fields: []field{dec("pan", "w"), dec("tilt", "z")}},
+	}},
+	{pattern: "09 06 12", name: "Pan-tiltPosInq", replies: []entry{
+		{pattern: "0w 0w 0w 0w 0w 0z 0z 0z 0z", fields: []field{signed("pan", "w"), signed("tilt", "z")}},
+		{pattern: "0w 0w 0w 0w 0z 0z 0z 0z", fields: []field{signed("pan", "w"), signed("tilt", "z")}},
+	}},
+	{pattern: "09 06 44", name: "Pan-tiltSlowModeInq", replies: values("slow", onOff)},
+}
+
+// Fields shared by the Pan-tilt commands
+var (
+	panTiltSpeeds   = []field{dec("panSpeed", "v"), dec("tiltSpeed", "w")}
+	panTiltPosition = []field{dec("panSpeed", "v"), dec("tiltSpeed", "w"), signed("pan", "y"), signed("tilt", "z")}
+	panTiltLimit    = []field{enum("corner", "w", limitCorner), signed("pan", "y"), signed("tilt", "z")}
+)
+
+// values is the reply to an inquiry that answers with one byte that has a name
+func values(name string, names map[int64]string) []entry {
+	return []entry{{pattern: "pq", fields: []field{enum(name, "pq", names)}}}
+}
+
+// position is the reply to an inquiry that answers with a 4-nibble value
+func position(name string) []entry {
+	return []entry{{pattern: "0p 0q 0r 0s", fields: []field{hex(name, "pqrs")}}}
+}
+
+func init() {
+	compile(messages)
+}
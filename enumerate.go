@@ -0,0 +1,212 @@
+//  enumerate.go - daisy-chain address assignment
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package visca
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Error constants
+var (
+	ErrInvalidAddressSetReply = errors.New("invalid AddressSet reply")
+	ErrNotEnumerated          = errors.New("no bus has been enumerated")
+)
+
+// EnumerateTimeout is how long to wait for the chain when enumerating it again after a network change
+var EnumerateTimeout = 5 * time.Second
+
+// Enumerate assigns addresses to the cameras daisy-chained on conn and adds a camera for each of them
+//
+// It broadcasts AddressSet, which each camera in the chain takes an address from, increments, and passes
+// along; the last camera returns it to us with the number of cameras + 1. Then it broadcasts IF_Clear to
+// empty the cameras' command buffers. Any commands still waiting on the cameras fail with CommandCanceled.
+//
+// conn is shared through a Bus, which is reused when conn is enumerated again; see EnumerateBus.
+// Enumerate returns the number of cameras found.
+func (c *Controller) Enumerate(ctx context.Context, conn Connection) (int, error) {
+	c.mu.RLock()
+	bus := c.bus
+	c.mu.RUnlock()
+	if bus == nil || bus.conn != conn {
+		bus = NewBus(conn)
+	}
+	return c.EnumerateBus(ctx, bus)
+}
+
+// EnumerateBus assigns addresses to the cameras daisy-chained on the bus, like Enumerate, and adds each
+// camera with its Connection from the bus
+//
+// The bus is started if it isn't already, and the broadcasts go through it. Cameras 1-7 that were
+// previously on the bus but are no longer in the chain are removed. The bus's Connection keeps running for
+// the next enumeration when its cameras are removed; stopping it is left to the caller. EnumerateBus returns
+// the number of cameras found.
+func (c *Controller) EnumerateBus(ctx context.Context, bus *Bus) (int, error) {
+	all, err := bus.Camera(BroadcastAddress)
+	if err != nil {
+		return 0, err
+	}
+	c.mu.RLock()
+	started := c.bus == bus
+	c.mu.RUnlock()
+	if !started {
+		all.SetReceiveQueue(c.receiveQueue)
+		err := all.Start()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	count, err := c.enumerate(ctx, all)
+	if err != nil {
+		if !started {
+			all.Stop()
+		}
+		return 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.bus != nil && c.bus != bus {
+		// only one chain is renumerated on network changes
+		if all, err := c.bus.Camera(BroadcastAddress); err == nil {
+			all.Stop()
+		}
+	}
+	c.bus = bus
+	for cam := 1; cam <= 7; cam++ {
+		conn, err := bus.Camera(cam)
+		if err != nil {
+			return 0, err
+		}
+		prev := c.connections[cam]
+		switch {
+		case cam <= count && prev != conn:
+			c.connections[cam] = conn
+			conn.SetReceiveQueue(c.receiveQueue)
+			conn.Start()
+		case cam > count && prev == conn:
+			c.connections[cam] = nil
+			conn.Stop()
+		case cam > count:
+			// not one of ours
+			continue
+		}
+		if prev != nil && prev != conn && prev != bus.conn && !c.inUse(prev) {
+			prev.Stop()
+		}
+		// IF_Clear emptied the cameras' buffers, and a replaced camera will never answer
+		c.trackers[cam].clear(cam)
+	}
+	c.logger.Info().Msgf("Found %v camera(s)", count)
+
+	return count, nil
+}
+
+// enumerate does the AddressSet and IF_Clear broadcasts and returns the number of cameras in the chain
+func (c *Controller) enumerate(ctx context.Context, conn Connection) (int, error) {
+	msg, err := NewAddressSetMessage(1)
+	if err != nil {
+		return 0, err
+	}
+	res, err := c.broadcast(ctx, conn, msg)
+	if err != nil {
+		return 0, err
+	}
+	if res.Message.Type() != MsgAddressSet || len(res.Message) < 2 {
+		return 0, ErrInvalidAddressSetReply
+	}
+	next := int(res.Message[1])
+	if next < 1 || next > BroadcastAddress {
+		return 0, ErrInvalidAddressSetReply
+	}
+
+	_, err = c.broadcast(ctx, conn, NewIFClearMessage())
+	if err != nil {
+		return 0, err
+	}
+
+	return next - 1, nil
+}
+
+// processNetworkChanges enumerates the bus again whenever a camera reports a network change
+func (c *Controller) processNetworkChanges() {
+	defer c.wg.Done()
+	for {
+		select {
+		case <-c.quit:
+			return
+		case <-c.renumerate:
+		}
+
+		c.mu.RLock()
+		bus := c.bus
+		c.mu.RUnlock()
+
+		event := Event{Type: EventNetworkChange}
+		if bus == nil {
+			c.logger.Warn().Msg("network changed, but there's no bus to enumerate")
+			event.Err = ErrNotEnumerated
+		} else {
+			ctx, cancel := context.WithTimeout(context.Background(), EnumerateTimeout)
+			event.Cameras, event.Err = c.EnumerateBus(ctx, bus)
+			cancel()
+			if event.Err != nil {
+				c.logger.Warn().Err(event.Err).Msg("error enumerating cameras after network change")
+			}
+		}
+		c.events.publish(event)
+	}
+}
+
+// broadcast sends a Message to every camera on conn and waits for it to make it back around the chain
+func (c *Controller) broadcast(ctx context.Context, conn Connection, msg Message) (*Packet, error) {
+	pkt, err := NewBroadcastPacket(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	t := c.trackers[BroadcastAddress]
+	req := newRequest(msg)
+	t.add(req)
+	err = conn.Send(pkt)
+	if err != nil {
+		t.remove(req)
+		return nil, err
+	}
+
+	select {
+	case <-req.done:
+		return req.reply, req.err
+	case <-ctx.Done():
+		t.remove(req)
+		return nil, ctx.Err()
+	case <-c.quit:
+		return nil, ErrControllerStopped
+	}
+}
+
+// inUse returns true if any camera is using the given Connection; c.mu must be held
+func (c *Controller) inUse(conn Connection) bool {
+	for _, cc := range c.connections {
+		if cc != nil && cc == conn {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,219 @@
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package visca
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// chainConnection returns a mock Connection that answers broadcasts like a chain of the given number of cameras
+func chainConnection(t *testing.T, ctrl *Controller, cameras *byte) *MockConnection {
+	conn := &MockConnection{}
+	conn.On("SetReceiveQueue", mock.Anything).Return()
+	conn.On("Start").Return(nil)
+	conn.On("Send", &Packet{0, 8, Message{0x30, 0x01}}).Run(func(mock.Arguments) {
+		go func() { ctrl.receiveQueue <- reply(t, 0x88, 0x30, *cameras+1, 0xFF) }()
+	}).Return(nil)
+	conn.On("Send", &Packet{0, 8, Message{0x01, 0x00, 0x01}}).Run(func(mock.Arguments) {
+		go func() { ctrl.receiveQueue <- reply(t, 0x88, 0x01, 0x00, 0x01, 0xFF) }()
+	}).Return(nil)
+	return conn
+}
+
+// onBus returns the Connection for the camera with the given address on the controller's bus
+func onBus(ctrl *Controller, address int) Connection {
+	cam, _ := ctrl.bus.Camera(address)
+	return cam
+}
+
+func TestEnumerate(t *testing.T) {
+	ctrl := NewController()
+	ctrl.Start()
+	defer ctrl.Stop()
+
+	// A network camera that will be replaced by one on the chain
+	other := &MockConnection{}
+	other.On("SetReceiveQueue", mock.Anything).Return()
+	other.On("Start").Return(nil)
+	other.On("Stop").Return().Once()
+	ctrl.AddCamera(2, other)
+
+	// And one that won't be
+	kept := &MockConnection{}
+	kept.On("SetReceiveQueue", mock.Anything).Return()
+	kept.On("Start").Return(nil)
+	ctrl.AddCamera(6, kept)
+
+	cameras := byte(3)
+	conn := chainConnection(t, ctrl, &cameras)
+	count, err := ctrl.Enumerate(context.Background(), conn)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, conn, ctrl.bus.conn)
+	for cam := 1; cam <= 3; cam++ {
+		assert.Equal(t, onBus(ctrl, cam), ctrl.connections[cam])
+	}
+	assert.Nil(t, ctrl.connections[4])
+	assert.Equal(t, kept, ctrl.connections[6])
+	conn.AssertNumberOfCalls(t, "Start", 1)
+	other.AssertExpectations(t)
+
+	// The cameras share the connection
+	conn.On("Send", &Packet{0, 3, Message{0x01, 0x04, 0x3F, 0x02, 0x01}}).Return(nil).Once()
+	ctrl.SetCamera(3)
+	assert.Nil(t, ctrl.PresetRecall(1))
+
+	// Removing one of them leaves the connection running for the others
+	assert.Nil(t, ctrl.RemoveCamera(3))
+	conn.AssertNotCalled(t, "Stop")
+
+	// Enumerating again doesn't restart the connection, and drops cameras that are gone
+	bus := ctrl.bus
+	cameras = 1
+	count, err = ctrl.Enumerate(context.Background(), conn)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, bus, ctrl.bus)
+	assert.Equal(t, onBus(ctrl, 1), ctrl.connections[1])
+	assert.Nil(t, ctrl.connections[2])
+	assert.Equal(t, kept, ctrl.connections[6])
+	conn.AssertNumberOfCalls(t, "Start", 1)
+}
+
+func TestEnumerateBus(t *testing.T) {
+	ctrl := NewController()
+	ctrl.Start()
+	defer ctrl.Stop()
+
+	cameras := byte(2)
+	port := chainConnection(t, ctrl, &cameras)
+	bus := NewBus(port)
+
+	// A camera added by hand shares the port with the ones found
+	cam2, _ := bus.Camera(2)
+	ctrl.AddCamera(2, cam2)
+
+	count, err := ctrl.EnumerateBus(context.Background(), bus)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, bus, ctrl.bus)
+	assert.Equal(t, cam2, ctrl.connections[2])
+	port.AssertNumberOfCalls(t, "SetReceiveQueue", 1)
+	port.AssertNumberOfCalls(t, "Start", 1)
+
+	// Enumerating the port the bus is on goes through the bus
+	count, err = ctrl.Enumerate(context.Background(), port)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, bus, ctrl.bus)
+	port.AssertNumberOfCalls(t, "SetReceiveQueue", 1)
+}
+
+func TestEnumerateFailsPendingCommands(t *testing.T) {
+	ctrl := NewController()
+	ctrl.Start()
+	defer ctrl.Stop()
+
+	cameras := byte(2)
+	conn := chainConnection(t, ctrl, &cameras)
+	_, err := ctrl.Enumerate(context.Background(), conn)
+	assert.Nil(t, err)
+
+	conn.On("Send", &Packet{0, 2, Message{0x01, 0x04, 0x07, 0x02}}).Return(nil).Once()
+	req, err := ctrl.send(2, Message{0x01, 0x04, 0x07, 0x02})
+	assert.Nil(t, err)
+
+	_, err = ctrl.Enumerate(context.Background(), conn)
+	assert.Nil(t, err)
+	<-req.done
+	assert.Equal(t, CommandCanceled, req.reply.Message.Error())
+}
+
+func TestEnumerateNoReply(t *testing.T) {
+	ctrl := NewController()
+	ctrl.Start()
+	defer ctrl.Stop()
+
+	conn := &MockConnection{}
+	conn.On("SetReceiveQueue", mock.Anything).Return()
+	conn.On("Start").Return(nil)
+	conn.On("Stop").Return().Once()
+	conn.On("Send", mock.Anything).Return(nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	count, err := ctrl.Enumerate(ctx, conn)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, 0, count)
+	assert.Nil(t, ctrl.bus)
+	conn.AssertExpectations(t)
+}
+
+func TestEnumerateBadReply(t *testing.T) {
+	ctrl := NewController()
+	ctrl.Start()
+	defer ctrl.Stop()
+
+	cameras := byte(9)
+	conn := chainConnection(t, ctrl, &cameras)
+	conn.On("Stop").Return().Once()
+	_, err := ctrl.Enumerate(context.Background(), conn)
+	assert.Equal(t, ErrInvalidAddressSetReply, err)
+}
+
+func TestNetworkChange(t *testing.T) {
+	ctrl := NewController()
+	ctrl.Start()
+	defer ctrl.Stop()
+	events := ctrl.Subscribe()
+	defer ctrl.Unsubscribe(events)
+
+	// Nothing enumerated yet
+	ctrl.receiveQueue <- reply(t, 0x90, 0x38, 0xFF)
+	e := <-events
+	assert.Equal(t, EventNetworkChange, e.Type)
+	assert.Equal(t, ErrNotEnumerated, e.Err)
+
+	cameras := byte(2)
+	conn := chainConnection(t, ctrl, &cameras)
+	count, err := ctrl.Enumerate(context.Background(), conn)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+
+	// A camera was hot-plugged onto the end of the chain
+	cameras = 3
+	ctrl.receiveQueue <- reply(t, 0xA0, 0x38, 0xFF)
+	e = <-events
+	assert.Equal(t, Event{Type: EventNetworkChange, Cameras: 3}, e)
+	ctrl.mu.RLock()
+	assert.Equal(t, onBus(ctrl, 3), ctrl.connections[3])
+	ctrl.mu.RUnlock()
+
+	// And unplugged again
+	cameras = 1
+	ctrl.receiveQueue <- reply(t, 0x90, 0x38, 0xFF)
+	e = <-events
+	assert.Equal(t, Event{Type: EventNetworkChange, Cameras: 1}, e)
+	ctrl.mu.RLock()
+	assert.Nil(t, ctrl.connections[2])
+	assert.Nil(t, ctrl.connections[3])
+	ctrl.mu.RUnlock()
+}
@@ -16,7 +16,10 @@
 
 package visca
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // Error is an "enum"
 type Error byte
@@ -31,6 +34,69 @@ const (
 	CommandNotExecutable Error = 0x41
 )
 
+// ErrNotAnErrorMessage is returned when parsing a Message that isn't a VISCA Error
+var ErrNotAnErrorMessage = errors.New("not an error message")
+
+// Error satisfies the error interface
+func (e Error) Error() string {
+	switch e {
+	case MessageLengthError:
+		return "message length error"
+	case SyntaxError:
+		return "syntax error"
+	case CommandBufferFull:
+		return "command buffer full"
+	case CommandCanceled:
+		return "command canceled"
+	case NoSocket:
+		return "no socket (to be canceled)"
+	case CommandNotExecutable:
+		return "command not executable"
+	default:
+		return fmt.Sprintf("unknown VISCA error 0x%02X", byte(e))
+	}
+}
+
+// ReplyError is an Error returned by a camera for the command or inquiry in a socket
+//
+// It wraps the Error, so errors.Is can be used to check for a specific one:
+//
+//  if errors.Is(err, visca.CommandBufferFull) {
+//    // try again later
+//  }
+//
+// It also matches ErrCommandFailed, for callers that don't care which Error it was.
+type ReplyError struct {
+	Socket uint8
+	Code   Error
+}
+
+// Error satisfies the error interface
+func (e *ReplyError) Error() string {
+	return fmt.Sprintf("%v (socket %d)", e.Code, e.Socket)
+}
+
+// Unwrap returns the underlying Error
+func (e *ReplyError) Unwrap() error {
+	return e.Code
+}
+
+// Is reports whether target is ErrCommandFailed
+func (e *ReplyError) Is(target error) bool {
+	return target == ErrCommandFailed
+}
+
+// ParseError parses an Error message (0x6y) into a ReplyError
+func ParseError(msg Message) (*ReplyError, error) {
+	if len(msg) < 2 || msg.Type() != MsgError {
+		return nil, ErrNotAnErrorMessage
+	}
+	return &ReplyError{
+		Socket: msg.Socket(),
+		Code:   msg.Error(),
+	}, nil
+}
+
 // NewErrorMessage creates an error message
 func NewErrorMessage(socket uint8, err Error) (Message, error) {
 	if socket > 2 {
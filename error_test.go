@@ -14,3 +14,36 @@
 // 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
 
 package visca
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorStrings(t *testing.T) {
+	assert.Equal(t, "command buffer full", CommandBufferFull.Error())
+	assert.Equal(t, "command not executable", CommandNotExecutable.Error())
+	assert.Equal(t, "unknown VISCA error 0x7F", Error(0x7F).Error())
+}
+
+func TestParseError(t *testing.T) {
+	err, parseErr := ParseError(Message{0x62, 0x03})
+	assert.Nil(t, parseErr)
+	assert.Equal(t, uint8(2), err.Socket)
+	assert.Equal(t, "command buffer full (socket 2)", err.Error())
+	assert.True(t, errors.Is(err, CommandBufferFull))
+	assert.False(t, errors.Is(err, CommandNotExecutable))
+	assert.True(t, errors.Is(err, ErrCommandFailed))
+
+	var code Error
+	assert.True(t, errors.As(err, &code))
+	assert.Equal(t, CommandBufferFull, code)
+
+	_, parseErr = ParseError(Message{0x51})
+	assert.Equal(t, ErrNotAnErrorMessage, parseErr)
+
+	_, parseErr = ParseError(Message{0x60})
+	assert.Equal(t, ErrNotAnErrorMessage, parseErr)
+}
@@ -0,0 +1,104 @@
+//  events.go - notifications from the Controller
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package visca
+
+import (
+	"sync"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// EventType indicates what an Event is about
+type EventType int
+
+const (
+	// EventNetworkChange is sent after a camera reports a network change and the bus has been enumerated again
+	EventNetworkChange EventType = iota
+)
+
+// Event is something that happened on the Controller's cameras
+type Event struct {
+	Type    EventType
+	Cameras int   // number of cameras on the bus, for EventNetworkChange
+	Err     error // why handling the event failed, if it did
+}
+
+// eventQueueSize is how many Events a subscriber can fall behind by before it starts missing them
+const eventQueueSize = 8
+
+// eventHub fans Events out to subscribers
+type eventHub struct {
+	mu          sync.Mutex
+	subscribers []chan Event
+	logger      zerolog.Logger
+}
+
+// newEventHub creates an eventHub with no subscribers
+func newEventHub() *eventHub {
+	return &eventHub{
+		subscribers: make([]chan Event, 0),
+		logger:      log.Logger,
+	}
+}
+
+// subscribe adds a subscriber
+func (h *eventHub) subscribe() chan Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c := make(chan Event, eventQueueSize)
+	h.subscribers = append(h.subscribers, c)
+	return c
+}
+
+// unsubscribe removes a subscriber and closes its channel
+func (h *eventHub) unsubscribe(c <-chan Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, s := range h.subscribers {
+		if s == c {
+			h.subscribers = append(h.subscribers[:i], h.subscribers[i+1:]...)
+			close(s)
+			return
+		}
+	}
+}
+
+// publish sends an Event to every subscriber, without waiting on any that have fallen behind
+func (h *eventHub) publish(e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, s := range h.subscribers {
+		select {
+		case s <- e:
+		default:
+			h.logger.Warn().Msg("subscriber isn't keeping up; dropping event")
+		}
+	}
+}
+
+// Subscribe returns a channel that receives the Controller's Events
+//
+// Events are dropped for subscribers that fall too far behind.
+func (c *Controller) Subscribe() <-chan Event {
+	return c.events.subscribe()
+}
+
+// Unsubscribe stops sending Events to a channel returned by Subscribe, and closes it
+func (c *Controller) Unsubscribe(events <-chan Event) {
+	c.events.unsubscribe(events)
+}
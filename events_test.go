@@ -0,0 +1,46 @@
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package visca
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventHub(t *testing.T) {
+	h := newEventHub()
+	a := h.subscribe()
+	b := h.subscribe()
+
+	h.publish(Event{Type: EventNetworkChange, Cameras: 2})
+	assert.Equal(t, 2, (<-a).Cameras)
+	assert.Equal(t, 2, (<-b).Cameras)
+
+	// Slow subscribers miss events instead of blocking everyone
+	for i := 0; i < eventQueueSize+2; i++ {
+		h.publish(Event{Type: EventNetworkChange, Cameras: i})
+	}
+	assert.Equal(t, eventQueueSize, len(a))
+
+	h.unsubscribe(b)
+	_, ok := <-b
+	for ok {
+		_, ok = <-b
+	}
+	assert.False(t, ok)
+	assert.Len(t, h.subscribers, 1)
+}
@@ -14,6 +14,7 @@ require (
 	github.com/vektra/mockery/v2 v2.6.0 // indirect
 	go.bug.st/serial v1.1.2
 	golang.org/x/mod v0.4.0 // indirect
+	golang.org/x/sys v0.0.0-20200930185726-fdedc70b468f
 	golang.org/x/tools v0.0.0-20210105210202-9ed45478a130
 	google.golang.org/genproto v0.0.0-20201106154455-f9bfe239b0ba
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
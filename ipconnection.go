@@ -0,0 +1,497 @@
+//  ipconnection.go - Sony VISCA over IP connections
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package visca
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultIPPort is the UDP port Sony cameras listen for VISCA over IP on
+const DefaultIPPort = 52381
+
+// ipHeaderLength is the length of the header in front of every VISCA over IP payload
+const ipHeaderLength = 8
+
+// IPPayloadType indicates what a VISCA over IP message carries
+type IPPayloadType uint16
+
+// IPPayloadType constants
+const (
+	IPCommand        IPPayloadType = 0x0100 // a VISCA command
+	IPInquiry        IPPayloadType = 0x0110 // a VISCA inquiry
+	IPReply          IPPayloadType = 0x0111 // a VISCA ACK, Completion, or Error
+	IPDeviceSetting  IPPayloadType = 0x0120 // a VISCA device setting command
+	IPControlCommand IPPayloadType = 0x0200 // a control command, e.g. RESET
+	IPControlReply   IPPayloadType = 0x0201 // the reply to a control command, or a control error
+)
+
+// Control payloads
+var (
+	IPControlReset               = []byte{0x01}       // RESET command, and its ACK
+	IPControlSequenceAbnormality = []byte{0x0F, 0x01} // the sequence number was out of order
+	IPControlMessageAbnormality  = []byte{0x0F, 0x02} // the message type was not valid
+)
+
+// Error constants
+var (
+	ErrInvalidIPMessage           = errors.New("invalid VISCA over IP message")
+	ErrIPMessagePayloadMismatched = errors.New("VISCA over IP payload length doesn't match the header")
+)
+
+// IPMessage is a message in Sony's VISCA over IP protocol; a header followed by a payload
+type IPMessage struct {
+	Type     IPPayloadType
+	Sequence uint32
+	Payload  []byte // a whole VISCA packet (header and terminator included), or a control payload
+}
+
+// ParseIPMessage parses a datagram into an IPMessage
+func ParseIPMessage(b []byte) (*IPMessage, error) {
+	if len(b) < ipHeaderLength {
+		return nil, ErrInvalidIPMessage
+	}
+	length := int(binary.BigEndian.Uint16(b[2:4]))
+	if length != len(b)-ipHeaderLength {
+		return nil, ErrIPMessagePayloadMismatched
+	}
+	return &IPMessage{
+		Type:     IPPayloadType(binary.BigEndian.Uint16(b[0:2])),
+		Sequence: binary.BigEndian.Uint32(b[4:8]),
+		Payload:  b[ipHeaderLength:],
+	}, nil
+}
+
+// Bytes returns the wire representation of the message
+func (m *IPMessage) Bytes() []byte {
+	b := make([]byte, ipHeaderLength, ipHeaderLength+len(m.Payload))
+	binary.BigEndian.PutUint16(b[0:2], uint16(m.Type))
+	binary.BigEndian.PutUint16(b[2:4], uint16(len(m.Payload)))
+	binary.BigEndian.PutUint32(b[4:8], m.Sequence)
+	return append(b, m.Payload...)
+}
+
+// Retransmission defaults for IPConnection
+const (
+	DefaultIPTimeout = 200 * time.Millisecond
+	DefaultIPRetries = 3
+)
+
+// ipRecentReplies is how many replies are remembered to weed out duplicates caused by retransmission
+const ipRecentReplies = 32
+
+// ipKey identifies a message awaiting a reply; control messages are numbered separately from VISCA messages
+type ipKey struct {
+	control  bool
+	sequence uint32
+}
+
+// ipPending is a message that hasn't been answered yet
+type ipPending struct {
+	msg   *IPMessage
+	tries int
+	timer *time.Timer
+}
+
+// IPConnection implements the Connection interface for Sony's VISCA over IP protocol
+//
+// Every VISCA packet is sent in its own UDP datagram behind a header with the payload type, length, and a
+// sequence number. The sequence numbers are reset (on both ends) with a RESET control command when the
+// connection is started and whenever the camera complains they're out of order.
+//
+// UDP doesn't guarantee delivery, so a message that isn't answered within the timeout is sent again with
+// the same sequence number, which tells the camera it's a retransmission. Duplicate replies are dropped. A
+// VISCA message that's never answered, or that the camera rejects, is answered on the receive queue with a
+// CommandCanceled Error in socket 0, so nothing waits on it forever.
+type IPConnection struct {
+	hostPort     string
+	logger       zerolog.Logger
+	mu           sync.Mutex // protects everything below
+	receiveQueue chan *Packet
+	conn         net.Conn
+	quit         chan struct{} // closed when stopped; recreated by Start
+	done         chan struct{} // closed when the read goroutine returns
+	sequence     uint32        // sequence number of the next message sent
+	timeout      time.Duration
+	retries      int
+	pending      map[ipKey]*ipPending
+	recent       []string // recently received replies, oldest first
+}
+
+// NewIPConnection creates a new VISCA over IP connection; the port defaults to DefaultIPPort
+func NewIPConnection(hostPort string) (*IPConnection, error) {
+	if _, _, err := net.SplitHostPort(hostPort); err != nil {
+		hostPort = net.JoinHostPort(hostPort, strconv.Itoa(DefaultIPPort))
+	}
+	return &IPConnection{
+		hostPort:     hostPort,
+		logger:       log.Logger,
+		receiveQueue: nil,
+		conn:         nil,
+		timeout:      DefaultIPTimeout,
+		retries:      DefaultIPRetries,
+		pending:      make(map[ipKey]*ipPending),
+		recent:       make([]string, 0, ipRecentReplies),
+	}, nil
+}
+
+// SetLogger sets where the connection logs; it must be called before Start
+func (i *IPConnection) SetLogger(logger zerolog.Logger) {
+	i.logger = logger
+}
+
+// SetRetransmission sets how long to wait for a reply to each message, and how many times to send it
+// again before giving up; a timeout of 0 turns retransmission off
+func (i *IPConnection) SetRetransmission(timeout time.Duration, retries int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.timeout = timeout
+	i.retries = retries
+}
+
+// Start the interface
+func (i *IPConnection) Start() error {
+	conn, err := net.DialTimeout("udp", i.hostPort, time.Second)
+	if err != nil {
+		return err
+	}
+
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	i.mu.Lock()
+	i.conn = conn
+	i.quit = quit
+	i.done = done
+	i.mu.Unlock()
+
+	go i.read(conn, quit, done)
+	i.logger.Info().Msgf("Started read loop from VISCA over IP connection to %v", i.hostPort)
+
+	return i.Reset()
+}
+
+// Stop the interface
+func (i *IPConnection) Stop() {
+	i.mu.Lock()
+	conn := i.conn
+	if conn == nil {
+		i.mu.Unlock()
+		i.logger.Warn().Msg("Never Started")
+		return
+	}
+
+	// Stop the receive goroutine and retransmissions first
+	close(i.quit)
+	for key, p := range i.pending {
+		p.timer.Stop()
+		delete(i.pending, key)
+	}
+	i.conn = nil
+	done := i.done
+	i.mu.Unlock()
+
+	err := conn.Close()
+	if err != nil {
+		i.logger.Warn().Err(err).Msgf("Error stopping VISCA over IP connection to %v", i.hostPort)
+	}
+	<-done
+}
+
+// Send a packet
+func (i *IPConnection) Send(pkt *Packet) error {
+	payloadType := IPCommand
+	if pkt.Message.Type() == MsgInquiry {
+		payloadType = IPInquiry
+	}
+	i.logger.Debug().Msgf("Sending packet %v to %v", pkt, i.hostPort)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.conn == nil {
+		i.logger.Warn().Msg("not started")
+		return ErrNotStarted
+	}
+	return i.write(payloadType, pkt.Bytes())
+}
+
+// SetReceiveQueue for received packets
+func (i *IPConnection) SetReceiveQueue(q chan *Packet) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.receiveQueue = q
+}
+
+// queue returns the receive queue
+func (i *IPConnection) queue() chan *Packet {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.receiveQueue
+}
+
+// Reset sends a RESET control command, resetting the sequence numbers on both ends to 0
+func (i *IPConnection) Reset() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.conn == nil {
+		i.logger.Warn().Msg("not started")
+		return ErrNotStarted
+	}
+	return i.reset()
+}
+
+// reset sends a RESET control command; i.mu must be held
+func (i *IPConnection) reset() error {
+	i.logger.Debug().Msgf("Resetting sequence number for %v", i.hostPort)
+	i.sequence = 0
+	// replies to the new sequence numbers could look just like the old ones
+	i.recent = i.recent[:0]
+	return i.write(IPControlCommand, IPControlReset)
+}
+
+// write sends a payload with the next sequence number and waits for its reply; i.mu must be held
+func (i *IPConnection) write(payloadType IPPayloadType, payload []byte) error {
+	msg := &IPMessage{
+		Type:     payloadType,
+		Sequence: i.sequence,
+		Payload:  payload,
+	}
+	key := ipKey{control: payloadType == IPControlCommand, sequence: msg.Sequence}
+	if !key.control {
+		// RESET doesn't use up a sequence number; the camera ignores it anyway
+		i.sequence++
+	}
+
+	err := i.transmit(msg)
+	if err != nil {
+		return err
+	}
+
+	if old, ok := i.pending[key]; ok {
+		// only possible for RESETs; the new one replaces the old one
+		old.timer.Stop()
+	}
+	if i.timeout > 0 {
+		i.pending[key] = &ipPending{
+			msg:   msg,
+			timer: time.AfterFunc(i.timeout, func() { i.retransmit(key) }),
+		}
+	}
+	return nil
+}
+
+// transmit writes a single datagram
+func (i *IPConnection) transmit(msg *IPMessage) error {
+	b := msg.Bytes()
+	written, err := i.conn.Write(b)
+	if err != nil {
+		return err
+	}
+	if written != len(b) {
+		return ErrIncompletePacketSent
+	}
+	return nil
+}
+
+// retransmit sends a message again if it still hasn't been answered, or gives up on it
+func (i *IPConnection) retransmit(key ipKey) {
+	lost, quit := i.resend(key)
+	if lost != nil {
+		i.unanswered(lost, quit)
+	}
+}
+
+// resend sends a message again if it still hasn't been answered; it returns the message if it has been
+// tried too many times instead, along with the quit channel of the connection it was sent on
+func (i *IPConnection) resend(key ipKey) (*IPMessage, chan struct{}) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	p, ok := i.pending[key]
+	if !ok || i.conn == nil {
+		// answered, or stopped
+		return nil, nil
+	}
+	if p.tries >= i.retries {
+		i.logger.Warn().Msgf("No reply from %v to message %v after %v tries; giving up", i.hostPort, key.sequence, p.tries+1)
+		delete(i.pending, key)
+		return p.msg, i.quit
+	}
+	p.tries++
+	i.logger.Debug().Msgf("Resending message %v to %v", key.sequence, i.hostPort)
+	err := i.transmit(p.msg)
+	if err != nil {
+		i.logger.Err(err).Msgf("error resending message %v to %v", key.sequence, i.hostPort)
+	}
+	p.timer.Reset(i.timeout)
+	return nil, nil
+}
+
+// unanswered answers a VISCA message that will never get a reply with a CommandCanceled Error in socket 0,
+// giving up when quit is closed; i.mu must not be held, since whoever is receiving may send in response
+func (i *IPConnection) unanswered(msg *IPMessage, quit chan struct{}) {
+	if msg.Type == IPControlCommand {
+		return
+	}
+	sent, err := PacketFromBytes(msg.Payload)
+	if err != nil || sent.IsBroadcast() {
+		return
+	}
+	pkt, err := NewPacket(sent.Destination(), 0, Message{0x60, byte(CommandCanceled)})
+	if err != nil {
+		return
+	}
+	select {
+	case i.queue() <- pkt:
+	case <-quit:
+	}
+}
+
+// answered stops waiting on the message with the given key
+func (i *IPConnection) answered(key ipKey) *ipPending {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	p, ok := i.pending[key]
+	if !ok {
+		return nil
+	}
+	p.timer.Stop()
+	delete(i.pending, key)
+	return p
+}
+
+// duplicate returns true if the same reply was received recently, i.e. it answers a retransmission
+func (i *IPConnection) duplicate(msg *IPMessage) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	key := string(msg.Bytes())
+	for _, r := range i.recent {
+		if r == key {
+			return true
+		}
+	}
+	if len(i.recent) == ipRecentReplies {
+		i.recent = i.recent[1:]
+	}
+	i.recent = append(i.recent, key)
+	return false
+}
+
+// resync resets the sequence numbers and sends every unanswered VISCA message again with new ones
+func (i *IPConnection) resync() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	unanswered := make([]*IPMessage, 0, len(i.pending))
+	for key, p := range i.pending {
+		p.timer.Stop()
+		delete(i.pending, key)
+		if !key.control {
+			unanswered = append(unanswered, p.msg)
+		}
+	}
+	sort.Slice(unanswered, func(a, b int) bool {
+		return unanswered[a].Sequence < unanswered[b].Sequence
+	})
+	err := i.reset()
+	if err != nil {
+		i.logger.Err(err).Msgf("error resetting sequence number for %v", i.hostPort)
+		return
+	}
+	for _, msg := range unanswered {
+		err = i.write(msg.Type, msg.Payload)
+		if err != nil {
+			i.logger.Err(err).Msgf("error resending message to %v", i.hostPort)
+		}
+	}
+}
+
+// read receives datagrams from conn until quit is closed; it closes done when it returns
+func (i *IPConnection) read(conn net.Conn, quit, done chan struct{}) {
+	defer close(done)
+	buf := make([]byte, 1500)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			select {
+			case <-quit:
+			default:
+				i.logger.Err(err).Msgf("Read from %v stopped", i.hostPort)
+			}
+			return
+		}
+
+		b := make([]byte, n)
+		copy(b, buf[:n])
+		msg, err := ParseIPMessage(b)
+		if err != nil {
+			i.logger.Err(err).Msgf("error parsing datagram from %v", i.hostPort)
+			continue
+		}
+		i.handle(msg, quit)
+	}
+}
+
+// handle processes a received message, giving up on delivering it when quit is closed
+func (i *IPConnection) handle(msg *IPMessage, quit chan struct{}) {
+	switch msg.Type {
+	case IPReply:
+		i.answered(ipKey{sequence: msg.Sequence})
+		if i.duplicate(msg) {
+			i.logger.Debug().Msgf("Dropping duplicate reply to message %v from %v", msg.Sequence, i.hostPort)
+			return
+		}
+		pkt, err := PacketFromBytes(msg.Payload)
+		if err != nil {
+			i.logger.Err(err).Msg("error creating packet from bytes")
+			return
+		}
+		select {
+		case i.queue() <- pkt:
+		case <-quit:
+		}
+	case IPControlReply:
+		switch string(msg.Payload) {
+		case string(IPControlReset):
+			i.answered(ipKey{control: true, sequence: msg.Sequence})
+			i.logger.Debug().Msgf("Sequence number reset for %v", i.hostPort)
+		case string(IPControlSequenceAbnormality):
+			// we got out of sync somehow; start over
+			i.logger.Warn().Msgf("Sequence number abnormality reported by %v; resetting", i.hostPort)
+			i.resync()
+		case string(IPControlMessageAbnormality):
+			// sending it again won't help
+			p := i.answered(ipKey{sequence: msg.Sequence})
+			if p != nil {
+				i.logger.Error().Msgf("Message abnormality reported by %v; dropping message %v: %x", i.hostPort, msg.Sequence, p.msg.Payload)
+				i.unanswered(p.msg, quit)
+			} else {
+				i.logger.Error().Msgf("Message abnormality reported by %v for message %v", i.hostPort, msg.Sequence)
+			}
+		default:
+			i.logger.Warn().Msgf("got control reply %x from %v", msg.Payload, i.hostPort)
+		}
+	default:
+		i.logger.Warn().Msgf("got unexpected payload type 0x%04X from %v", uint16(msg.Type), i.hostPort)
+	}
+}
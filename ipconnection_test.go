@@ -0,0 +1,315 @@
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package visca
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeIPCamera listens for VISCA over IP on a local UDP port
+type fakeIPCamera struct {
+	t    *testing.T
+	conn *net.UDPConn
+	peer *net.UDPAddr
+}
+
+func newFakeIPCamera(t *testing.T) *fakeIPCamera {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP had an error: %v", err)
+	}
+	return &fakeIPCamera{t: t, conn: conn}
+}
+
+func (f *fakeIPCamera) addr() string {
+	return f.conn.LocalAddr().String()
+}
+
+// receive reads the next message sent to the camera
+func (f *fakeIPCamera) receive() *IPMessage {
+	buf := make([]byte, 1500)
+	f.conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, peer, err := f.conn.ReadFromUDP(buf)
+	if err != nil {
+		f.t.Fatalf("fake camera read error: %v", err)
+	}
+	f.peer = peer
+	msg, err := ParseIPMessage(buf[:n])
+	if err != nil {
+		f.t.Fatalf("fake camera got a bad message: %v", err)
+	}
+	return msg
+}
+
+// expectNothing makes sure nothing else is sent to the camera for a while
+func (f *fakeIPCamera) expectNothing(d time.Duration) {
+	buf := make([]byte, 1500)
+	f.conn.SetReadDeadline(time.Now().Add(d))
+	n, _, err := f.conn.ReadFromUDP(buf)
+	if err == nil {
+		f.t.Errorf("fake camera unexpectedly received %x", buf[:n])
+	}
+}
+
+// send sends a message back to whoever last sent one to the camera
+func (f *fakeIPCamera) send(msg *IPMessage) {
+	_, err := f.conn.WriteToUDP(msg.Bytes(), f.peer)
+	if err != nil {
+		f.t.Fatalf("fake camera write error: %v", err)
+	}
+}
+
+func TestIPMessage(t *testing.T) {
+	msg := &IPMessage{
+		Type:     IPCommand,
+		Sequence: 0x01020304,
+		Payload:  []byte{0x81, 0x01, 0x04, 0x07, 0x02, 0xFF},
+	}
+	b := msg.Bytes()
+	assert.Equal(t, []byte{0x01, 0x00, 0x00, 0x06, 0x01, 0x02, 0x03, 0x04, 0x81, 0x01, 0x04, 0x07, 0x02, 0xFF}, b)
+
+	parsed, err := ParseIPMessage(b)
+	assert.Nil(t, err)
+	assert.Equal(t, msg, parsed)
+
+	_, err = ParseIPMessage([]byte{0x01, 0x00, 0x00})
+	assert.Equal(t, ErrInvalidIPMessage, err)
+
+	_, err = ParseIPMessage([]byte{0x01, 0x00, 0x00, 0x09, 0x00, 0x00, 0x00, 0x00, 0x81, 0xFF})
+	assert.Equal(t, ErrIPMessagePayloadMismatched, err)
+}
+
+func TestIPConnectionDefaultPort(t *testing.T) {
+	conn, err := NewIPConnection("10.0.0.5")
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.5:52381", conn.hostPort)
+
+	conn, err = NewIPConnection("10.0.0.5:1259")
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.5:1259", conn.hostPort)
+}
+
+func TestIPConnection(t *testing.T) {
+	var _ Connection = new(IPConnection)
+
+	cam := newFakeIPCamera(t)
+	defer cam.conn.Close()
+
+	conn, err := NewConnectionFromString("visca-ip://" + cam.addr())
+	assert.Nil(t, err)
+	assert.IsType(t, &IPConnection{}, conn)
+	conn.(*IPConnection).SetRetransmission(0, 0)
+
+	q := make(chan *Packet)
+	conn.SetReceiveQueue(q)
+	assert.Nil(t, conn.Start())
+	defer conn.Stop()
+
+	// Starting resets the sequence number
+	msg := cam.receive()
+	assert.Equal(t, IPControlCommand, msg.Type)
+	assert.Equal(t, IPControlReset, msg.Payload)
+	cam.send(&IPMessage{Type: IPControlReply, Sequence: msg.Sequence, Payload: IPControlReset})
+
+	// Commands and inquiries are sent with consecutive sequence numbers
+	pkt, _ := NewPacket(0, 1, Message{0x01, 0x04, 0x07, 0x02})
+	assert.Nil(t, conn.Send(pkt))
+	msg = cam.receive()
+	assert.Equal(t, &IPMessage{Type: IPCommand, Sequence: 0, Payload: []byte{0x81, 0x01, 0x04, 0x07, 0x02, 0xFF}}, msg)
+
+	pkt, _ = NewPacket(0, 1, Message{0x09, 0x04, 0x47})
+	assert.Nil(t, conn.Send(pkt))
+	msg = cam.receive()
+	assert.Equal(t, &IPMessage{Type: IPInquiry, Sequence: 1, Payload: []byte{0x81, 0x09, 0x04, 0x47, 0xFF}}, msg)
+
+	// Replies end up on the receive queue
+	cam.send(&IPMessage{Type: IPReply, Sequence: 1, Payload: []byte{0x90, 0x50, 0x01, 0x02, 0x03, 0x04, 0xFF}})
+	select {
+	case pkt = <-q:
+		assert.Equal(t, Message{0x50, 0x01, 0x02, 0x03, 0x04}, pkt.Message)
+	case <-time.After(time.Second):
+		t.Fatal("no reply received")
+	}
+
+	// The camera says we're out of sync, so we start over
+	cam.send(&IPMessage{Type: IPControlReply, Sequence: 1, Payload: IPControlSequenceAbnormality})
+	msg = cam.receive()
+	assert.Equal(t, IPControlCommand, msg.Type)
+	assert.Equal(t, IPControlReset, msg.Payload)
+
+	pkt, _ = NewPacket(0, 1, Message{0x01, 0x04, 0x07, 0x00})
+	assert.Nil(t, conn.Send(pkt))
+	msg = cam.receive()
+	assert.Equal(t, uint32(0), msg.Sequence)
+}
+
+func TestIPConnectionNotStarted(t *testing.T) {
+	conn, _ := NewIPConnection("127.0.0.1")
+	pkt, _ := NewPacket(0, 1, Message{0x01, 0x04, 0x07, 0x00})
+	assert.Equal(t, ErrNotStarted, conn.Send(pkt))
+}
+
+func TestIPConnectionRestart(t *testing.T) {
+	cam := newFakeIPCamera(t)
+	defer cam.conn.Close()
+	conn, _ := NewIPConnection(cam.addr())
+	conn.SetRetransmission(0, 0)
+	q := make(chan *Packet)
+	conn.SetReceiveQueue(q)
+
+	assert.Nil(t, conn.Start())
+	assert.Equal(t, IPControlReset, cam.receive().Payload)
+	conn.Stop()
+	pkt, _ := NewPacket(0, 1, Message{0x01, 0x04, 0x07, 0x00})
+	assert.Equal(t, ErrNotStarted, conn.Send(pkt))
+
+	// Started again, replies are delivered
+	assert.Nil(t, conn.Start())
+	defer conn.Stop()
+	msg := cam.receive()
+	assert.Equal(t, IPControlReset, msg.Payload)
+	cam.send(&IPMessage{Type: IPReply, Sequence: 0, Payload: []byte{0x90, 0x41, 0xFF}})
+	select {
+	case pkt = <-q:
+		assert.Equal(t, Message{0x41}, pkt.Message)
+	case <-time.After(time.Second):
+		t.Fatal("no reply received")
+	}
+}
+
+// startLossy starts a connection to the fake camera with a short retransmission timeout
+func startLossy(t *testing.T, cam *fakeIPCamera, q chan *Packet) *IPConnection {
+	conn, err := NewIPConnection(cam.addr())
+	assert.Nil(t, err)
+	conn.SetRetransmission(20*time.Millisecond, 2)
+	conn.SetReceiveQueue(q)
+	assert.Nil(t, conn.Start())
+
+	// Drop the first RESET; it should be sent again
+	msg := cam.receive()
+	assert.Equal(t, IPControlReset, msg.Payload)
+	msg = cam.receive()
+	assert.Equal(t, IPControlReset, msg.Payload)
+	cam.send(&IPMessage{Type: IPControlReply, Sequence: msg.Sequence, Payload: IPControlReset})
+	return conn
+}
+
+func TestIPConnectionRetransmission(t *testing.T) {
+	cam := newFakeIPCamera(t)
+	defer cam.conn.Close()
+	q := make(chan *Packet, 4)
+	conn := startLossy(t, cam, q)
+	defer conn.Stop()
+
+	// The command is lost, so it's sent again with the same sequence number
+	pkt, _ := NewPacket(0, 1, Message{0x01, 0x04, 0x07, 0x02})
+	assert.Nil(t, conn.Send(pkt))
+	first := cam.receive()
+	again := cam.receive()
+	assert.Equal(t, first, again)
+
+	// The camera answers both; the duplicate ACK is dropped
+	ack := &IPMessage{Type: IPReply, Sequence: first.Sequence, Payload: []byte{0x90, 0x41, 0xFF}}
+	cam.send(ack)
+	cam.send(ack)
+	cam.send(&IPMessage{Type: IPReply, Sequence: first.Sequence, Payload: []byte{0x90, 0x51, 0xFF}})
+	assert.Equal(t, Message{0x41}, (<-q).Message)
+	assert.Equal(t, Message{0x51}, (<-q).Message)
+	cam.expectNothing(60 * time.Millisecond)
+	assert.Len(t, q, 0)
+}
+
+func TestIPConnectionReordered(t *testing.T) {
+	cam := newFakeIPCamera(t)
+	defer cam.conn.Close()
+	q := make(chan *Packet, 4)
+	conn := startLossy(t, cam, q)
+	defer conn.Stop()
+
+	pkt, _ := NewPacket(0, 1, Message{0x01, 0x04, 0x07, 0x02})
+	assert.Nil(t, conn.Send(pkt))
+	pkt, _ = NewPacket(0, 1, Message{0x09, 0x04, 0x47})
+	assert.Nil(t, conn.Send(pkt))
+	command := cam.receive()
+	inquiry := cam.receive()
+
+	// The replies arrive out of order, but both count
+	cam.send(&IPMessage{Type: IPReply, Sequence: inquiry.Sequence, Payload: []byte{0x90, 0x50, 0x00, 0x00, 0x00, 0x00, 0xFF}})
+	cam.send(&IPMessage{Type: IPReply, Sequence: command.Sequence, Payload: []byte{0x90, 0x41, 0xFF}})
+	assert.Equal(t, MsgCompletion, (<-q).Message.Type())
+	assert.Equal(t, MsgACK, (<-q).Message.Type())
+	cam.expectNothing(60 * time.Millisecond)
+}
+
+func TestIPConnectionGivesUp(t *testing.T) {
+	cam := newFakeIPCamera(t)
+	defer cam.conn.Close()
+	q := make(chan *Packet, 4)
+	conn := startLossy(t, cam, q)
+	defer conn.Stop()
+
+	pkt, _ := NewPacket(0, 1, Message{0x01, 0x04, 0x07, 0x02})
+	assert.Nil(t, conn.Send(pkt))
+	for n := 0; n < 3; n++ {
+		assert.Equal(t, uint32(0), cam.receive().Sequence)
+	}
+	cam.expectNothing(60 * time.Millisecond)
+
+	// Whoever sent it is told it isn't coming
+	assert.Equal(t, &Packet{1, 0, Message{0x60, 0x04}}, <-q)
+}
+
+func TestIPConnectionAbnormalities(t *testing.T) {
+	cam := newFakeIPCamera(t)
+	defer cam.conn.Close()
+	q := make(chan *Packet, 4)
+	conn := startLossy(t, cam, q)
+	defer conn.Stop()
+
+	// A message the camera doesn't like is not sent again
+	pkt, _ := NewPacket(0, 1, Message{0x01, 0x04, 0x07, 0x02})
+	assert.Nil(t, conn.Send(pkt))
+	msg := cam.receive()
+	cam.send(&IPMessage{Type: IPControlReply, Sequence: msg.Sequence, Payload: IPControlMessageAbnormality})
+	cam.expectNothing(60 * time.Millisecond)
+	assert.Equal(t, &Packet{1, 0, Message{0x60, 0x04}}, <-q)
+
+	// Out of sequence: reset, then everything unanswered is sent again from 0
+	pkt, _ = NewPacket(0, 1, Message{0x01, 0x04, 0x07, 0x03})
+	assert.Nil(t, conn.Send(pkt))
+	pkt, _ = NewPacket(0, 1, Message{0x09, 0x04, 0x47})
+	assert.Nil(t, conn.Send(pkt))
+	assert.Equal(t, uint32(1), cam.receive().Sequence)
+	assert.Equal(t, uint32(2), cam.receive().Sequence)
+	cam.send(&IPMessage{Type: IPControlReply, Sequence: 1, Payload: IPControlSequenceAbnormality})
+
+	msg = cam.receive()
+	assert.Equal(t, IPControlReset, msg.Payload)
+	cam.send(&IPMessage{Type: IPControlReply, Sequence: msg.Sequence, Payload: IPControlReset})
+	msg = cam.receive()
+	assert.Equal(t, &IPMessage{Type: IPCommand, Sequence: 0, Payload: []byte{0x81, 0x01, 0x04, 0x07, 0x03, 0xFF}}, msg)
+	cam.send(&IPMessage{Type: IPReply, Sequence: 0, Payload: []byte{0x90, 0x41, 0xFF}})
+	msg = cam.receive()
+	assert.Equal(t, &IPMessage{Type: IPInquiry, Sequence: 1, Payload: []byte{0x81, 0x09, 0x04, 0x47, 0xFF}}, msg)
+	cam.send(&IPMessage{Type: IPReply, Sequence: 1, Payload: []byte{0x90, 0x50, 0x00, 0x00, 0x00, 0x00, 0xFF}})
+	assert.Equal(t, MsgACK, (<-q).Message.Type())
+	assert.Equal(t, MsgCompletion, (<-q).Message.Type())
+	cam.expectNothing(60 * time.Millisecond)
+}
@@ -96,10 +96,8 @@ func (m Message) Type() MessageType {
 // Socket returns the socket for the packet, if applicable
 func (m Message) Socket() uint8 {
 	switch m.Type() {
-	case MsgACK:
-	case MsgCompletion:
-	case MsgError:
-		return uint8(m[0]) & 0b0000_0011
+	case MsgCancel, MsgACK, MsgCompletion, MsgError:
+		return uint8(m[0]) & SocketMask
 	default:
 	}
 	return 0
@@ -116,6 +114,29 @@ func (m Message) SetSocket(s uint8) error {
 	return nil
 }
 
+// NewCancelMessage creates a message that cancels the command executing in the given socket
+func NewCancelMessage(socket uint8) (Message, error) {
+	if socket < 1 || socket > 2 {
+		return nil, errors.New("invalid socket number")
+	}
+
+	return []byte{0x20 + socket&SocketMask}, nil
+}
+
+// NewAddressSetMessage creates an AddressSet message that numbers the cameras starting at the given address
+func NewAddressSetMessage(address uint8) (Message, error) {
+	if address < 1 || address > 7 {
+		return nil, errors.New("invalid address")
+	}
+
+	return []byte{0x30, address}, nil
+}
+
+// NewIFClearMessage creates an IF_Clear command, which clears the command buffers of the camera(s)
+func NewIFClearMessage() Message {
+	return []byte{0x01, 0x00, 0x01}
+}
+
 // Category returns the message's category code
 func (m Message) Category() CategoryCode {
 	if len(m) >= 2 {
@@ -127,3 +148,11 @@ func (m Message) Category() CategoryCode {
 	}
 	return CatInvalid
 }
+
+// Error returns the message's Error code, or 0 if it isn't an Error message
+func (m Message) Error() Error {
+	if m.Type() != MsgError || len(m) < 2 {
+		return 0
+	}
+	return Error(m[1])
+}
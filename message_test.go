@@ -40,6 +40,56 @@ func TestErrorMessage(t *testing.T) {
 		m := Message(tt.bytes)
 		assert.Equal(t, tt.messageType, m.Type())
 		assert.Equal(t, tt.socket, m.Socket())
-		// assert.Equal(t, tt.err, m.Error())
+		assert.Equal(t, tt.err, m.Error())
 	}
 }
+
+func TestMessageSocket(t *testing.T) {
+	var tests = []struct {
+		bytes  []byte
+		socket uint8
+	}{
+		{[]byte{0x01, 0x04, 0x07, 0x02}, 0},
+		{[]byte{0x21}, 1},
+		{[]byte{0x41}, 1},
+		{[]byte{0x42}, 2},
+		{[]byte{0x51}, 1},
+		{[]byte{0x52}, 2},
+		{[]byte{0x50, 0x01}, 0},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.socket, Message(tt.bytes).Socket())
+	}
+}
+
+func TestNewCancelMessage(t *testing.T) {
+	m, err := NewCancelMessage(1)
+	assert.Nil(t, err)
+	assert.Equal(t, Message{0x21}, m)
+	assert.Equal(t, MsgCancel, m.Type())
+	assert.Equal(t, uint8(1), m.Socket())
+
+	_, err = NewCancelMessage(0)
+	assert.NotNil(t, err)
+	_, err = NewCancelMessage(3)
+	assert.NotNil(t, err)
+}
+
+func TestNewAddressSetMessage(t *testing.T) {
+	m, err := NewAddressSetMessage(1)
+	assert.Nil(t, err)
+	assert.Equal(t, Message{0x30, 0x01}, m)
+	assert.Equal(t, MsgAddressSet, m.Type())
+
+	_, err = NewAddressSetMessage(8)
+	assert.NotNil(t, err)
+
+	assert.Equal(t, Message{0x01, 0x00, 0x01}, NewIFClearMessage())
+}
+
+func TestMessageTypeString(t *testing.T) {
+	assert.Equal(t, "MsgAddressSet", MsgAddressSet.String())
+	assert.Equal(t, "MsgNetworkChange", MsgNetworkChange.String())
+	assert.Equal(t, "MsgError", MsgError.String())
+}
@@ -8,9 +8,9 @@ import (
 	"fmt"
 )
 
-const _MessageTypeName = "MsgInvalidMsgCommandMsgInquiryMsgCancelMsgNetworkChangeMsgACKMsgCompletionMsgError"
+const _MessageTypeName = "MsgInvalidMsgCommandMsgInquiryMsgCancelMsgAddressSetMsgNetworkChangeMsgACKMsgCompletionMsgError"
 
-var _MessageTypeIndex = [...]uint8{0, 10, 20, 30, 39, 55, 61, 74, 82}
+var _MessageTypeIndex = [...]uint8{0, 10, 20, 30, 39, 52, 68, 74, 87, 95}
 
 func (i MessageType) String() string {
 	if i < 0 || i >= MessageType(len(_MessageTypeIndex)-1) {
@@ -19,17 +19,18 @@ func (i MessageType) String() string {
 	return _MessageTypeName[_MessageTypeIndex[i]:_MessageTypeIndex[i+1]]
 }
 
-var _MessageTypeValues = []MessageType{0, 1, 2, 3, 4, 5, 6, 7}
+var _MessageTypeValues = []MessageType{0, 1, 2, 3, 4, 5, 6, 7, 8}
 
 var _MessageTypeNameToValueMap = map[string]MessageType{
 	_MessageTypeName[0:10]:  0,
 	_MessageTypeName[10:20]: 1,
 	_MessageTypeName[20:30]: 2,
 	_MessageTypeName[30:39]: 3,
-	_MessageTypeName[39:55]: 4,
-	_MessageTypeName[55:61]: 5,
-	_MessageTypeName[61:74]: 6,
-	_MessageTypeName[74:82]: 7,
+	_MessageTypeName[39:52]: 4,
+	_MessageTypeName[52:68]: 5,
+	_MessageTypeName[68:74]: 6,
+	_MessageTypeName[74:87]: 7,
+	_MessageTypeName[87:95]: 8,
 }
 
 // MessageTypeString retrieves an enum value from the enum constants string name.
@@ -0,0 +1,152 @@
+//  model.go - how camera models measure pan and tilt
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package visca
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Error constants
+var (
+	ErrUnknownModel  = errors.New("unknown camera model")
+	ErrOutOfRange    = errors.New("position out of range")
+	ErrInvalidLength = errors.New("invalid length")
+)
+
+// Model describes how a camera model measures pan and tilt
+//
+// Cameras send positions as signed counts of their own units; a Model converts between those and degrees,
+// and knows how far the camera can turn.
+type Model struct {
+	Name       string
+	PanNibbles int     // nibbles of pan position in commands and replies, 4 or 5; tilt is always 4
+	PanScale   float64 // pan units per degree
+	TiltScale  float64 // tilt units per degree
+	MinPan     float64 // degrees; negative is left
+	MaxPan     float64
+	MinTilt    float64 // degrees; negative is down
+	MaxTilt    float64
+}
+
+// Built-in models
+var (
+	ModelSRG300    = &Model{Name: "SRG-300", PanNibbles: 5, PanScale: 235.9, TiltScale: 235.9, MinPan: -170, MaxPan: 170, MinTilt: -30, MaxTilt: 90}
+	ModelEVID70    = &Model{Name: "EVI-D70", PanNibbles: 4, PanScale: 2267.0 / 170, TiltScale: 1200.0 / 90, MinPan: -170, MaxPan: 170, MinTilt: -30, MaxTilt: 90}
+	ModelEVID100   = &Model{Name: "EVI-D100", PanNibbles: 4, PanScale: 14.4, TiltScale: 14.4, MinPan: -100, MaxPan: 100, MinTilt: -25, MaxTilt: 25}
+	ModelPTZOptics = &Model{Name: "PTZOptics", PanNibbles: 4, PanScale: 14.4, TiltScale: 14.4, MinPan: -170, MaxPan: 170, MinTilt: -30, MaxTilt: 90}
+)
+
+// DefaultModel is used for cameras whose model hasn't been set; most current Sony cameras measure like it
+var DefaultModel = ModelSRG300
+
+// Models are the built-in models
+var Models = []*Model{ModelSRG300, ModelEVID70, ModelEVID100, ModelPTZOptics}
+
+// LookupModel finds a built-in model by name, ignoring case
+func LookupModel(name string) (*Model, error) {
+	for _, m := range Models {
+		if strings.EqualFold(m.Name, name) {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownModel, name)
+}
+
+// String returns the model's name
+func (m *Model) String() string {
+	return m.Name
+}
+
+// PanPosition converts an absolute pan angle to a position, checking the camera can get there
+func (m *Model) PanPosition(deg float64) (int, error) {
+	if math.IsNaN(deg) || deg < m.MinPan || deg > m.MaxPan {
+		return 0, fmt.Errorf("%w: pan %v° isn't between %v° and %v°", ErrOutOfRange, deg, m.MinPan, m.MaxPan)
+	}
+	return int(math.Round(deg * m.PanScale)), nil
+}
+
+// TiltPosition converts an absolute tilt angle to a position, checking the camera can get there
+func (m *Model) TiltPosition(deg float64) (int, error) {
+	if math.IsNaN(deg) || deg < m.MinTilt || deg > m.MaxTilt {
+		return 0, fmt.Errorf("%w: tilt %v° isn't between %v° and %v°", ErrOutOfRange, deg, m.MinTilt, m.MaxTilt)
+	}
+	return int(math.Round(deg * m.TiltScale)), nil
+}
+
+// PanOffset converts a relative pan angle to a position offset; it can be as much as the whole range of pan
+// either way
+func (m *Model) PanOffset(deg float64) (int, error) {
+	span := m.MaxPan - m.MinPan
+	if math.IsNaN(deg) || math.Abs(deg) > span {
+		return 0, fmt.Errorf("%w: pan by %v° is more than %v°", ErrOutOfRange, deg, span)
+	}
+	return int(math.Round(deg * m.PanScale)), nil
+}
+
+// TiltOffset converts a relative tilt angle to a position offset; it can be as much as the whole range of
+// tilt either way
+func (m *Model) TiltOffset(deg float64) (int, error) {
+	span := m.MaxTilt - m.MinTilt
+	if math.IsNaN(deg) || math.Abs(deg) > span {
+		return 0, fmt.Errorf("%w: tilt by %v° is more than %v°", ErrOutOfRange, deg, span)
+	}
+	return int(math.Round(deg * m.TiltScale)), nil
+}
+
+// PanDegrees converts a pan position to degrees
+func (m *Model) PanDegrees(pos int) float64 {
+	return float64(pos) / m.PanScale
+}
+
+// TiltDegrees converts a tilt position to degrees
+func (m *Model) TiltDegrees(pos int) float64 {
+	return float64(pos) / m.TiltScale
+}
+
+// EncodePosition encodes pan and tilt positions into nibbles, as they're sent in AbsolutePosition and
+// RelativePosition
+func (m *Model) EncodePosition(pan, tilt int) []byte {
+	return append(EncodeNibbles(pan, m.PanNibbles), EncodeNibbles(tilt, 4)...)
+}
+
+// DecodePosition decodes the nibbles of pan and tilt position in the reply to Pan-tiltPosInq into degrees
+func (m *Model) DecodePosition(nibbles []byte) (pan, tilt float64, err error) {
+	p, t, err := m.decodeUnits(nibbles)
+	if err != nil {
+		return 0, 0, err
+	}
+	return m.PanDegrees(p), m.TiltDegrees(t), nil
+}
+
+// decodeUnits decodes the nibbles of pan and tilt position in the reply to Pan-tiltPosInq
+func (m *Model) decodeUnits(nibbles []byte) (pan, tilt int, err error) {
+	if len(nibbles) != m.PanNibbles+4 {
+		return 0, 0, ErrInvalidLength
+	}
+	p, ok := DecodeNibbles(nibbles[:m.PanNibbles], true)
+	if !ok {
+		return 0, 0, ErrInvalidReply
+	}
+	t, ok := DecodeNibbles(nibbles[m.PanNibbles:], true)
+	if !ok {
+		return 0, 0, ErrInvalidReply
+	}
+	return p, t, nil
+}
@@ -0,0 +1,70 @@
+package visca
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupModel(t *testing.T) {
+	m, err := LookupModel("evi-d100")
+	assert.Nil(t, err)
+	assert.Equal(t, ModelEVID100, m)
+	assert.Equal(t, "EVI-D100", m.String())
+
+	_, err = LookupModel("SRG-999")
+	assert.True(t, errors.Is(err, ErrUnknownModel))
+}
+
+func TestModelPositions(t *testing.T) {
+	var tests = []struct {
+		model     *Model
+		pan, tilt float64
+		want      []byte
+	}{
+		{ModelSRG300, -170, 90, []byte{0x0F, 0x06, 0x03, 0x05, 0x09, 0x05, 0x02, 0x0E, 0x0F}},
+		{ModelSRG300, 0, -30, []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x0E, 0x04, 0x05, 0x0B}},
+		{ModelPTZOptics, 45, -30, []byte{0x00, 0x02, 0x08, 0x08, 0x0F, 0x0E, 0x05, 0x00}},
+		{ModelEVID100, -100, 25, []byte{0x0F, 0x0A, 0x06, 0x00, 0x00, 0x01, 0x06, 0x08}},
+		{ModelEVID70, 170, -30, []byte{0x00, 0x08, 0x0D, 0x0B, 0x0F, 0x0E, 0x07, 0x00}},
+	}
+	for _, tt := range tests {
+		pan, err := tt.model.PanPosition(tt.pan)
+		assert.Nil(t, err)
+		tilt, err := tt.model.TiltPosition(tt.tilt)
+		assert.Nil(t, err)
+		assert.Equal(t, tt.want, tt.model.EncodePosition(pan, tilt), "%v %v %v", tt.model, tt.pan, tt.tilt)
+
+		panDeg, tiltDeg, err := tt.model.DecodePosition(tt.want)
+		assert.Nil(t, err)
+		assert.InDelta(t, tt.pan, panDeg, 0.01)
+		assert.InDelta(t, tt.tilt, tiltDeg, 0.01)
+	}
+}
+
+func TestModelRanges(t *testing.T) {
+	m := ModelEVID100
+	_, err := m.PanPosition(100.5)
+	assert.True(t, errors.Is(err, ErrOutOfRange))
+	_, err = m.TiltPosition(-26)
+	assert.True(t, errors.Is(err, ErrOutOfRange))
+	_, err = m.PanPosition(math.NaN())
+	assert.True(t, errors.Is(err, ErrOutOfRange))
+
+	// relative moves can cross the whole range
+	pan, err := m.PanOffset(-200)
+	assert.Nil(t, err)
+	assert.Equal(t, -2880, pan)
+	_, err = m.PanOffset(200.5)
+	assert.True(t, errors.Is(err, ErrOutOfRange))
+	tilt, err := m.TiltOffset(50)
+	assert.Nil(t, err)
+	assert.Equal(t, 720, tilt)
+	_, err = m.TiltOffset(-51)
+	assert.True(t, errors.Is(err, ErrOutOfRange))
+
+	_, _, err = m.DecodePosition([]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00})
+	assert.Equal(t, ErrInvalidLength, err)
+}
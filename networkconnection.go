@@ -17,88 +17,75 @@
 package visca
 
 import (
+	"io"
 	"net"
 	"time"
 
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
 )
 
-// NetworkConnection implements the Iface interface without sending packets anywhere
+// NetworkConnection implements the Connection interface for VISCA over a stream or datagram socket
+//
+// If the connection fails after it's been started, it's redialed with a Backoff until it's stopped.
 type NetworkConnection struct {
-	hostPort     string
-	proto        string
-	conn         net.Conn
-	scanner      *Scanner
-	receiveQueue chan *Packet
-	quit         chan struct{}
+	hostPort string
+	proto    string
+	link     *link
 }
 
 // NewNetworkConnection creates a new connection with the specified protocol
 func NewNetworkConnection(proto string, hostPort string) (*NetworkConnection, error) {
-	return &NetworkConnection{
-		hostPort:     hostPort,
-		proto:        proto,
-		conn:         nil,
-		scanner:      nil,
-		receiveQueue: nil,
-		quit:         make(chan struct{}),
-	}, nil
+	i := &NetworkConnection{
+		hostPort: hostPort,
+		proto:    proto,
+	}
+	i.link = newLink(proto+" connection to "+hostPort, i.Dial)
+	return i, nil
+}
+
+// Dial opens a new connection to the camera, separate from the one used once the connection is started
+func (i *NetworkConnection) Dial() (io.ReadWriteCloser, error) {
+	return net.DialTimeout(i.proto, i.hostPort, time.Second)
 }
 
 // Start the interface
 func (i *NetworkConnection) Start() error {
-	conn, err := net.DialTimeout(i.proto, i.hostPort, time.Second)
-	if err != nil {
-		return err
-	}
-
-	i.conn = conn
-
-	i.scanner = NewScanner(i.conn)
-
-	go i.scanner.Scan(i.receiveQueue, i.quit)
-	log.Info().Msgf("Started read loop from %v connection to %v", i.proto, i.hostPort)
-
-	return nil
+	return i.link.start()
 }
 
 // Stop the interface
 func (i *NetworkConnection) Stop() {
-	if i.conn == nil {
-		log.Warn().Msg("Never Started")
-		return
-	}
-
-	// Stop the receive goroutine first
-	close(i.quit)
-	err := i.conn.Close()
-	if err != nil {
-		log.Warn().Err(err).Msgf("Error stopping %v connection to %v", i.proto, i.hostPort)
+	if !i.link.stop() {
+		i.link.logger.Warn().Msg("Never Started")
 	}
-	return
 }
 
 // Send a packet
 func (i *NetworkConnection) Send(pkt *Packet) error {
-	if i.conn == nil {
-		log.Warn().Msg("not started")
-		return ErrNotStarted
+	i.link.logger.Debug().Msgf("Sending packet %v to %v", pkt, i.hostPort)
+	err := i.link.write(pkt.Bytes())
+	if err == ErrNotStarted {
+		i.link.logger.Warn().Msg("not started")
 	}
-	log.Debug().Msgf("Sending packet %v to %v", pkt, i.hostPort)
-	written, err := i.conn.Write(pkt.Bytes())
-	if err != nil {
-		return err
-	}
-
-	if written != len(pkt.Bytes()) {
-		return ErrIncompletePacketSent
-	}
-
-	return nil
+	return err
 }
 
 // SetReceiveQueue for received packets
 func (i *NetworkConnection) SetReceiveQueue(q chan *Packet) {
-	i.receiveQueue = q
-	return
+	i.link.setReceiveQueue(q)
+}
+
+// SetBackoff sets how long to wait between attempts to reconnect
+func (i *NetworkConnection) SetBackoff(b Backoff) {
+	i.link.setBackoff(b)
+}
+
+// SetStateHandler sets a function to be told whenever the connection goes up or down
+func (i *NetworkConnection) SetStateHandler(h StateHandler) {
+	i.link.setStateHandler(h)
+}
+
+// SetLogger sets where the connection logs; it must be called before Start
+func (i *NetworkConnection) SetLogger(logger zerolog.Logger) {
+	i.link.logger = logger
 }
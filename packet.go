@@ -23,6 +23,9 @@ import (
 // Terminator is the last byte in a VISCA packet
 const Terminator = 0xFF
 
+// BroadcastAddress is the destination address of packets sent to every camera
+const BroadcastAddress = 8
+
 // Errors
 var (
 	ErrInvalidVISCAPacket = errors.New("Invalid VISCA packet")
@@ -38,7 +41,7 @@ type Packet struct {
 
 // NewPacket constructs a new Packet or returns an error
 func NewPacket(source, destination int, message []byte) (*Packet, error) {
-	if source > 7 || destination > 8 {
+	if source > 7 || destination > BroadcastAddress {
 		return nil, ErrAddressOutOfBounds
 	}
 
@@ -54,6 +57,11 @@ func NewPacket(source, destination int, message []byte) (*Packet, error) {
 	}, nil
 }
 
+// NewBroadcastPacket constructs a new Packet from the controller to every camera
+func NewBroadcastPacket(message []byte) (*Packet, error) {
+	return NewPacket(0, BroadcastAddress, message)
+}
+
 // PacketFromBytes constructs a Packet from raw bytes or returns an error
 func PacketFromBytes(byteArr []byte) (*Packet, error) {
 	// Check for packet small/big
@@ -97,7 +105,7 @@ func (p *Packet) Bytes() (bytes []byte) {
 
 // IsBroadcast returns true if the packet is a broadcast packet, false otherwise
 func (p *Packet) IsBroadcast() bool {
-	return p.destination == 8
+	return p.destination == BroadcastAddress
 }
 
 // Source returns the source address
@@ -76,3 +76,10 @@ func TestIsBroadcast(t *testing.T) {
 	pkt.destination = 8
 	assert.Equal(t, true, pkt.IsBroadcast())
 }
+
+func TestNewBroadcastPacket(t *testing.T) {
+	pkt, err := NewBroadcastPacket(Message{0x30, 0x01})
+	assert.Nil(t, err)
+	assert.True(t, pkt.IsBroadcast())
+	assert.Equal(t, []byte{0x88, 0x30, 0x01, 0xFF}, pkt.Bytes())
+}
@@ -0,0 +1,195 @@
+//  pairer.go - pairing a camera's replies with the requests sent to it
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package visca
+
+// Pairer pairs the replies from a single camera with the requests sent to it, the way the camera uses its
+// sockets
+//
+// Commands are ACKed in the order they were sent, and the ACK gives the oldest command the socket the camera
+// is executing it in; the Completion or Error in that socket answers it. Inquiries are never ACKed, and
+// their Completions come back in socket 0, so a Completion in socket 0 answers the oldest inquiry, or if
+// there isn't one, the oldest command (IF_Clear is completed without an ACK). An Error in socket 0 answers
+// the oldest request of any type, since that's how a camera turns away a request it couldn't take, e.g.
+// with a syntax error or a full buffer.
+//
+// A request is whatever the caller wants back when its reply arrives, e.g. a pointer to its own record of
+// it; it must not be nil. A Pairer isn't safe for concurrent use.
+//
+// Example
+//
+//  p := visca.NewPairer()
+//  p.Add(msg, req)
+//  // ... and for each reply from the camera:
+//  if req, ok := p.Pair(reply).(*myRequest); ok {
+//    // reply answers req
+//  }
+type Pairer struct {
+	unacked []pairing   // sent, but not yet ACKed or answered, in the order they were sent
+	sockets [3]*pairing // ACKed and executing; indexed by socket, 0 is not used
+}
+
+// pairing is a request and the Message that was sent for it
+type pairing struct {
+	message Message
+	request interface{}
+}
+
+// NewPairer creates a Pairer with nothing outstanding
+func NewPairer() *Pairer {
+	return &Pairer{
+		unacked: make([]pairing, 0),
+	}
+}
+
+// Add starts waiting for the reply to a command or inquiry; it should be called before the Message is sent
+func (p *Pairer) Add(msg Message, request interface{}) {
+	p.unacked = append(p.unacked, pairing{message: msg, request: request})
+}
+
+// Remove stops waiting for the reply to a request, e.g. because it could not be sent; requests are compared
+// with ==. It returns false if the request wasn't outstanding.
+func (p *Pairer) Remove(request interface{}) bool {
+	for i, u := range p.unacked {
+		if u.request == request {
+			p.unacked = append(p.unacked[:i:i], p.unacked[i+1:]...)
+			return true
+		}
+	}
+	for s, u := range p.sockets {
+		if u != nil && u.request == request {
+			p.sockets[s] = nil
+			return true
+		}
+	}
+	return false
+}
+
+// Pair returns the request an ACK, Completion or Error answers, or nil if nothing was waiting for it
+//
+// A request is outstanding until its Completion or Error is paired with it.
+func (p *Pairer) Pair(reply Message) interface{} {
+	if len(reply) == 0 {
+		return nil
+	}
+	socket := reply.Socket()
+	switch reply.Type() {
+	case MsgACK:
+		if socket == 0 || int(socket) >= len(p.sockets) {
+			return nil
+		}
+		i := p.oldest(MsgCommand)
+		if i < 0 {
+			return nil
+		}
+		u := p.take(i)
+		p.sockets[socket] = &u
+		return u.request
+	case MsgCompletion, MsgError:
+		if socket != 0 {
+			if int(socket) >= len(p.sockets) || p.sockets[socket] == nil {
+				return nil
+			}
+			u := p.sockets[socket]
+			p.sockets[socket] = nil
+			return u.request
+		}
+		i := 0
+		if reply.Type() == MsgCompletion {
+			i = p.oldest(MsgInquiry)
+			if i < 0 {
+				i = 0
+			}
+		}
+		if i >= len(p.unacked) {
+			return nil
+		}
+		return p.take(i).request
+	default:
+		return nil
+	}
+}
+
+// InSocket returns the request executing in the given socket, or nil if the socket is free
+func (p *Pairer) InSocket(socket uint8) interface{} {
+	if int(socket) >= len(p.sockets) || p.sockets[socket] == nil {
+		return nil
+	}
+	return p.sockets[socket].request
+}
+
+// Shift stops waiting for the oldest request that hasn't been ACKed and returns it, or nil if there isn't one
+//
+// This is how broadcasts are answered: they travel around the whole chain and come back to us.
+func (p *Pairer) Shift() interface{} {
+	if len(p.unacked) == 0 {
+		return nil
+	}
+	return p.take(0).request
+}
+
+// Unacked returns the requests that haven't been ACKed or answered yet, oldest first
+func (p *Pairer) Unacked() []interface{} {
+	requests := make([]interface{}, len(p.unacked))
+	for i, u := range p.unacked {
+		requests[i] = u.request
+	}
+	return requests
+}
+
+// Len returns the number of outstanding requests
+func (p *Pairer) Len() int {
+	n := len(p.unacked)
+	for _, u := range p.sockets {
+		if u != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Clear stops waiting for every request and returns them, the unACKed ones oldest first and then the ones
+// in sockets 1 and 2
+//
+// The camera forgets all of its commands when it's sent an IF_Clear, so nothing will answer them.
+func (p *Pairer) Clear() []interface{} {
+	requests := p.Unacked()
+	for s, u := range p.sockets {
+		if u != nil {
+			requests = append(requests, u.request)
+			p.sockets[s] = nil
+		}
+	}
+	p.unacked = make([]pairing, 0)
+	return requests
+}
+
+// oldest returns the index of the oldest unACKed request of the given type, or -1 if there isn't one
+func (p *Pairer) oldest(typ MessageType) int {
+	for i, u := range p.unacked {
+		if u.message.Type() == typ {
+			return i
+		}
+	}
+	return -1
+}
+
+// take removes and returns the unACKed request at the given index
+func (p *Pairer) take(i int) pairing {
+	u := p.unacked[i]
+	p.unacked = append(p.unacked[:i:i], p.unacked[i+1:]...)
+	return u
+}
@@ -0,0 +1,94 @@
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package visca
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPairer(t *testing.T) {
+	p := NewPairer()
+	zoom, recall, pos := "zoom", "recall", "pos"
+	p.Add(Message{0x01, 0x04, 0x07, 0x02}, zoom)
+	p.Add(Message{0x09, 0x06, 0x12}, pos)
+	p.Add(Message{0x01, 0x04, 0x3F, 0x02, 0x01}, recall)
+	assert.Equal(t, 3, p.Len())
+
+	// ACKs go to the commands, oldest first, skipping the inquiry
+	assert.Equal(t, zoom, p.Pair(Message{0x41}))
+	assert.Equal(t, recall, p.Pair(Message{0x42}))
+	assert.Equal(t, zoom, p.InSocket(1))
+	assert.Equal(t, []interface{}{pos}, p.Unacked())
+
+	// Completions in a socket answer what's in it; in socket 0, the inquiry
+	assert.Equal(t, recall, p.Pair(Message{0x52}))
+	assert.Equal(t, pos, p.Pair(Message{0x50, 0x01, 0x02}))
+	assert.Equal(t, zoom, p.Pair(Message{0x61, 0x41}))
+	assert.Equal(t, 0, p.Len())
+
+	// Nothing left to answer
+	assert.Nil(t, p.Pair(Message{0x41}))
+	assert.Nil(t, p.Pair(Message{0x51}))
+	assert.Nil(t, p.Pair(Message{0x60, 0x02}))
+	assert.Nil(t, p.Pair(Message{}))
+}
+
+func TestPairerSocketZero(t *testing.T) {
+	p := NewPairer()
+
+	// An Error in socket 0 answers the oldest request, whatever it is
+	p.Add(Message{0x01, 0x04, 0x07, 0x02}, "zoom")
+	p.Add(Message{0x09, 0x04, 0x47}, "inq")
+	assert.Equal(t, "zoom", p.Pair(Message{0x60, 0x03}))
+	assert.Equal(t, "inq", p.Pair(Message{0x60, 0x02}))
+
+	// IF_Clear is completed without an ACK
+	p.Add(Message{0x01, 0x00, 0x01}, "clear")
+	assert.Equal(t, "clear", p.Pair(Message{0x50}))
+
+	// Sockets the camera doesn't have
+	p.Add(Message{0x01, 0x04, 0x07, 0x02}, "zoom")
+	assert.Nil(t, p.Pair(Message{0x40}))
+	assert.Nil(t, p.Pair(Message{0x4F}))
+	assert.Nil(t, p.Pair(Message{0x5F}))
+	assert.Nil(t, p.InSocket(7))
+	assert.Equal(t, 1, p.Len())
+}
+
+func TestPairerRemoveAndClear(t *testing.T) {
+	p := NewPairer()
+	a, b, c := &struct{ int }{1}, &struct{ int }{2}, &struct{ int }{3}
+	p.Add(Message{0x01, 0x04, 0x07, 0x02}, a)
+	p.Add(Message{0x01, 0x04, 0x07, 0x03}, b)
+	p.Add(Message{0x01, 0x04, 0x07, 0x00}, c)
+
+	assert.True(t, p.Remove(a))
+	assert.False(t, p.Remove(a))
+	assert.Equal(t, b, p.Pair(Message{0x42}))
+	assert.True(t, p.Remove(b))
+	assert.Nil(t, p.InSocket(2))
+
+	assert.Equal(t, c, p.Pair(Message{0x41}))
+	p.Add(Message{0x09, 0x04, 0x47}, a)
+	assert.Equal(t, []interface{}{a, c}, p.Clear())
+	assert.Equal(t, 0, p.Len())
+	assert.Nil(t, p.Shift())
+
+	p.Add(Message{0x30, 0x01}, b)
+	assert.Equal(t, b, p.Shift())
+}
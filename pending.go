@@ -0,0 +1,185 @@
+//  pending.go - tracking of outstanding commands and inquiries
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package visca
+
+import (
+	"sync"
+	"time"
+)
+
+// replyTimeout is how long a request is kept waiting for its ACK or socket 0 reply; cameras answer well
+// within it, so anything older was lost
+const replyTimeout = time.Second
+
+// request is a Message that was sent to a camera and is waiting for its reply
+type request struct {
+	message Message
+	socket  uint8         // assigned by the camera's ACK; 0 until then
+	acked   chan struct{} // closed when the ACK arrives
+	done    chan struct{} // closed when the Completion or Error arrives
+	reply   *Packet       // the Completion or Error; only valid once done is closed
+	err     error         // set instead of reply if the request was given up on
+	sent    time.Time     // when it started being tracked
+	cancel  bool          // nobody is waiting for it; cancel the command as soon as it's ACKed
+}
+
+// newRequest creates a request for the given Message
+func newRequest(msg Message) *request {
+	return &request{
+		message: msg,
+		acked:   make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// expectsACK returns true if the camera ACKs the request before completing it
+func (r *request) expectsACK() bool {
+	return r.message.Type() == MsgCommand
+}
+
+// tracker pairs the replies from a single camera with the requests sent to it, using a Pairer, and resolves
+// the requests as their replies arrive
+//
+// A request waits in line for its ACK, or its socket 0 reply, in case it's just late, but only for so long:
+// if its ACK was lost, the next command's ACK would otherwise be taken for it. That goes for requests nobody
+// is waiting on too, like the commands sent by sendMessage.
+type tracker struct {
+	mu      sync.Mutex
+	pairer  *Pairer
+	timeout time.Duration // how long requests wait for an ACK or a socket 0 reply
+}
+
+// newTracker creates an empty tracker
+func newTracker() *tracker {
+	return &tracker{
+		pairer:  NewPairer(),
+		timeout: replyTimeout,
+	}
+}
+
+// add starts tracking the request; it must be called before the request is sent
+func (t *tracker) add(r *request) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.expire()
+	r.sent = time.Now()
+	t.pairer.Add(r.message, r)
+}
+
+// remove stops tracking the request, e.g. because it could not be sent
+func (t *tracker) remove(r *request) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pairer.Remove(r)
+}
+
+// inSocket returns the request executing in the given socket, or nil if the socket is free
+func (t *tracker) inSocket(socket uint8) *request {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r, _ := t.pairer.InSocket(socket).(*request)
+	return r
+}
+
+// cancelOnACK marks the request as abandoned, arranging for it to be canceled once the camera tells us its
+// socket
+//
+// If the request has already been ACKed, its socket is returned instead and it must be canceled now.
+// Requests that have already completed are left alone.
+func (t *tracker) cancelOnACK(r *request) (socket uint8, acked bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if r.socket != 0 {
+		return r.socket, t.pairer.InSocket(r.socket) == r
+	}
+	r.cancel = true
+	return 0, false
+}
+
+// shouldCancel returns true if the request was marked to be canceled once ACKed
+func (t *tracker) shouldCancel(r *request) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return r.cancel && r.expectsACK()
+}
+
+// expire resolves the requests that have waited too long for an ACK or a socket 0 reply with ErrNoReply;
+// t.mu must be held
+func (t *tracker) expire() {
+	for _, u := range t.pairer.Unacked() {
+		r := u.(*request)
+		if time.Since(r.sent) >= t.timeout {
+			t.pairer.Remove(r)
+			r.err = ErrNoReply
+			close(r.done)
+		}
+	}
+}
+
+// next resolves the oldest request with the given reply, regardless of its type
+//
+// This is how broadcasts are answered: they travel around the whole chain and come back to us.
+func (t *tracker) next(pkt *Packet) *request {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r, ok := t.pairer.Shift().(*request)
+	if !ok {
+		return nil
+	}
+	r.reply = pkt
+	close(r.done)
+	return r
+}
+
+// clear resolves every request with a CommandCanceled error from the given camera
+//
+// The camera forgets all of its commands when it's sent an IF_Clear, so nothing else will resolve them.
+func (t *tracker) clear(camera int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, u := range t.pairer.Clear() {
+		r := u.(*request)
+		r.reply = &Packet{
+			source:      camera,
+			destination: 0,
+			Message:     Message{0x60 + r.socket&SocketMask, byte(CommandCanceled)},
+		}
+		close(r.done)
+	}
+}
+
+// handle matches an ACK, Completion, or Error packet to the request it belongs to
+//
+// It returns the matched request, or nil if nothing was waiting for the packet.
+func (t *tracker) handle(pkt *Packet) *request {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.expire()
+
+	r, ok := t.pairer.Pair(pkt.Message).(*request)
+	if !ok {
+		return nil
+	}
+	if pkt.Message.Type() == MsgACK {
+		r.socket = pkt.Message.Socket()
+		close(r.acked)
+		return r
+	}
+	r.reply = pkt
+	close(r.done)
+	return r
+}
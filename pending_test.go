@@ -0,0 +1,139 @@
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package visca
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func reply(t *testing.T, b ...byte) *Packet {
+	pkt, err := PacketFromBytes(b)
+	if err != nil {
+		t.Fatalf("bad test packet %x: %v", b, err)
+	}
+	return pkt
+}
+
+func isClosed(c chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestTrackerSockets(t *testing.T) {
+	tr := newTracker()
+	zoom := newRequest(Message{0x01, 0x04, 0x07, 0x02})
+	recall := newRequest(Message{0x01, 0x04, 0x3F, 0x02, 0x01})
+	tr.add(zoom)
+	tr.add(recall)
+
+	// ACKs are handed out in the order the commands were sent
+	assert.Equal(t, zoom, tr.handle(reply(t, 0x90, 0x41, 0xFF)))
+	assert.Equal(t, uint8(1), zoom.socket)
+	assert.True(t, isClosed(zoom.acked))
+	assert.Equal(t, recall, tr.handle(reply(t, 0x90, 0x42, 0xFF)))
+	assert.Equal(t, uint8(2), recall.socket)
+
+	// Completions can come back in any order
+	assert.Equal(t, recall, tr.handle(reply(t, 0x90, 0x52, 0xFF)))
+	assert.True(t, isClosed(recall.done))
+	assert.False(t, isClosed(zoom.done))
+	assert.Equal(t, Message{0x52}, recall.reply.Message)
+
+	assert.Equal(t, zoom, tr.handle(reply(t, 0x90, 0x61, 0x41, 0xFF)))
+	assert.True(t, isClosed(zoom.done))
+	assert.Equal(t, MsgError, zoom.reply.Message.Type())
+
+	// Nothing left to complete
+	assert.Nil(t, tr.handle(reply(t, 0x90, 0x51, 0xFF)))
+	assert.Nil(t, tr.handle(reply(t, 0x90, 0x41, 0xFF)))
+}
+
+func TestTrackerSocketZero(t *testing.T) {
+	tr := newTracker()
+	inq := newRequest(Message{0x09, 0x04, 0x47})
+	cmd := newRequest(Message{0x01, 0x04, 0x07, 0x02})
+	tr.add(inq)
+	tr.add(cmd)
+
+	// Inquiries aren't ACKed, so the ACK goes to the command behind it
+	assert.Equal(t, cmd, tr.handle(reply(t, 0x90, 0x41, 0xFF)))
+	assert.False(t, isClosed(inq.acked))
+
+	// The inquiry's reply comes back on socket 0
+	assert.Equal(t, inq, tr.handle(reply(t, 0x90, 0x50, 0x01, 0x02, 0x03, 0x04, 0xFF)))
+	assert.Equal(t, Message{0x50, 0x01, 0x02, 0x03, 0x04}, inq.reply.Message)
+
+	// A command that never got a socket gets its error on socket 0
+	full := newRequest(Message{0x01, 0x04, 0x07, 0x03})
+	tr.add(full)
+	assert.Equal(t, full, tr.handle(reply(t, 0x90, 0x60, 0x03, 0xFF)))
+	assert.False(t, isClosed(full.acked))
+	assert.True(t, isClosed(full.done))
+}
+
+func TestTrackerRemove(t *testing.T) {
+	tr := newTracker()
+	a := newRequest(Message{0x01, 0x04, 0x07, 0x02})
+	b := newRequest(Message{0x01, 0x04, 0x07, 0x03})
+	tr.add(a)
+	tr.add(b)
+	tr.remove(a)
+
+	assert.Equal(t, b, tr.handle(reply(t, 0x90, 0x41, 0xFF)))
+	tr.remove(b)
+	assert.Nil(t, tr.handle(reply(t, 0x90, 0x51, 0xFF)))
+}
+
+func TestTrackerLostReplies(t *testing.T) {
+	tr := newTracker()
+	tr.timeout = 10 * time.Millisecond
+	lost := newRequest(Message{0x01, 0x04, 0x07, 0x02})
+	inq := newRequest(Message{0x09, 0x04, 0x47})
+	unwatched := newRequest(Message{0x01, 0x04, 0x00, 0x02})
+	tr.add(lost)
+	tr.add(inq)
+	tr.add(unwatched)
+	_, acked := tr.cancelOnACK(lost)
+	assert.False(t, acked)
+	tr.cancelOnACK(inq)
+
+	// Still in line while the replies might just be late
+	assert.Equal(t, 3, tr.pairer.Len())
+
+	// The ACK and Completion never came, so they don't take the next request's replies
+	time.Sleep(20 * time.Millisecond)
+	zoom := newRequest(Message{0x01, 0x04, 0x07, 0x03})
+	pos := newRequest(Message{0x09, 0x06, 0x12})
+	tr.add(zoom)
+	tr.add(pos)
+	assert.Equal(t, zoom, tr.handle(reply(t, 0x90, 0x41, 0xFF)))
+	assert.False(t, tr.shouldCancel(zoom))
+	assert.Equal(t, pos, tr.handle(reply(t, 0x90, 0x50, 0x01, 0xFF)))
+	assert.False(t, isClosed(lost.acked))
+	for _, r := range []*request{lost, inq, unwatched} {
+		assert.True(t, isClosed(r.done))
+		assert.Equal(t, ErrNoReply, r.err)
+	}
+	assert.Equal(t, 1, tr.pairer.Len())
+	assert.Equal(t, zoom, tr.inSocket(1))
+}
@@ -0,0 +1,313 @@
+//  reconnect.go - keeping stream connections up
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package visca
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrNotConnected is returned when sending on a Connection that is waiting to reconnect
+var ErrNotConnected = errors.New("not connected")
+
+// ConnectionState is the state of a Connection's link to its camera(s)
+type ConnectionState int
+
+// ConnectionState constants
+const (
+	StateConnecting ConnectionState = iota // dialing or opening the device
+	StateUp                                // connected
+	StateDown                              // failed; will try again after a backoff, unless stopped
+)
+
+// String returns the name of the state
+func (s ConnectionState) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateUp:
+		return "up"
+	case StateDown:
+		return "down"
+	default:
+		return "unknown"
+	}
+}
+
+// StateHandler is called whenever a Connection's state changes; err is why it went down, if it did
+//
+// It's called from the Connection's own goroutine, so it shouldn't block.
+type StateHandler func(state ConnectionState, err error)
+
+// Backoff controls how long to wait between attempts to reconnect
+//
+// The delay starts at Initial and is multiplied by Multiplier after every failed attempt, up to Max. Up to
+// Jitter (0-1) of each delay is randomized, so cameras that went down together don't all retry together.
+type Backoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	Jitter     float64
+}
+
+// DefaultBackoff is the Backoff used by Connections unless they're told otherwise
+var DefaultBackoff = Backoff{
+	Initial:    100 * time.Millisecond,
+	Max:        30 * time.Second,
+	Multiplier: 2,
+	Jitter:     0.2,
+}
+
+// delay returns how long to wait before the given attempt (0 being the first retry)
+func (b Backoff) delay(attempt int) time.Duration {
+	d := float64(b.Initial)
+	for i := 0; i < attempt && d < float64(b.Max); i++ {
+		d *= b.Multiplier
+	}
+	if d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+	d -= d * b.Jitter * rand.Float64()
+	return time.Duration(d)
+}
+
+// link keeps a stream to a camera up, redialing with a Backoff whenever reading or writing fails
+//
+// Received packets go to the receive queue, which is never closed, so it can be shared with other
+// Connections.
+type link struct {
+	name         string
+	dial         func() (io.ReadWriteCloser, error)
+	logger       zerolog.Logger // only set before starting
+	mu           sync.Mutex     // protects everything below
+	rw           io.ReadWriteCloser
+	backoff      Backoff
+	onState      StateHandler
+	receiveQueue chan *Packet
+	quit         chan struct{}
+	done         chan struct{} // closed when the run goroutine returns
+}
+
+// newLink creates a link that connects with the given dial function; name is used for logging
+func newLink(name string, dial func() (io.ReadWriteCloser, error)) *link {
+	return &link{
+		name:    name,
+		dial:    dial,
+		logger:  log.Logger,
+		backoff: DefaultBackoff,
+	}
+}
+
+// setState reports a state change to the StateHandler, if there is one
+func (l *link) setState(state ConnectionState, err error) {
+	l.mu.Lock()
+	onState := l.onState
+	l.mu.Unlock()
+	if onState != nil {
+		onState(state, err)
+	}
+}
+
+// start connects for the first time; if that fails, the error is returned and no retries are made
+func (l *link) start() error {
+	l.setState(StateConnecting, nil)
+	rw, err := l.dial()
+	if err != nil {
+		l.setState(StateDown, err)
+		return err
+	}
+
+	l.mu.Lock()
+	l.rw = rw
+	l.quit = make(chan struct{})
+	l.done = make(chan struct{})
+	quit, done := l.quit, l.done
+	l.mu.Unlock()
+	l.setState(StateUp, nil)
+
+	go l.run(rw, quit, done)
+	return nil
+}
+
+// running returns true if the link has been started and not stopped since; l.mu must be held
+func (l *link) running() bool {
+	if l.quit == nil {
+		return false
+	}
+	select {
+	case <-l.quit:
+		return false
+	default:
+		return true
+	}
+}
+
+// stop disconnects for good, waiting for the receive goroutine to finish; it returns false if the link was
+// never started
+func (l *link) stop() bool {
+	l.mu.Lock()
+	if l.quit == nil {
+		l.mu.Unlock()
+		return false
+	}
+	done := l.done
+	if !l.running() {
+		// already stopped
+		l.mu.Unlock()
+		<-done
+		return true
+	}
+
+	// Stop the receive goroutine first
+	close(l.quit)
+	if l.rw != nil {
+		err := l.rw.Close()
+		if err != nil {
+			l.logger.Warn().Err(err).Msgf("Error stopping %v", l.name)
+		}
+		l.rw = nil
+	}
+	l.mu.Unlock()
+	<-done
+	return true
+}
+
+// write sends bytes, taking the link down if that fails
+func (l *link) write(b []byte) error {
+	l.mu.Lock()
+	rw := l.rw
+	running := l.running()
+	l.mu.Unlock()
+	if !running {
+		return ErrNotStarted
+	}
+	if rw == nil {
+		return ErrNotConnected
+	}
+
+	written, err := rw.Write(b)
+	if err != nil {
+		// closing it makes the read fail too, which sets off the reconnect
+		rw.Close()
+		return err
+	}
+	if written != len(b) {
+		return ErrIncompletePacketSent
+	}
+	return nil
+}
+
+// run reads packets until the link is stopped, reconnecting as needed; it closes done when it returns
+func (l *link) run(rw io.ReadWriteCloser, quit, done chan struct{}) {
+	defer close(done)
+	for {
+		l.logger.Info().Msgf("Started read loop from %v", l.name)
+		scanner := NewScanner(rw)
+		scanner.SetLogger(l.logger)
+		err := scanner.Run(l.queue(), quit)
+		select {
+		case <-quit:
+			return
+		default:
+		}
+
+		l.logger.Warn().Err(err).Msgf("Lost %v", l.name)
+		l.mu.Lock()
+		if l.rw == rw {
+			l.rw = nil
+		}
+		l.mu.Unlock()
+		rw.Close()
+		l.setState(StateDown, err)
+
+		rw = l.redial(quit)
+		if rw == nil {
+			return
+		}
+	}
+}
+
+// redial reconnects with backoff; it returns nil if the link is stopped first
+func (l *link) redial(quit chan struct{}) io.ReadWriteCloser {
+	for attempt := 0; ; attempt++ {
+		l.mu.Lock()
+		d := l.backoff.delay(attempt)
+		l.mu.Unlock()
+		select {
+		case <-quit:
+			return nil
+		case <-time.After(d):
+		}
+
+		l.setState(StateConnecting, nil)
+		rw, err := l.dial()
+		if err != nil {
+			l.logger.Debug().Err(err).Msgf("Reconnecting %v failed", l.name)
+			l.setState(StateDown, err)
+			continue
+		}
+
+		l.mu.Lock()
+		select {
+		case <-quit:
+			// stopped while we were dialing
+			l.mu.Unlock()
+			rw.Close()
+			return nil
+		default:
+		}
+		l.rw = rw
+		l.mu.Unlock()
+		l.logger.Info().Msgf("Reconnected %v", l.name)
+		l.setState(StateUp, nil)
+		return rw
+	}
+}
+
+// queue returns the receive queue
+func (l *link) queue() chan *Packet {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.receiveQueue
+}
+
+// setReceiveQueue sets the receive queue
+func (l *link) setReceiveQueue(q chan *Packet) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.receiveQueue = q
+}
+
+// setBackoff sets the Backoff used when reconnecting
+func (l *link) setBackoff(b Backoff) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.backoff = b
+}
+
+// setStateHandler sets the function told about state changes
+func (l *link) setStateHandler(h StateHandler) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onState = h
+}
@@ -0,0 +1,191 @@
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package visca
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{
+		Initial:    100 * time.Millisecond,
+		Max:        time.Second,
+		Multiplier: 2,
+	}
+	assert.Equal(t, 100*time.Millisecond, b.delay(0))
+	assert.Equal(t, 200*time.Millisecond, b.delay(1))
+	assert.Equal(t, 800*time.Millisecond, b.delay(3))
+	assert.Equal(t, time.Second, b.delay(4))
+	assert.Equal(t, time.Second, b.delay(100))
+
+	b.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := b.delay(4)
+		assert.True(t, d > 500*time.Millisecond && d <= time.Second, "%v is out of range", d)
+	}
+}
+
+func TestConnectionStateString(t *testing.T) {
+	assert.Equal(t, "connecting", StateConnecting.String())
+	assert.Equal(t, "up", StateUp.String())
+	assert.Equal(t, "down", StateDown.String())
+	assert.Equal(t, "unknown", ConnectionState(42).String())
+}
+
+func TestNetworkConnectionReconnects(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error starting test TCP server: %v", err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn)
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- c
+		}
+	}()
+
+	conn, err := NewNetworkConnection("tcp", ln.Addr().String())
+	assert.Nil(t, err)
+	conn.SetBackoff(Backoff{Initial: 10 * time.Millisecond, Max: 50 * time.Millisecond, Multiplier: 2})
+	states := make(chan ConnectionState, 16)
+	conn.SetStateHandler(func(state ConnectionState, err error) {
+		states <- state
+	})
+	q := make(chan *Packet)
+	conn.SetReceiveQueue(q)
+	assert.Nil(t, conn.Start())
+	defer conn.Stop()
+	assert.Equal(t, StateConnecting, <-states)
+	assert.Equal(t, StateUp, <-states)
+
+	camera := <-accepted
+	camera.Write([]byte{0x90, 0x41, 0xFF})
+	assert.Equal(t, Message{0x41}, (<-q).Message)
+
+	// The camera goes away; the connection comes back on its own
+	camera.Close()
+	assert.Equal(t, StateDown, <-states)
+	assert.Equal(t, StateConnecting, <-states)
+	assert.Equal(t, StateUp, <-states)
+	camera = <-accepted
+	defer camera.Close()
+
+	// And the same receive queue is still in use
+	camera.Write([]byte{0x90, 0x51, 0xFF})
+	assert.Equal(t, Message{0x51}, (<-q).Message)
+
+	pkt, _ := NewPacket(0, 1, Message{0x01, 0x04, 0x07, 0x00})
+	assert.Nil(t, conn.Send(pkt))
+	buf := make([]byte, 16)
+	n, err := camera.Read(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, pkt.Bytes(), buf[:n])
+}
+
+func TestNetworkConnectionRetriesDial(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error starting test TCP server: %v", err)
+	}
+	addr := ln.Addr().String()
+
+	conn, _ := NewNetworkConnection("tcp", addr)
+	conn.SetBackoff(Backoff{Initial: 10 * time.Millisecond, Max: 20 * time.Millisecond, Multiplier: 2})
+	states := make(chan ConnectionState, 64)
+	conn.SetStateHandler(func(state ConnectionState, err error) {
+		states <- state
+	})
+	conn.SetReceiveQueue(make(chan *Packet))
+	assert.Nil(t, conn.Start())
+	defer conn.Stop()
+	assert.Equal(t, StateConnecting, <-states)
+	assert.Equal(t, StateUp, <-states)
+
+	// Nobody's listening anymore, so redialing fails a few times
+	camera, _ := ln.Accept()
+	ln.Close()
+	camera.Close()
+	assert.Equal(t, StateDown, <-states)
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, StateConnecting, <-states)
+		assert.Equal(t, StateDown, <-states)
+	}
+	pkt, _ := NewPacket(0, 1, Message{0x01, 0x04, 0x07, 0x00})
+	assert.Equal(t, ErrNotConnected, conn.Send(pkt))
+
+	// Until it's back
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("couldn't listen on %v again: %v", addr, err)
+	}
+	defer ln.Close()
+	go ln.Accept()
+	for state := range states {
+		if state == StateUp {
+			break
+		}
+	}
+
+	// Stopped for good
+	conn.Stop()
+	assert.Equal(t, ErrNotStarted, conn.Send(pkt))
+}
+
+func TestNetworkConnectionLogger(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error starting test TCP server: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		camera, err := ln.Accept()
+		if err == nil {
+			// a runt, which the scanner logs, then a packet
+			camera.Write([]byte{0x90, 0xFF, 0x90, 0x41, 0xFF})
+		}
+	}()
+
+	var buf bytes.Buffer
+	conn, _ := NewNetworkConnection("tcp", ln.Addr().String())
+	conn.SetLogger(zerolog.New(&buf))
+	q := make(chan *Packet)
+	conn.SetReceiveQueue(q)
+	assert.Nil(t, conn.Start())
+	<-q
+
+	// Once it's stopped, nothing else is logged
+	conn.Stop()
+	assert.Contains(t, buf.String(), "Started read loop")
+	assert.Contains(t, buf.String(), "Packet is smaller than 3 bytes!")
+}
+
+func TestNetworkConnectionNotStarted(t *testing.T) {
+	conn, _ := NewNetworkConnection("tcp", "127.0.0.1:1")
+	pkt, _ := NewPacket(0, 1, Message{0x01, 0x04, 0x07, 0x00})
+	assert.Equal(t, ErrNotStarted, conn.Send(pkt))
+	conn.Stop()
+}
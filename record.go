@@ -0,0 +1,413 @@
+//  record.go - recording Connections, and replaying what they recorded
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package visca
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Error constants
+var (
+	ErrReplayMismatch   = errors.New("sent packet doesn't match the recording")
+	ErrReplayIncomplete = errors.New("recording has packets that weren't sent")
+	ErrBadRecording     = errors.New("bad recording")
+)
+
+// RecordedPacket is a packet sent or received by a RecordingConnection
+//
+// In a recording, it's a line of JSON, e.g.
+//
+//  {"offset":"1.503ms","dir":"received","packet":"90 41 FF"}
+type RecordedPacket struct {
+	Offset time.Duration // since the recording started
+	Sent   bool          // sent to the camera; otherwise received from it
+	Packet *Packet
+}
+
+// recordedPacketJSON is the JSON form of a RecordedPacket
+type recordedPacketJSON struct {
+	Offset string `json:"offset"`
+	Dir    string `json:"dir"`
+	Packet string `json:"packet"`
+}
+
+// MarshalJSON satisfies json.Marshaler
+func (r RecordedPacket) MarshalJSON() ([]byte, error) {
+	dir := "received"
+	if r.Sent {
+		dir = "sent"
+	}
+	return json.Marshal(recordedPacketJSON{
+		Offset: r.Offset.String(),
+		Dir:    dir,
+		Packet: fmt.Sprintf("% X", r.Packet.Bytes()),
+	})
+}
+
+// UnmarshalJSON satisfies json.Unmarshaler
+func (r *RecordedPacket) UnmarshalJSON(b []byte) error {
+	var j recordedPacketJSON
+	err := json.Unmarshal(b, &j)
+	if err != nil {
+		return err
+	}
+	offset, err := time.ParseDuration(j.Offset)
+	if err != nil {
+		return fmt.Errorf("%w: offset %q", ErrBadRecording, j.Offset)
+	}
+	if j.Dir != "sent" && j.Dir != "received" {
+		return fmt.Errorf("%w: dir %q", ErrBadRecording, j.Dir)
+	}
+	raw, err := hex.DecodeString(strings.Replace(j.Packet, " ", "", -1))
+	if err != nil {
+		return fmt.Errorf("%w: packet %q", ErrBadRecording, j.Packet)
+	}
+	pkt, err := PacketFromBytes(raw)
+	if err != nil {
+		return fmt.Errorf("%w: packet %q: %v", ErrBadRecording, j.Packet, err)
+	}
+	*r = RecordedPacket{
+		Offset: offset,
+		Sent:   j.Dir == "sent",
+		Packet: pkt,
+	}
+	return nil
+}
+
+// ReadRecording reads the packets written by a RecordingConnection
+func ReadRecording(r io.Reader) ([]RecordedPacket, error) {
+	var packets []RecordedPacket
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var p RecordedPacket
+		err := json.Unmarshal(scanner.Bytes(), &p)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		packets = append(packets, p)
+	}
+	return packets, scanner.Err()
+}
+
+// RecordingConnection is a Connection that writes every packet sent and received through another Connection
+// to a recording, one line of JSON each, timed from when the RecordingConnection was created
+//
+// Example
+//
+//  f, err := os.Create("session.jsonl")
+//  conn, err := visca.NewConnectionFromString("/dev/ttyUSB0")
+//  ctrl.AddCamera(1, visca.NewRecordingConnection(conn, f))
+type RecordingConnection struct {
+	conn         Connection
+	start        time.Time
+	inner        chan *Packet // the receive queue given to conn
+	sending      sync.Mutex   // held while a packet is sent and recorded
+	mu           sync.Mutex   // protects everything below, and serializes writes to w
+	w            io.Writer
+	err          error // the first error writing the recording
+	receiveQueue chan *Packet
+	quit         chan struct{}
+}
+
+// NewRecordingConnection creates a RecordingConnection that records conn to w
+func NewRecordingConnection(conn Connection, w io.Writer) *RecordingConnection {
+	r := &RecordingConnection{
+		conn:  conn,
+		start: time.Now(),
+		inner: make(chan *Packet),
+		w:     w,
+	}
+	conn.SetReceiveQueue(r.inner)
+	return r
+}
+
+// Start starts the underlying Connection
+func (r *RecordingConnection) Start() error {
+	r.mu.Lock()
+	if r.quit == nil {
+		r.quit = make(chan struct{})
+		go r.relay(r.quit)
+	}
+	r.mu.Unlock()
+	return r.conn.Start()
+}
+
+// Stop stops the underlying Connection
+func (r *RecordingConnection) Stop() {
+	r.conn.Stop()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.quit != nil {
+		close(r.quit)
+		r.quit = nil
+	}
+}
+
+// Send sends the packet, recording it if it was sent
+func (r *RecordingConnection) Send(pkt *Packet) error {
+	r.sending.Lock()
+	defer r.sending.Unlock()
+	err := r.conn.Send(pkt)
+	if err != nil {
+		return err
+	}
+	r.record(pkt, true)
+	return nil
+}
+
+// SetReceiveQueue sets where received packets go, after they're recorded
+func (r *RecordingConnection) SetReceiveQueue(q chan *Packet) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.receiveQueue = q
+}
+
+// Err returns the first error writing the recording, if there was one
+func (r *RecordingConnection) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// relay records packets received by the underlying Connection and passes them on, until quit is closed
+func (r *RecordingConnection) relay(quit chan struct{}) {
+	for {
+		select {
+		case pkt := <-r.inner:
+			// a reply can arrive before Send has recorded its request
+			r.sending.Lock()
+			r.record(pkt, false)
+			r.sending.Unlock()
+			r.mu.Lock()
+			q := r.receiveQueue
+			r.mu.Unlock()
+			if q == nil {
+				continue
+			}
+			select {
+			case q <- pkt:
+			case <-quit:
+				return
+			}
+		case <-quit:
+			return
+		}
+	}
+}
+
+// record writes a packet to the recording
+func (r *RecordingConnection) record(pkt *Packet, sent bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// timed under the lock, so the recording is in order
+	b, err := json.Marshal(RecordedPacket{
+		Offset: time.Since(r.start),
+		Sent:   sent,
+		Packet: pkt,
+	})
+	if err == nil {
+		_, err = r.w.Write(append(b, '\n'))
+	}
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+}
+
+// ReplayConnection is a Connection that plays back a recording, standing in for the camera
+//
+// Every packet sent has to match the next packet the recording says was sent; if it does, the packets that
+// were received after it are delivered, as far apart as they were when they were recorded. If it doesn't,
+// Send returns an error wrapping ErrReplayMismatch, and so does every Send after it. Verify reports whether
+// the whole recording was played.
+//
+// It can also be made with NewConnectionFromString, from replay:///path/to/recording.jsonl.
+type ReplayConnection struct {
+	packets      []RecordedPacket
+	mu           sync.Mutex // protects everything below
+	next         int        // the next packet in the recording
+	err          error      // the first mismatch
+	started      bool
+	receiveQueue chan *Packet
+	deliveries   chan delivery
+	quit         chan struct{}
+}
+
+// delivery is a received packet that's due to be delivered
+type delivery struct {
+	at  time.Time
+	pkt *Packet
+}
+
+// NewReplayConnection creates a ReplayConnection that plays back the given packets
+func NewReplayConnection(packets []RecordedPacket) *ReplayConnection {
+	return &ReplayConnection{
+		packets: packets,
+	}
+}
+
+// OpenReplay creates a ReplayConnection that plays back the recording in the named file
+func OpenReplay(name string) (*ReplayConnection, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	packets, err := ReadRecording(f)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayConnection(packets), nil
+}
+
+// replayScheme creates a ReplayConnection from a replay:// URL
+func replayScheme(u *url.URL) (Connection, error) {
+	name := u.Host + u.Path // replay://session.jsonl as well as replay:///tmp/session.jsonl
+	if name == "" {
+		return nil, ErrConnectionPathInvalid
+	}
+	return OpenReplay(name)
+}
+
+// Start starts playing; any packets received before the first one sent are delivered
+func (r *ReplayConnection) Start() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.started {
+		return nil
+	}
+	r.started = true
+	r.deliveries = make(chan delivery, len(r.packets))
+	r.quit = make(chan struct{})
+	go r.deliver(r.deliveries, r.quit)
+	r.schedule(0)
+	return nil
+}
+
+// Stop stops playing; packets that haven't been delivered yet are dropped
+func (r *ReplayConnection) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.started {
+		r.started = false
+		close(r.quit)
+	}
+}
+
+// Send checks the packet against the recording, and delivers the packets that were received after it
+func (r *ReplayConnection) Send(pkt *Packet) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.started {
+		return ErrNotStarted
+	}
+	if r.err != nil {
+		return r.err
+	}
+
+	if r.next >= len(r.packets) {
+		r.err = fmt.Errorf("%w: sent % X after the end of the recording", ErrReplayMismatch, pkt.Bytes())
+		return r.err
+	}
+	want := r.packets[r.next]
+	if !bytes.Equal(want.Packet.Bytes(), pkt.Bytes()) {
+		r.err = fmt.Errorf("%w: sent % X, but packet %d of the recording, at %v, is % X", ErrReplayMismatch,
+			pkt.Bytes(), r.next+1, want.Offset, want.Packet.Bytes())
+		return r.err
+	}
+	r.next++
+	r.schedule(want.Offset)
+	return nil
+}
+
+// SetReceiveQueue sets where the recorded replies go
+func (r *ReplayConnection) SetReceiveQueue(q chan *Packet) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.receiveQueue = q
+}
+
+// Verify returns the first mismatch, or an error wrapping ErrReplayIncomplete if some of the packets the
+// recording says were sent haven't been
+func (r *ReplayConnection) Verify() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	var missing []*Packet
+	for _, p := range r.packets[r.next:] {
+		if p.Sent {
+			missing = append(missing, p.Packet)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %d packets, starting with % X", ErrReplayIncomplete, len(missing), missing[0].Bytes())
+	}
+	return nil
+}
+
+// schedule queues the received packets up to the next sent one for delivery, timed from now as if now were
+// the given offset in the recording; r.mu must be held
+func (r *ReplayConnection) schedule(since time.Duration) {
+	now := time.Now()
+	for r.next < len(r.packets) && !r.packets[r.next].Sent {
+		p := r.packets[r.next]
+		r.deliveries <- delivery{at: now.Add(p.Offset - since), pkt: p.Packet}
+		r.next++
+	}
+}
+
+// deliver delivers packets to the receive queue when they're due, in order, until quit is closed
+func (r *ReplayConnection) deliver(deliveries chan delivery, quit chan struct{}) {
+	for {
+		select {
+		case d := <-deliveries:
+			select {
+			case <-time.After(time.Until(d.at)):
+			case <-quit:
+				return
+			}
+			r.mu.Lock()
+			q := r.receiveQueue
+			r.mu.Unlock()
+			if q == nil {
+				continue
+			}
+			select {
+			case q <- d.pkt:
+			case <-quit:
+				return
+			}
+		case <-quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package visca
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeCamera is a Connection that answers inquiries with On and completes commands in socket 1
+type fakeCamera struct {
+	mu    sync.Mutex
+	queue chan *Packet
+}
+
+func (c *fakeCamera) Start() error { return nil }
+func (c *fakeCamera) Stop()        {}
+
+func (c *fakeCamera) SetReceiveQueue(q chan *Packet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queue = q
+}
+
+func (c *fakeCamera) Send(pkt *Packet) error {
+	c.mu.Lock()
+	q := c.queue
+	c.mu.Unlock()
+	replies := []Message{{0x41}, {0x51}}
+	if pkt.Message.Type() == MsgInquiry {
+		replies = []Message{{0x50, 0x02}}
+	}
+	go func() {
+		for _, msg := range replies {
+			reply, _ := NewPacket(pkt.Destination(), 0, msg)
+			q <- reply
+		}
+	}()
+	return nil
+}
+
+// session is what the tests do with the camera
+func session(t *testing.T, conn Connection, msgs ...Message) []error {
+	ctrl := NewController()
+	ctrl.Start()
+	defer ctrl.Stop()
+	ctrl.AddCamera(1, conn)
+	defer ctrl.RemoveCamera(1)
+
+	var errs []error
+	for _, msg := range msgs {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		reply, err := ctrl.Do(ctx, 1, msg)
+		cancel()
+		if err == nil {
+			assert.Equal(t, MsgCompletion, reply.Message.Type())
+		}
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+var (
+	powerInq = Message{0x09, 0x04, 0x00}
+	zoomTele = Message{0x01, 0x04, 0x07, 0x02}
+	zoomWide = Message{0x01, 0x04, 0x07, 0x03}
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	var buf bytes.Buffer
+	rec := NewRecordingConnection(&fakeCamera{}, &buf)
+	assert.Equal(t, []error{nil, nil}, session(t, rec, powerInq, zoomTele))
+	assert.Nil(t, rec.Err())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, 5, len(lines))
+	assert.Contains(t, lines[0], `"dir":"sent","packet":"81 09 04 00 FF"`)
+	assert.Contains(t, lines[4], `"dir":"received","packet":"90 51 FF"`)
+
+	packets, err := ReadRecording(strings.NewReader(buf.String()))
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(packets))
+	for i := 1; i < len(packets); i++ {
+		assert.True(t, packets[i].Offset >= packets[i-1].Offset)
+	}
+
+	// the same session plays back
+	replay := NewReplayConnection(packets)
+	assert.Equal(t, []error{nil, nil}, session(t, replay, powerInq, zoomTele))
+	assert.Nil(t, replay.Verify())
+
+	// a different one doesn't
+	replay = NewReplayConnection(packets)
+	errs := session(t, replay, powerInq, zoomWide)
+	assert.Nil(t, errs[0])
+	assert.True(t, errors.Is(errs[1], ErrReplayMismatch), "%v", errs[1])
+	assert.True(t, errors.Is(replay.Verify(), ErrReplayMismatch))
+
+	// and neither does one that stops short
+	replay = NewReplayConnection(packets)
+	session(t, replay, powerInq)
+	assert.True(t, errors.Is(replay.Verify(), ErrReplayIncomplete))
+}
+
+func TestRecordFailedSend(t *testing.T) {
+	var buf bytes.Buffer
+	conn := &MockConnection{}
+	conn.On("SetReceiveQueue", mock.Anything).Return()
+	conn.On("Send", mock.Anything).Return(ErrNotStarted)
+	rec := NewRecordingConnection(conn, &buf)
+	rec.SetReceiveQueue(make(chan *Packet, 1))
+
+	pkt, _ := NewPacket(0, 1, zoomTele)
+	assert.Equal(t, ErrNotStarted, rec.Send(pkt))
+	assert.Equal(t, "", buf.String())
+}
+
+func TestReplayTiming(t *testing.T) {
+	packets, err := ReadRecording(strings.NewReader(`
+{"offset":"0s","dir":"received","packet":"90 38 FF"}
+{"offset":"10ms","dir":"sent","packet":"81 01 04 07 02 FF"}
+{"offset":"12ms","dir":"received","packet":"90 41 FF"}
+{"offset":"62ms","dir":"received","packet":"90 51 FF"}
+`))
+	assert.Nil(t, err)
+
+	replay := NewReplayConnection(packets)
+	q := make(chan *Packet, 4)
+	replay.SetReceiveQueue(q)
+	pkt, _ := NewPacket(0, 1, zoomTele)
+	assert.Equal(t, ErrNotStarted, replay.Send(pkt))
+
+	assert.Nil(t, replay.Start())
+	defer replay.Stop()
+	// received before anything was sent
+	assert.Equal(t, Message{0x38}, (<-q).Message)
+
+	start := time.Now()
+	assert.Nil(t, replay.Send(pkt))
+	assert.Equal(t, Message{0x41}, (<-q).Message)
+	assert.Equal(t, Message{0x51}, (<-q).Message)
+	assert.True(t, time.Since(start) >= 50*time.Millisecond, "%v", time.Since(start))
+	assert.Nil(t, replay.Verify())
+
+	// anything more is past the end
+	assert.True(t, errors.Is(replay.Send(pkt), ErrReplayMismatch))
+}
+
+func TestReadRecordingErrors(t *testing.T) {
+	for _, line := range []string{
+		`{"offset":"soon","dir":"sent","packet":"81 09 04 00 FF"}`,
+		`{"offset":"1ms","dir":"sideways","packet":"81 09 04 00 FF"}`,
+		`{"offset":"1ms","dir":"sent","packet":"zz"}`,
+		`{"offset":"1ms","dir":"sent","packet":"81 09 04 00"}`,
+		`not json`,
+	} {
+		_, err := ReadRecording(strings.NewReader(line))
+		assert.NotNil(t, err, line)
+	}
+	_, err := ReadRecording(strings.NewReader(`{"offset":"1ms","dir":"sent","packet":"81 09 04 00"}`))
+	assert.True(t, errors.Is(err, ErrBadRecording))
+}
+
+func TestReplayFromString(t *testing.T) {
+	f, err := ioutil.TempFile("", "visca-replay")
+	assert.Nil(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`{"offset":"0s","dir":"sent","packet":"81 09 04 00 FF"}` + "\n")
+	f.WriteString(`{"offset":"1ms","dir":"received","packet":"90 50 02 FF"}` + "\n")
+	f.Close()
+
+	conn, err := NewConnectionFromString("replay://" + f.Name())
+	assert.Nil(t, err)
+	assert.IsType(t, &ReplayConnection{}, conn)
+	assert.Equal(t, []error{nil}, session(t, conn, powerInq))
+	assert.Nil(t, conn.(*ReplayConnection).Verify())
+
+	_, err = NewConnectionFromString("replay:///does/not/exist.jsonl")
+	assert.NotNil(t, err)
+}
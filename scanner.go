@@ -20,6 +20,7 @@ import (
 	"bufio"
 	"io"
 
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
@@ -27,9 +28,13 @@ import (
 type Scanner struct {
 	scanner *bufio.Scanner
 	buffer  io.Reader
+	logger  zerolog.Logger
 }
 
-func splitPackets(data []byte, atEOF bool) (advance int, token []byte, err error) {
+// SplitPackets is a bufio.SplitFunc that splits a stream of bytes into packets, each ending with the Terminator
+//
+// At EOF, whatever is left is returned as the final token, even though it isn't a complete packet.
+func SplitPackets(data []byte, atEOF bool) (advance int, token []byte, err error) {
 	for i := 0; i < len(data); i++ {
 		// If we found a terminator byte, we have a packet
 		if data[i] == 0xFF {
@@ -47,46 +52,70 @@ func splitPackets(data []byte, atEOF bool) (advance int, token []byte, err error
 func NewScanner(buffer io.Reader) *Scanner {
 	scanner := bufio.NewScanner(buffer)
 	scanner.Buffer([]byte{}, 32)
-	scanner.Split(splitPackets)
+	scanner.Split(SplitPackets)
 
 	return &Scanner{
-		scanner,
-		buffer,
+		scanner: scanner,
+		buffer:  buffer,
+		logger:  log.Logger,
 	}
 }
 
-// Scan sends packets to the given channel
+// SetLogger sets where the Scanner logs bad packets; the default is zerolog's global logger
+func (s *Scanner) SetLogger(logger zerolog.Logger) {
+	s.logger = logger
+}
+
+// Scan sends packets to the given channel, and closes it when the underlying reader is exhausted
 func (s *Scanner) Scan(c chan *Packet, quit chan struct{}) {
-loop:
+	err := s.Run(c, quit)
+	if err != nil {
+		s.logger.Err(err).Msg("Scan stopped")
+	}
+	if c != nil {
+		close(c)
+	}
+}
+
+// Run sends packets to the given channel until quit is closed or reading fails
+//
+// Unlike Scan, it leaves the channel open, so it can be shared with other Scanners. It returns nil if quit
+// was closed, or the read error (io.EOF if the reader was simply exhausted).
+func (s *Scanner) Run(c chan *Packet, quit chan struct{}) error {
 	for {
 		select {
 		case <-quit:
-			break loop
+			return nil
 		default:
 			ok := s.scanner.Scan()
 			if !ok {
-				log.Err(s.scanner.Err()).Msg("Scan stopped")
-				break loop
+				err := s.scanner.Err()
+				if err == nil {
+					err = io.EOF
+				}
+				return err
 			}
 			packetBytes := s.scanner.Bytes()
 			if len(packetBytes) == 0 {
-				log.Warn().Msg("Packet is empty!!!")
+				s.logger.Warn().Msg("Packet is empty!!!")
 				continue
 			}
 			if len(packetBytes) <= 2 {
-				log.Warn().Msg("Packet is smaller than 3 bytes!")
+				s.logger.Warn().Msg("Packet is smaller than 3 bytes!")
 				// Skip runts (scanner returns a zero-length slice last in the common case)
 				continue
 			}
-			packet, err := PacketFromBytes(packetBytes)
+			// the scanner reuses its buffer, so the packet needs its own copy
+			packet, err := PacketFromBytes(append([]byte(nil), packetBytes...))
 			if err != nil {
-				log.Err(err).Msg("error creating packet from bytes")
+				s.logger.Err(err).Msg("error creating packet from bytes")
 				continue
 			}
-			c <- packet
+			select {
+			case c <- packet:
+			case <-quit:
+				return nil
+			}
 		}
 	}
-	if c != nil {
-		close(c)
-	}
 }
@@ -18,6 +18,7 @@ package visca
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -70,3 +71,37 @@ func TestScannerWithBadPackets(t *testing.T) {
 
 	assert.Equal(t, 0, len(packets), "should have no packets")
 }
+
+func TestScannerRunLeavesQueueOpen(t *testing.T) {
+	buffer := bytes.NewBuffer([]byte{
+		0x90, 0x41, 0xFF,
+		0x90, 0x51, 0xFF,
+	})
+
+	scanner := NewScanner(buffer)
+	c := make(chan *Packet, 2)
+	quit := make(chan struct{})
+	defer close(quit)
+	err := scanner.Run(c, quit)
+	assert.Equal(t, io.EOF, err)
+
+	assert.Equal(t, Message{0x41}, (<-c).Message)
+	assert.Equal(t, Message{0x51}, (<-c).Message)
+	select {
+	case _, ok := <-c:
+		assert.True(t, ok, "queue should still be open")
+	default:
+	}
+}
+
+func TestSplitPackets(t *testing.T) {
+	advance, token, err := SplitPackets([]byte{0x81, 0x09, 0x04, 0x00, 0xFF, 0x90}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, advance)
+	assert.Equal(t, []byte{0x81, 0x09, 0x04, 0x00, 0xFF}, token)
+
+	advance, token, err = SplitPackets([]byte{0x90, 0x50}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, advance)
+	assert.Nil(t, token)
+}
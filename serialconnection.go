@@ -17,96 +17,184 @@
 package visca
 
 import (
-	"github.com/rs/zerolog/log"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+
+	"github.com/rs/zerolog"
 	"go.bug.st/serial"
 )
 
-// SerialConnection implements the Iface interface for serial VISCA connections
+// ErrInvalidSerialOption is returned for serial port options that are unknown or unsupported
+var ErrInvalidSerialOption = errors.New("invalid serial option")
+
+// DefaultSerialMode is 9600 8N1, the VISCA default
+var DefaultSerialMode = serial.Mode{
+	BaudRate: 9600,
+	DataBits: 8,
+	Parity:   serial.NoParity,
+	StopBits: serial.OneStopBit,
+}
+
+// serialBaudRates are the baud rates cameras are known to support
+var serialBaudRates = []int{1200, 2400, 4800, 9600, 14400, 19200, 38400, 57600, 115200}
+
+// ParseSerialMode parses serial port options, e.g. the query of serial:///dev/ttyUSB0?baud=38400&parity=none
+//
+// The options are baud, data (bits), parity (none, odd, even, mark, or space), stop (bits: 1, 1.5, or 2),
+// and flow (only none, since the serial library has no flow control). Any that are missing are taken from
+// DefaultSerialMode.
+func ParseSerialMode(options url.Values) (*serial.Mode, error) {
+	mode := DefaultSerialMode
+	for key, values := range options {
+		value := values[len(values)-1]
+		switch key {
+		case "baud":
+			baud, err := strconv.Atoi(value)
+			if err != nil || !validBaudRate(baud) {
+				return nil, fmt.Errorf("%w: unsupported baud rate %q", ErrInvalidSerialOption, value)
+			}
+			mode.BaudRate = baud
+		case "data":
+			bits, err := strconv.Atoi(value)
+			if err != nil || bits < 5 || bits > 8 {
+				return nil, fmt.Errorf("%w: unsupported data bits %q", ErrInvalidSerialOption, value)
+			}
+			mode.DataBits = bits
+		case "parity":
+			switch value {
+			case "none":
+				mode.Parity = serial.NoParity
+			case "odd":
+				mode.Parity = serial.OddParity
+			case "even":
+				mode.Parity = serial.EvenParity
+			case "mark":
+				mode.Parity = serial.MarkParity
+			case "space":
+				mode.Parity = serial.SpaceParity
+			default:
+				return nil, fmt.Errorf("%w: unsupported parity %q", ErrInvalidSerialOption, value)
+			}
+		case "stop":
+			switch value {
+			case "1":
+				mode.StopBits = serial.OneStopBit
+			case "1.5":
+				mode.StopBits = serial.OnePointFiveStopBits
+			case "2":
+				mode.StopBits = serial.TwoStopBits
+			default:
+				return nil, fmt.Errorf("%w: unsupported stop bits %q", ErrInvalidSerialOption, value)
+			}
+		case "flow":
+			if value != "none" {
+				return nil, fmt.Errorf("%w: unsupported flow control %q", ErrInvalidSerialOption, value)
+			}
+		default:
+			return nil, fmt.Errorf("%w: unknown option %q", ErrInvalidSerialOption, key)
+		}
+	}
+	return &mode, nil
+}
+
+// validBaudRate returns true if the baud rate is one cameras are known to support
+func validBaudRate(baud int) bool {
+	for _, b := range serialBaudRates {
+		if baud == b {
+			return true
+		}
+	}
+	return false
+}
+
+// SerialConnection implements the Connection interface for serial VISCA connections
+//
+// If the port fails after it's been started, e.g. because a USB adapter was unplugged, it's reopened with
+// a Backoff until it's stopped.
 type SerialConnection struct {
-	device       string
-	port         *serial.Port
-	scanner      *Scanner
-	receiveQueue chan *Packet
-	quit         chan struct{}
+	device string
+	mode   serial.Mode
+	link   *link
 }
 
-// NewSerialConnection creates a new SerialIface
+// NewSerialConnection creates a new SerialIface with the DefaultSerialMode
 func NewSerialConnection(device string) (*SerialConnection, error) {
-	return &SerialConnection{
-		device:       device,
-		port:         nil,
-		scanner:      nil,
-		receiveQueue: nil,
-		quit:         make(chan struct{}),
-	}, nil
+	return NewSerialConnectionWithMode(device, &DefaultSerialMode)
 }
 
-// Start the interface
-func (i *SerialConnection) Start() error {
-	log.Info().Msgf("Opening serial interface %v...", i.device)
-	mode := &serial.Mode{
-		BaudRate: 9600,
-		DataBits: 8,
-		Parity:   serial.NoParity,
-		StopBits: 1,
+// NewSerialConnectionWithMode creates a new SerialIface with the given baud rate, parity, etc.
+func NewSerialConnectionWithMode(device string, mode *serial.Mode) (*SerialConnection, error) {
+	i := &SerialConnection{
+		device: device,
+		mode:   *mode,
 	}
-	port, err := serial.Open(i.device, mode)
-	if err != nil {
-		return err
-	}
-	log.Info().Msgf("Opened serial interface %v", i.device)
-	i.port = &port
+	i.link = newLink("serial interface "+device, i.Dial)
+	return i, nil
+}
 
-	i.quit = make(chan struct{})
+// Device returns the serial device the connection opens
+func (i *SerialConnection) Device() string {
+	return i.device
+}
 
-	i.scanner = NewScanner(*i.port)
+// Mode returns the baud rate, parity, etc. the port is opened with
+func (i *SerialConnection) Mode() serial.Mode {
+	return i.mode
+}
 
-	go i.scanner.Scan(i.receiveQueue, i.quit)
-	log.Info().Msgf("Started read loop from serial interface %v", i.device)
+// Dial opens the serial port, separately from the connection; the port can't be open twice at once
+func (i *SerialConnection) Dial() (io.ReadWriteCloser, error) {
+	i.link.logger.Info().Msgf("Opening serial interface %v...", i.device)
+	mode := i.mode
+	port, err := serial.Open(i.device, &mode)
+	if err != nil {
+		return nil, err
+	}
+	i.link.logger.Info().Msgf("Opened serial interface %v", i.device)
+	return port, nil
+}
 
-	return nil
+// Start the interface
+func (i *SerialConnection) Start() error {
+	return i.link.start()
 }
 
 // Stop the connection
 func (i *SerialConnection) Stop() {
-	if i.port == nil {
-		log.Warn().Msg("Never Started")
-		return
-	}
-
-	// Stop the receive goroutine first
-	close(i.quit)
-
-	// Then close the port
-	port := *i.port
-	err := port.Close()
-	if err != nil {
-		log.Warn().Err(err).Msgf("Error stopping serial interface %v", i.device)
+	if !i.link.stop() {
+		i.link.logger.Warn().Msg("Never Started")
 	}
 }
 
 // Send a packet
 func (i *SerialConnection) Send(pkt *Packet) error {
-	if i.port == nil {
-		log.Warn().Msg("not started")
-		return ErrNotStarted
-	}
-	port := *i.port
-	log.Debug().Msgf("Sending packet %v via %v", pkt, i.device)
-	written, err := port.Write(pkt.Bytes())
-	if err != nil {
-		return err
-	}
-
-	if written != len(pkt.Bytes()) {
-		return ErrIncompletePacketSent
+	i.link.logger.Debug().Msgf("Sending packet %v via %v", pkt, i.device)
+	err := i.link.write(pkt.Bytes())
+	if err == ErrNotStarted {
+		i.link.logger.Warn().Msg("not started")
 	}
-
-	return nil
+	return err
 }
 
 // SetReceiveQueue for received packets
 func (i *SerialConnection) SetReceiveQueue(q chan *Packet) {
-	i.receiveQueue = q
-	return
+	i.link.setReceiveQueue(q)
+}
+
+// SetBackoff sets how long to wait between attempts to reopen the port
+func (i *SerialConnection) SetBackoff(b Backoff) {
+	i.link.setBackoff(b)
+}
+
+// SetStateHandler sets a function to be told whenever the port goes up or down
+func (i *SerialConnection) SetStateHandler(h StateHandler) {
+	i.link.setStateHandler(h)
+}
+
+// SetLogger sets where the connection logs; it must be called before Start
+func (i *SerialConnection) SetLogger(logger zerolog.Logger) {
+	i.link.logger = logger
 }
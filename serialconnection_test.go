@@ -0,0 +1,80 @@
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package visca
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.bug.st/serial"
+)
+
+func TestParseSerialMode(t *testing.T) {
+	var tests = []struct {
+		query string
+		want  serial.Mode
+	}{
+		{"", DefaultSerialMode},
+		{"baud=38400", serial.Mode{BaudRate: 38400, DataBits: 8, Parity: serial.NoParity, StopBits: serial.OneStopBit}},
+		{"baud=115200&parity=even&stop=2&data=7&flow=none", serial.Mode{BaudRate: 115200, DataBits: 7, Parity: serial.EvenParity, StopBits: serial.TwoStopBits}},
+		{"parity=odd&stop=1.5", serial.Mode{BaudRate: 9600, DataBits: 8, Parity: serial.OddParity, StopBits: serial.OnePointFiveStopBits}},
+	}
+
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.query)
+		mode, err := ParseSerialMode(q)
+		assert.Nil(t, err, tt.query)
+		assert.Equal(t, tt.want, *mode, tt.query)
+	}
+}
+
+func TestParseSerialModeErrors(t *testing.T) {
+	for _, query := range []string{
+		"baud=12345",
+		"baud=fast",
+		"data=9",
+		"parity=sometimes",
+		"stop=3",
+		"flow=rtscts",
+		"speed=9600",
+	} {
+		q, _ := url.ParseQuery(query)
+		_, err := ParseSerialMode(q)
+		assert.True(t, errors.Is(err, ErrInvalidSerialOption), query)
+	}
+}
+
+func TestSerialConnectionFromString(t *testing.T) {
+	conn, err := NewConnectionFromString("serial:///dev/ttyUSB0?baud=38400&parity=none&stop=1")
+	assert.Nil(t, err)
+	assert.IsType(t, &SerialConnection{}, conn)
+	sc := conn.(*SerialConnection)
+	assert.Equal(t, "/dev/ttyUSB0", sc.device)
+	assert.Equal(t, 38400, sc.mode.BaudRate)
+
+	conn, err = NewConnectionFromString("serial://COM3")
+	assert.Nil(t, err)
+	assert.Equal(t, "COM3", conn.(*SerialConnection).device)
+	assert.Equal(t, DefaultSerialMode, conn.(*SerialConnection).mode)
+
+	_, err = NewConnectionFromString("serial:///dev/ttyUSB0?baud=1234")
+	assert.True(t, errors.Is(err, ErrInvalidSerialOption))
+
+	_, err = NewConnectionFromString("serial://")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,97 @@
+//  axis.go - simulated motion of a single axis
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package simulator
+
+import (
+	"math"
+	"time"
+)
+
+// axis is one moving part of the camera: pan, tilt, zoom or focus
+//
+// The position isn't updated on a timer; it's worked out from the velocity and the time since the last
+// change whenever it's needed.
+type axis struct {
+	pos      float64   // position as of since
+	min, max float64   // limits
+	velocity float64   // units per second; 0 when still
+	target   float64   // where to stop, if targeted
+	targeted bool      // moving to a target rather than until the limit
+	since    time.Time // when pos was last worked out
+}
+
+// newAxis creates an axis at rest at pos
+func newAxis(pos, min, max int) *axis {
+	return &axis{
+		pos:   float64(pos),
+		min:   float64(min),
+		max:   float64(max),
+		since: time.Now(),
+	}
+}
+
+// at returns the position at the given time
+func (a *axis) at(now time.Time) float64 {
+	p := a.pos + a.velocity*now.Sub(a.since).Seconds()
+	if a.targeted && ((a.velocity > 0 && p > a.target) || (a.velocity < 0 && p < a.target)) {
+		p = a.target
+	}
+	return a.clamp(p)
+}
+
+// clamp limits p to the axis' range
+func (a *axis) clamp(p float64) float64 {
+	return math.Max(a.min, math.Min(a.max, p))
+}
+
+// settle works out the position as of now, so the velocity can be changed
+func (a *axis) settle(now time.Time) {
+	a.pos = a.at(now)
+	a.since = now
+}
+
+// drive moves the axis at the given velocity until it's stopped or hits a limit
+func (a *axis) drive(now time.Time, velocity float64) {
+	a.settle(now)
+	a.velocity = velocity
+	a.targeted = false
+}
+
+// stop stops the axis where it is
+func (a *axis) stop(now time.Time) {
+	a.drive(now, 0)
+}
+
+// moveTo moves the axis to target at the given speed, and returns how long it will take to get there
+func (a *axis) moveTo(now time.Time, target float64, speed float64) time.Duration {
+	a.settle(now)
+	a.target = a.clamp(target)
+	a.targeted = true
+	distance := a.target - a.pos
+	if distance == 0 || speed <= 0 {
+		a.pos = a.target
+		a.velocity = 0
+		return 0
+	}
+	a.velocity = math.Copysign(speed, distance)
+	return time.Duration(math.Abs(distance) / speed * float64(time.Second))
+}
+
+// position returns the position at the given time, rounded to whole units
+func (a *axis) position(now time.Time) int {
+	return int(math.Round(a.at(now)))
+}
@@ -0,0 +1,577 @@
+//  camera.go - a simulated VISCA camera
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package simulator is a simulated VISCA camera, for testing controllers without any hardware
+//
+// The Camera keeps its pan, tilt, zoom, focus and power state, moves at the commanded speeds, and answers
+// commands, inquiries and cancels the way a camera on the end of a VISCA connection would, with ACKs,
+// Completions and Errors in the right sockets.
+//
+// Example
+//
+//  cam := simulator.NewCamera()
+//  ln, err := net.Listen("tcp", "127.0.0.1:0")
+//  go cam.ServeListener(ln)
+//  conn, err := visca.NewConnectionFromString(ln.Addr().String())
+package simulator
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/josh23french/visca"
+	"github.com/rs/zerolog/log"
+)
+
+// Config describes the simulated camera's range of movement and how fast it moves
+//
+// Positions are in the units used on the wire; speeds are in units per second for each step of the speed
+// given in a command.
+type Config struct {
+	PanMin, PanMax     int // pan limits
+	TiltMin, TiltMax   int // tilt limits
+	ZoomMax            int // zoom position at full telephoto; full wide is 0
+	FocusMin, FocusMax int // focus limits; far is FocusMin
+	PanNibbles         int // nibbles of pan position in commands and replies; 4 or 5
+	PanRate            float64
+	TiltRate           float64
+	ZoomRate           float64
+	FocusRate          float64
+}
+
+// DefaultConfig is modeled on a Sony SRG-300: ±170° of pan and -30° to 90° of tilt, at 235.9 units per
+// degree, with a 5-nibble pan position
+var DefaultConfig = Config{
+	PanMin:     -40103,
+	PanMax:     40103,
+	TiltMin:    -7077,
+	TiltMax:    21231,
+	ZoomMax:    0x4000,
+	FocusMin:   0x1000,
+	FocusMax:   0xC000,
+	PanNibbles: 5,
+	PanRate:    1000, // 0x18 is about 100°/s
+	TiltRate:   1000,
+	ZoomRate:   2048, // 7 goes from wide to tele in a second
+	FocusRate:  6144,
+}
+
+// Speed limits for commands
+const (
+	MaxPanSpeed   = visca.MaxPanSpeed
+	MaxTiltSpeed  = visca.MaxTiltSpeed
+	MaxZoomSpeed  = 0x07
+	MaxFocusSpeed = 0x07
+)
+
+// standardSpeed is the zoom/focus speed used when the command doesn't give one
+const standardSpeed = 0x02
+
+// State is a snapshot of the simulated camera
+type State struct {
+	Address   int // address on the chain, set by AddressSet; 1 until then
+	Power     bool
+	AutoFocus bool
+	Pan       int
+	Tilt      int
+	Zoom      int
+	Focus     int
+}
+
+// Camera is a simulated VISCA camera
+type Camera struct {
+	config    Config
+	mu        sync.Mutex // protects everything below
+	address   int
+	power     bool
+	autoFocus bool
+	pan       *axis
+	tilt      *axis
+	zoom      *axis
+	focus     *axis
+	sockets   [3]*job // commands executing in each socket; 0 is not used
+	faults    Faults
+	rand      *rand.Rand
+	delayed   chan delivery // replies waiting out the Latency; created when first needed
+	closed    chan struct{} // closed by Close to stop sending delayed replies
+}
+
+// job is a command that's executing in a socket
+type job struct {
+	socket uint8
+	reply  func(visca.Message) // where the Completion goes
+	axes   []*axis             // what it's moving, to stop if it's canceled
+	timer  *time.Timer         // nil if it only completes when it's canceled
+}
+
+// NewCamera creates a powered-on simulated camera with the DefaultConfig
+func NewCamera() *Camera {
+	return NewCameraWithConfig(DefaultConfig)
+}
+
+// NewCameraWithConfig creates a powered-on simulated camera with the given Config
+func NewCameraWithConfig(config Config) *Camera {
+	return &Camera{
+		config:    config,
+		address:   1,
+		power:     true,
+		autoFocus: true,
+		pan:       newAxis(0, config.PanMin, config.PanMax),
+		tilt:      newAxis(0, config.TiltMin, config.TiltMax),
+		zoom:      newAxis(0, 0, config.ZoomMax),
+		focus:     newAxis(config.FocusMin, config.FocusMin, config.FocusMax),
+		rand:      rand.New(rand.NewSource(0)),
+	}
+}
+
+// State returns the camera's current state
+func (c *Camera) State() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	return State{
+		Address:   c.address,
+		Power:     c.power,
+		AutoFocus: c.autoFocus,
+		Pan:       c.pan.position(now),
+		Tilt:      c.tilt.position(now),
+		Zoom:      c.zoom.position(now),
+		Focus:     c.focus.position(now),
+	}
+}
+
+// handle answers a packet; send is used for the replies, and must not block
+func (c *Camera) handle(pkt *visca.Packet, send func(*visca.Packet)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if pkt.IsBroadcast() {
+		c.broadcast(pkt, send)
+		return
+	}
+	if pkt.Destination() != c.address {
+		log.Debug().Msgf("simulator: ignoring packet for %v", pkt.Destination())
+		return
+	}
+
+	from, to := c.address, pkt.Source()
+	reply := func(msg visca.Message) {
+		p, err := visca.NewPacket(from, to, msg)
+		if err != nil {
+			log.Err(err).Msg("simulator: error creating reply")
+			return
+		}
+		c.emit(p, send)
+	}
+
+	msg := pkt.Message
+	switch msg.Type() {
+	case visca.MsgCommand:
+		c.command(msg, reply)
+	case visca.MsgInquiry:
+		c.inquiry(msg, reply)
+	case visca.MsgCancel:
+		c.cancel(msg.Socket(), reply)
+	default:
+		reply(errorMessage(0, visca.SyntaxError))
+	}
+}
+
+// broadcast handles the broadcasts used to set up the chain, passing them on as the next camera would
+func (c *Camera) broadcast(pkt *visca.Packet, send func(*visca.Packet)) {
+	msg := pkt.Message
+	switch {
+	case msg.Type() == visca.MsgAddressSet && len(msg) == 2:
+		c.address = int(msg[1])
+		next, err := visca.NewAddressSetMessage(msg[1] + 1)
+		if err != nil {
+			// we're the 8th camera; nothing valid to pass on
+			log.Warn().Err(err).Msg("simulator: too many cameras")
+			return
+		}
+		pkt, _ = visca.NewBroadcastPacket(next)
+	case isIFClear(msg):
+		c.clear()
+	default:
+		log.Debug().Msgf("simulator: ignoring %v broadcast", msg.Type())
+		return
+	}
+	c.emit(pkt, send)
+}
+
+// isIFClear returns true if msg is an IF_Clear command
+func isIFClear(msg visca.Message) bool {
+	return len(msg) == 3 && msg[0] == 0x01 && msg[1] == 0x00 && msg[2] == 0x01
+}
+
+// clear empties the command buffers, silently
+func (c *Camera) clear() {
+	now := time.Now()
+	for s, j := range c.sockets {
+		if j == nil {
+			continue
+		}
+		c.finish(j, now)
+		c.sockets[s] = nil
+	}
+}
+
+// command executes a command, ACKing it in a free socket first
+func (c *Camera) command(msg visca.Message, reply func(visca.Message)) {
+	if isIFClear(msg) {
+		// not ACKed; completes straight away
+		c.clear()
+		reply(visca.Message{0x50})
+		return
+	}
+
+	run := c.parseCommand(msg)
+	if run == nil {
+		reply(errorMessage(0, visca.SyntaxError))
+		return
+	}
+	socket := c.freeSocket()
+	if socket == 0 {
+		reply(errorMessage(0, visca.CommandBufferFull))
+		return
+	}
+	reply(visca.Message{0x40 + socket})
+
+	if c.faults.PowerOffNotExecutable && !c.power && !isPower(msg) {
+		reply(errorMessage(socket, visca.CommandNotExecutable))
+		return
+	}
+	now := time.Now()
+	wait, axes, verr := run(now)
+	if verr != 0 {
+		reply(errorMessage(socket, verr))
+		return
+	}
+	c.supersede(axes)
+	if wait <= 0 && !c.faults.HoldSockets {
+		reply(visca.Message{0x50 + socket})
+		return
+	}
+
+	j := &job{
+		socket: socket,
+		reply:  reply,
+		axes:   axes,
+	}
+	c.sockets[socket] = j
+	if c.faults.HoldSockets {
+		// it's done, but the camera doesn't say so
+		return
+	}
+	j.timer = time.AfterFunc(wait, func() {
+		c.complete(j)
+	})
+}
+
+// isPower returns true if msg is a Power command
+func isPower(msg visca.Message) bool {
+	return len(msg) == 4 && msg[1] == 0x04 && msg[2] == 0x00
+}
+
+// freeSocket returns a socket that's not executing a command, or 0 if they're both busy
+func (c *Camera) freeSocket() uint8 {
+	for s := uint8(1); s < uint8(len(c.sockets)); s++ {
+		if c.sockets[s] == nil {
+			return s
+		}
+	}
+	return 0
+}
+
+// supersede completes the commands that were moving any of the given axes; the new command took over
+func (c *Camera) supersede(axes []*axis) {
+	for s, j := range c.sockets {
+		if j == nil || !sharesAxis(j.axes, axes) {
+			continue
+		}
+		if j.timer != nil {
+			j.timer.Stop()
+		}
+		c.sockets[s] = nil
+		j.reply(visca.Message{0x50 + j.socket})
+	}
+}
+
+// sharesAxis returns true if a and b have an axis in common
+func sharesAxis(a, b []*axis) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// complete finishes a command once its movement is done
+func (c *Camera) complete(j *job) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sockets[j.socket] != j {
+		// canceled or superseded in the meantime
+		return
+	}
+	c.sockets[j.socket] = nil
+	j.reply(visca.Message{0x50 + j.socket})
+}
+
+// finish stops a job and whatever it was moving
+func (c *Camera) finish(j *job, now time.Time) {
+	if j.timer != nil {
+		j.timer.Stop()
+	}
+	for _, a := range j.axes {
+		a.stop(now)
+	}
+}
+
+// cancel cancels the command in the given socket
+func (c *Camera) cancel(socket uint8, reply func(visca.Message)) {
+	j := c.sockets[socket]
+	if j == nil {
+		reply(errorMessage(socket, visca.NoSocket))
+		return
+	}
+	c.finish(j, time.Now())
+	c.sockets[socket] = nil
+	// the error takes the place of the Completion, so it goes wherever that would have
+	j.reply(errorMessage(socket, visca.CommandCanceled))
+}
+
+// errorMessage creates an Error message for the given socket
+func errorMessage(socket uint8, err visca.Error) visca.Message {
+	return visca.Message{0x60 + socket, byte(err)}
+}
+
+// action carries out a parsed command, returning how long until it completes and what it moves
+type action func(now time.Time) (time.Duration, []*axis, visca.Error)
+
+// parseCommand returns the action for a command, or nil if it's not one the camera understands
+func (c *Camera) parseCommand(msg visca.Message) action {
+	if len(msg) < 3 {
+		return nil
+	}
+	switch visca.CategoryCode(msg[1]) {
+	case visca.CatCamera1:
+		return c.parseCamera(msg[2:])
+	case visca.CatPanTilter:
+		return c.parsePanTilt(msg[2:])
+	}
+	return nil
+}
+
+// parseCamera parses the camera (04) category of commands
+func (c *Camera) parseCamera(b []byte) action {
+	switch {
+	case len(b) == 2 && b[0] == 0x00 && (b[1] == 0x02 || b[1] == 0x03):
+		// Power
+		on := b[1] == 0x02
+		return func(now time.Time) (time.Duration, []*axis, visca.Error) {
+			c.power = on
+			return 0, nil, 0
+		}
+	case len(b) == 2 && b[0] == 0x07:
+		// Zoom
+		return c.parseDrive(b[1], c.zoom, c.config.ZoomRate, 1)
+	case len(b) == 5 && b[0] == 0x47:
+		// Zoom Direct
+		return c.parseDirect(b[1:], c.zoom, c.config.ZoomRate*(MaxZoomSpeed+1))
+	case len(b) == 2 && b[0] == 0x08:
+		// Focus; far is towards FocusMin
+		return c.parseDrive(b[1], c.focus, c.config.FocusRate, -1)
+	case len(b) == 5 && b[0] == 0x48:
+		// Focus Direct
+		return c.parseDirect(b[1:], c.focus, c.config.FocusRate*(MaxFocusSpeed+1))
+	case len(b) == 2 && b[0] == 0x38 && (b[1] == 0x02 || b[1] == 0x03 || b[1] == 0x10):
+		// Focus Auto/Manual
+		mode := b[1]
+		return func(now time.Time) (time.Duration, []*axis, visca.Error) {
+			switch mode {
+			case 0x02:
+				c.autoFocus = true
+			case 0x03:
+				c.autoFocus = false
+			default:
+				c.autoFocus = !c.autoFocus
+			}
+			return 0, nil, 0
+		}
+	}
+	return nil
+}
+
+// parseDrive parses a zoom or focus Stop (00), Standard (02/03) or Variable (2p/3p) drive
+//
+// direction is the sign of the movement for 02/2p.
+func (c *Camera) parseDrive(b byte, a *axis, rate float64, direction float64) action {
+	var speed float64
+	switch {
+	case b == 0x00:
+		speed = 0
+	case b == 0x02:
+		speed = rate * (standardSpeed + 1)
+	case b == 0x03:
+		speed = -rate * (standardSpeed + 1)
+	case b&0xF0 == 0x20 && b&0x0F <= MaxZoomSpeed:
+		speed = rate * float64(b&0x0F+1)
+	case b&0xF0 == 0x30 && b&0x0F <= MaxZoomSpeed:
+		speed = -rate * float64(b&0x0F+1)
+	default:
+		return nil
+	}
+	return func(now time.Time) (time.Duration, []*axis, visca.Error) {
+		a.drive(now, speed*direction)
+		return 0, []*axis{a}, 0
+	}
+}
+
+// parseDirect parses a zoom or focus Direct position
+func (c *Camera) parseDirect(b []byte, a *axis, speed float64) action {
+	pos, ok := visca.DecodeNibbles(b, false)
+	if !ok {
+		return nil
+	}
+	return func(now time.Time) (time.Duration, []*axis, visca.Error) {
+		return a.moveTo(now, float64(pos), speed), []*axis{a}, 0
+	}
+}
+
+// parsePanTilt parses the pan-tilter (06) category of commands
+func (c *Camera) parsePanTilt(b []byte) action {
+	n := c.config.PanNibbles
+	switch {
+	case len(b) == 5 && b[0] == 0x01:
+		// Pan-tiltDrive: 01 VV WW XX YY
+		panSpeed, tiltSpeed, ok := c.parseSpeeds(b[1], b[2])
+		if !ok {
+			return nil
+		}
+		pan, ok1 := driveDirection(b[3])
+		tilt, ok2 := driveDirection(b[4])
+		if !ok1 || !ok2 {
+			return nil
+		}
+		// pan increases to the right, so its sign is flipped
+		return func(now time.Time) (time.Duration, []*axis, visca.Error) {
+			c.pan.drive(now, -pan*panSpeed)
+			c.tilt.drive(now, tilt*tiltSpeed)
+			return 0, []*axis{c.pan, c.tilt}, 0
+		}
+	case len(b) == 3+n+4 && (b[0] == 0x02 || b[0] == 0x03):
+		// AbsolutePosition/RelativePosition: 02 VV WW pan tilt
+		panSpeed, tiltSpeed, ok := c.parseSpeeds(b[1], b[2])
+		if !ok {
+			return nil
+		}
+		pan, ok1 := visca.DecodeNibbles(b[3:3+n], true)
+		tilt, ok2 := visca.DecodeNibbles(b[3+n:], true)
+		if !ok1 || !ok2 {
+			return nil
+		}
+		relative := b[0] == 0x03
+		return func(now time.Time) (time.Duration, []*axis, visca.Error) {
+			panTarget, tiltTarget := float64(pan), float64(tilt)
+			if relative {
+				panTarget += c.pan.at(now)
+				tiltTarget += c.tilt.at(now)
+			}
+			return c.movePanTilt(now, panTarget, tiltTarget, panSpeed, tiltSpeed), []*axis{c.pan, c.tilt}, 0
+		}
+	case len(b) == 1 && (b[0] == 0x04 || b[0] == 0x05):
+		// Home/Reset
+		return func(now time.Time) (time.Duration, []*axis, visca.Error) {
+			panSpeed := c.config.PanRate * MaxPanSpeed
+			tiltSpeed := c.config.TiltRate * MaxTiltSpeed
+			return c.movePanTilt(now, 0, 0, panSpeed, tiltSpeed), []*axis{c.pan, c.tilt}, 0
+		}
+	}
+	return nil
+}
+
+// movePanTilt moves pan and tilt to their targets, returning how long until both are there
+func (c *Camera) movePanTilt(now time.Time, pan, tilt, panSpeed, tiltSpeed float64) time.Duration {
+	panWait := c.pan.moveTo(now, pan, panSpeed)
+	tiltWait := c.tilt.moveTo(now, tilt, tiltSpeed)
+	if panWait > tiltWait {
+		return panWait
+	}
+	return tiltWait
+}
+
+// parseSpeeds checks the pan and tilt speeds of a command and turns them into units per second
+func (c *Camera) parseSpeeds(pan, tilt byte) (float64, float64, bool) {
+	if pan < 0x01 || pan > MaxPanSpeed || tilt < 0x01 || tilt > MaxTiltSpeed {
+		return 0, 0, false
+	}
+	return c.config.PanRate * float64(pan), c.config.TiltRate * float64(tilt), true
+}
+
+// driveDirection turns a Pan-tiltDrive direction into a sign: 01 (left/up) is 1, 02 (right/down) is -1, and
+// 03 (stop) is 0
+func driveDirection(b byte) (float64, bool) {
+	switch b {
+	case 0x01:
+		return 1, true
+	case 0x02:
+		return -1, true
+	case 0x03:
+		return 0, true
+	}
+	return 0, false
+}
+
+// inquiry answers an inquiry
+func (c *Camera) inquiry(msg visca.Message, reply func(visca.Message)) {
+	now := time.Now()
+	b := []byte(msg[1:])
+	switch {
+	case len(b) == 2 && b[0] == 0x04 && b[1] == 0x00:
+		// PowerInq
+		reply(visca.Message{0x50, onOff(c.power)})
+	case len(b) == 2 && b[0] == 0x04 && b[1] == 0x47:
+		// ZoomPosInq
+		reply(append(visca.Message{0x50}, visca.EncodeNibbles(c.zoom.position(now), 4)...))
+	case len(b) == 2 && b[0] == 0x04 && b[1] == 0x48:
+		// FocusPosInq
+		reply(append(visca.Message{0x50}, visca.EncodeNibbles(c.focus.position(now), 4)...))
+	case len(b) == 2 && b[0] == 0x04 && b[1] == 0x38:
+		// FocusModeInq
+		reply(visca.Message{0x50, onOff(c.autoFocus)})
+	case len(b) == 2 && b[0] == 0x06 && b[1] == 0x12:
+		// Pan-tiltPosInq
+		r := visca.Message{0x50}
+		r = append(r, visca.EncodeNibbles(c.pan.position(now), c.config.PanNibbles)...)
+		r = append(r, visca.EncodeNibbles(c.tilt.position(now), 4)...)
+		reply(r)
+	default:
+		reply(errorMessage(0, visca.SyntaxError))
+	}
+}
+
+// onOff returns the VISCA byte for on (02) or off (03)
+func onOff(on bool) byte {
+	if on {
+		return 0x02
+	}
+	return 0x03
+}
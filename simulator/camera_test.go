@@ -0,0 +1,277 @@
+package simulator
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/josh23french/visca"
+	"github.com/stretchr/testify/assert"
+)
+
+// replies collects what a Camera sends
+type replies chan *visca.Packet
+
+func (r replies) send(pkt *visca.Packet) {
+	r <- pkt
+}
+
+// packet has the camera handle a packet made of the given bytes
+func (r replies) packet(t *testing.T, cam *Camera, b ...byte) {
+	pkt, err := visca.PacketFromBytes(b)
+	assert.Nil(t, err)
+	cam.handle(pkt, r.send)
+}
+
+// next returns the next reply's message
+func (r replies) next(t *testing.T) visca.Message {
+	select {
+	case pkt := <-r:
+		return pkt.Message
+	case <-time.After(2 * time.Second):
+		t.Fatal("no reply")
+		return nil
+	}
+}
+
+func (r replies) expectNothing(t *testing.T) {
+	select {
+	case pkt := <-r:
+		t.Errorf("unexpected reply % X", pkt.Bytes())
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestInquiries(t *testing.T) {
+	cam := NewCamera()
+	r := make(replies, 16)
+
+	r.packet(t, cam, 0x81, 0x09, 0x04, 0x00, 0xFF)
+	assert.Equal(t, visca.Message{0x50, 0x02}, r.next(t))
+
+	r.packet(t, cam, 0x81, 0x09, 0x06, 0x12, 0xFF)
+	assert.Equal(t, visca.Message{0x50, 0, 0, 0, 0, 0, 0, 0, 0, 0}, r.next(t))
+
+	r.packet(t, cam, 0x81, 0x09, 0x04, 0x47, 0xFF)
+	assert.Equal(t, visca.Message{0x50, 0, 0, 0, 0}, r.next(t))
+
+	r.packet(t, cam, 0x81, 0x09, 0x04, 0x48, 0xFF)
+	assert.Equal(t, visca.Message{0x50, 0x01, 0, 0, 0}, r.next(t))
+
+	r.packet(t, cam, 0x81, 0x09, 0x04, 0x38, 0xFF)
+	assert.Equal(t, visca.Message{0x50, 0x02}, r.next(t))
+
+	r.packet(t, cam, 0x81, 0x09, 0x7E, 0x7E, 0xFF)
+	assert.Equal(t, visca.Message{0x60, 0x02}, r.next(t))
+}
+
+func TestCommands(t *testing.T) {
+	cam := NewCamera()
+	r := make(replies, 16)
+
+	// Power off
+	r.packet(t, cam, 0x81, 0x01, 0x04, 0x00, 0x03, 0xFF)
+	assert.Equal(t, visca.Message{0x41}, r.next(t))
+	assert.Equal(t, visca.Message{0x51}, r.next(t))
+	assert.False(t, cam.State().Power)
+
+	// Focus Manual
+	r.packet(t, cam, 0x81, 0x01, 0x04, 0x38, 0x03, 0xFF)
+	assert.Equal(t, visca.Message{0x41}, r.next(t))
+	assert.Equal(t, visca.Message{0x51}, r.next(t))
+	assert.False(t, cam.State().AutoFocus)
+
+	// not a command
+	r.packet(t, cam, 0x81, 0x01, 0x04, 0x7F, 0x7F, 0xFF)
+	assert.Equal(t, visca.Message{0x60, 0x02}, r.next(t))
+
+	// speed out of range
+	r.packet(t, cam, 0x81, 0x01, 0x06, 0x01, 0x19, 0x01, 0x03, 0x03, 0xFF)
+	assert.Equal(t, visca.Message{0x60, 0x02}, r.next(t))
+
+	// not for us
+	r.packet(t, cam, 0x82, 0x09, 0x04, 0x00, 0xFF)
+	r.expectNothing(t)
+}
+
+func TestMovement(t *testing.T) {
+	config := DefaultConfig
+	config.ZoomRate *= 10
+	config.FocusRate *= 10
+	cam := NewCameraWithConfig(config)
+	r := make(replies, 16)
+
+	// Pan-tiltDrive up-right completes straight away, and keeps going
+	r.packet(t, cam, 0x81, 0x01, 0x06, 0x01, 0x18, 0x17, 0x02, 0x01, 0xFF)
+	assert.Equal(t, visca.Message{0x41}, r.next(t))
+	assert.Equal(t, visca.Message{0x51}, r.next(t))
+	time.Sleep(20 * time.Millisecond)
+	state := cam.State()
+	assert.True(t, state.Pan > 0, "pans right")
+	assert.True(t, state.Tilt > 0, "tilts up")
+
+	// Stop
+	r.packet(t, cam, 0x81, 0x01, 0x06, 0x01, 0x18, 0x17, 0x03, 0x03, 0xFF)
+	assert.Equal(t, visca.Message{0x41}, r.next(t))
+	assert.Equal(t, visca.Message{0x51}, r.next(t))
+	state = cam.State()
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, state.Pan, cam.State().Pan)
+
+	// AbsolutePosition completes once it gets there
+	r.packet(t, cam, 0x81, 0x01, 0x06, 0x02, 0x18, 0x17, 0x00, 0x00, 0x03, 0x0E, 0x08, 0x0F, 0x0F, 0x0F, 0x00, 0xFF)
+	assert.Equal(t, visca.Message{0x41}, r.next(t))
+	assert.Equal(t, visca.Message{0x51}, r.next(t))
+	state = cam.State()
+	assert.Equal(t, 1000, state.Pan)
+	assert.Equal(t, -16, state.Tilt)
+
+	// RelativePosition
+	r.packet(t, cam, 0x81, 0x01, 0x06, 0x03, 0x18, 0x17, 0x0F, 0x0F, 0x0C, 0x01, 0x08, 0x00, 0x00, 0x01, 0x00, 0xFF)
+	assert.Equal(t, visca.Message{0x41}, r.next(t))
+	assert.Equal(t, visca.Message{0x51}, r.next(t))
+	state = cam.State()
+	assert.Equal(t, 0, state.Pan)
+	assert.Equal(t, 0, state.Tilt)
+
+	// Zoom Direct stops at the limit
+	r.packet(t, cam, 0x81, 0x01, 0x04, 0x47, 0x0F, 0x0F, 0x0F, 0x0F, 0xFF)
+	assert.Equal(t, visca.Message{0x41}, r.next(t))
+	assert.Equal(t, visca.Message{0x51}, r.next(t))
+	assert.Equal(t, 0x4000, cam.State().Zoom)
+
+	// Zoom Wide (Variable)
+	r.packet(t, cam, 0x81, 0x01, 0x04, 0x07, 0x37, 0xFF)
+	assert.Equal(t, visca.Message{0x41}, r.next(t))
+	assert.Equal(t, visca.Message{0x51}, r.next(t))
+	time.Sleep(20 * time.Millisecond)
+	assert.True(t, cam.State().Zoom < 0x4000, "zooms out")
+
+	// Focus Far goes towards the minimum
+	r.packet(t, cam, 0x81, 0x01, 0x04, 0x48, 0x08, 0x00, 0x00, 0x00, 0xFF)
+	assert.Equal(t, visca.Message{0x41}, r.next(t))
+	assert.Equal(t, visca.Message{0x51}, r.next(t))
+	r.packet(t, cam, 0x81, 0x01, 0x04, 0x08, 0x02, 0xFF)
+	assert.Equal(t, visca.Message{0x41}, r.next(t))
+	assert.Equal(t, visca.Message{0x51}, r.next(t))
+	time.Sleep(20 * time.Millisecond)
+	assert.True(t, cam.State().Focus < 0x8000, "focuses far")
+}
+
+func TestSocketsAndCancel(t *testing.T) {
+	config := DefaultConfig
+	config.ZoomRate = 1 // takes forever
+	config.FocusRate = 1
+	cam := NewCameraWithConfig(config)
+	r := make(replies, 16)
+
+	// Zoom Direct to tele, in socket 1
+	r.packet(t, cam, 0x81, 0x01, 0x04, 0x47, 0x04, 0x00, 0x00, 0x00, 0xFF)
+	assert.Equal(t, visca.Message{0x41}, r.next(t))
+
+	// Focus Direct, in socket 2
+	r.packet(t, cam, 0x81, 0x01, 0x04, 0x48, 0x08, 0x00, 0x00, 0x00, 0xFF)
+	assert.Equal(t, visca.Message{0x42}, r.next(t))
+
+	// no room for a third
+	r.packet(t, cam, 0x81, 0x01, 0x04, 0x00, 0x02, 0xFF)
+	assert.Equal(t, visca.Message{0x60, 0x03}, r.next(t))
+
+	// cancel socket 1
+	r.packet(t, cam, 0x81, 0x21, 0xFF)
+	assert.Equal(t, visca.Message{0x61, 0x04}, r.next(t))
+	r.packet(t, cam, 0x81, 0x21, 0xFF)
+	assert.Equal(t, visca.Message{0x61, 0x05}, r.next(t))
+	zoom := cam.State().Zoom
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, zoom, cam.State().Zoom, "zoom stopped")
+
+	// stopping the focus finishes the command in socket 2
+	r.packet(t, cam, 0x81, 0x01, 0x04, 0x08, 0x00, 0xFF)
+	assert.Equal(t, visca.Message{0x41}, r.next(t))
+	assert.Equal(t, visca.Message{0x52}, r.next(t))
+	assert.Equal(t, visca.Message{0x51}, r.next(t))
+
+	// IF_Clear clears without a word, then completes
+	r.packet(t, cam, 0x81, 0x01, 0x04, 0x47, 0x04, 0x00, 0x00, 0x00, 0xFF)
+	assert.Equal(t, visca.Message{0x41}, r.next(t))
+	r.packet(t, cam, 0x81, 0x01, 0x00, 0x01, 0xFF)
+	assert.Equal(t, visca.Message{0x50}, r.next(t))
+	r.packet(t, cam, 0x81, 0x21, 0xFF)
+	assert.Equal(t, visca.Message{0x61, 0x05}, r.next(t))
+}
+
+func TestBroadcasts(t *testing.T) {
+	cam := NewCamera()
+	r := make(replies, 16)
+
+	r.packet(t, cam, 0x88, 0x30, 0x03, 0xFF)
+	pkt := <-r
+	assert.Equal(t, []byte{0x88, 0x30, 0x04, 0xFF}, pkt.Bytes())
+	assert.Equal(t, 3, cam.State().Address)
+
+	r.packet(t, cam, 0x88, 0x01, 0x00, 0x01, 0xFF)
+	pkt = <-r
+	assert.Equal(t, []byte{0x88, 0x01, 0x00, 0x01, 0xFF}, pkt.Bytes())
+
+	// now it's camera 3
+	r.packet(t, cam, 0x81, 0x09, 0x04, 0x00, 0xFF)
+	r.expectNothing(t)
+	r.packet(t, cam, 0x83, 0x09, 0x04, 0x00, 0xFF)
+	pkt = <-r
+	assert.Equal(t, []byte{0xB0, 0x50, 0x02, 0xFF}, pkt.Bytes())
+}
+
+func TestControllerOverTCP(t *testing.T) {
+	cam := NewCamera()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+	go cam.ServeListener(ln)
+
+	conn, err := visca.NewConnectionFromString(ln.Addr().String())
+	assert.Nil(t, err)
+	ctrl := visca.NewController()
+	ctrl.Start()
+	defer ctrl.Stop()
+	ctrl.AddCamera(1, conn)
+	defer ctrl.RemoveCamera(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	reply, err := ctrl.Do(ctx, 1, visca.Message{0x01, 0x06, 0x02, 0x18, 0x17, 0x00, 0x00, 0x00, 0x06, 0x04, 0x00, 0x00, 0x00, 0x0A})
+	assert.Nil(t, err)
+	assert.Equal(t, uint8(1), reply.Socket)
+
+	reply, err = ctrl.Do(ctx, 1, visca.Message{0x09, 0x06, 0x12})
+	assert.Nil(t, err)
+	assert.Equal(t, visca.Message{0x50, 0x00, 0x00, 0x00, 0x06, 0x04, 0x00, 0x00, 0x00, 0x0A}, reply.Message)
+
+	_, err = ctrl.Do(ctx, 1, visca.Message{0x01, 0x06, 0x7F})
+	assert.Equal(t, visca.SyntaxError, err.(*visca.ReplyError).Code)
+}
+
+func TestControllerOverUDP(t *testing.T) {
+	cam := NewCamera()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer pc.Close()
+	go cam.ServePacketConn(pc)
+
+	conn, err := visca.NewConnectionFromString("udp://" + pc.LocalAddr().String())
+	assert.Nil(t, err)
+	ctrl := visca.NewController()
+	ctrl.Start()
+	defer ctrl.Stop()
+	ctrl.AddCamera(1, conn)
+	defer ctrl.RemoveCamera(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	reply, err := ctrl.Do(ctx, 1, visca.Message{0x09, 0x04, 0x00})
+	assert.Nil(t, err)
+	assert.Equal(t, visca.Message{0x50, 0x02}, reply.Message)
+}
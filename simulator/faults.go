@@ -0,0 +1,148 @@
+//  faults.go - making a simulated camera misbehave
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package simulator
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/josh23french/visca"
+	"github.com/rs/zerolog/log"
+)
+
+// Faults are the ways a simulated Camera can be told to misbehave
+//
+// Probabilities are from 0 (never) to 1 (always). The random choices come from Seed, so a test that sends
+// the same packets gets the same faults every time.
+type Faults struct {
+	HoldSockets           bool          // commands keep their socket until canceled or cleared, so the buffer fills up
+	PowerOffNotExecutable bool          // commands other than Power reply CommandNotExecutable while the power is off
+	DropACK               float64       // probability an ACK isn't sent
+	DropCompletion        float64       // probability a Completion isn't sent; the socket is freed anyway
+	Corrupt               float64       // probability one byte of a reply is changed
+	Latency               time.Duration // delay before every reply
+	Jitter                time.Duration // up to this much more delay, at random; replies still arrive in order
+	Seed                  int64         // seed for the random choices
+}
+
+// delayQueueSize is how many delayed replies can be waiting to be sent
+const delayQueueSize = 64
+
+// delivery is a reply waiting out its latency
+type delivery struct {
+	at   time.Time
+	pkt  *visca.Packet
+	send func(*visca.Packet)
+}
+
+// SetFaults sets the ways the camera misbehaves, replacing any set before
+func (c *Camera) SetFaults(faults Faults) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.faults = faults
+	c.rand = rand.New(rand.NewSource(faults.Seed))
+}
+
+// emit sends a reply, unless the faults say it's lost, corrupted or late
+func (c *Camera) emit(pkt *visca.Packet, send func(*visca.Packet)) {
+	f := c.faults
+	switch pkt.Message.Type() {
+	case visca.MsgACK:
+		if c.chance(f.DropACK) {
+			log.Debug().Msg("simulator: dropping ACK")
+			return
+		}
+	case visca.MsgCompletion:
+		if c.chance(f.DropCompletion) {
+			log.Debug().Msg("simulator: dropping Completion")
+			return
+		}
+	}
+	if c.chance(f.Corrupt) {
+		pkt = c.corrupt(pkt)
+	}
+
+	delay := f.Latency
+	if f.Jitter > 0 {
+		delay += time.Duration(c.rand.Int63n(int64(f.Jitter) + 1))
+	}
+	if delay <= 0 {
+		send(pkt)
+		return
+	}
+
+	if c.delayed == nil {
+		c.delayed = make(chan delivery, delayQueueSize)
+		c.closed = make(chan struct{})
+		go deliver(c.delayed, c.closed)
+	}
+	select {
+	case c.delayed <- delivery{time.Now().Add(delay), pkt, send}:
+	default:
+		log.Warn().Msg("simulator: delay queue full; dropping reply")
+	}
+}
+
+// deliver sends delayed replies in the order they were made, each once its time has come, until quit is
+// closed
+func deliver(q chan delivery, quit chan struct{}) {
+	for {
+		select {
+		case d := <-q:
+			timer := time.NewTimer(time.Until(d.at))
+			select {
+			case <-timer.C:
+				d.send(d.pkt)
+			case <-quit:
+				timer.Stop()
+				return
+			}
+		case <-quit:
+			return
+		}
+	}
+}
+
+// Close drops the replies still waiting out their Latency and stops the goroutine that sends them; a camera
+// with Latency set should be closed once it's no longer needed
+func (c *Camera) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.delayed != nil {
+		close(c.closed)
+		c.delayed = nil
+		c.closed = nil
+	}
+}
+
+// chance returns true with the given probability
+func (c *Camera) chance(p float64) bool {
+	return p > 0 && c.rand.Float64() < p
+}
+
+// corrupt returns a copy of pkt with one byte of its message changed
+func (c *Camera) corrupt(pkt *visca.Packet) *visca.Packet {
+	msg := append(visca.Message(nil), pkt.Message...)
+	i := c.rand.Intn(len(msg))
+	msg[i] ^= byte(1 + c.rand.Intn(0xFE))
+	corrupted, err := visca.NewPacket(pkt.Source(), pkt.Destination(), msg)
+	if err != nil {
+		return pkt
+	}
+	log.Debug().Msgf("simulator: corrupted % X into % X", pkt.Bytes(), corrupted.Bytes())
+	return corrupted
+}
@@ -0,0 +1,157 @@
+package simulator
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/josh23french/visca"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPowerOffNotExecutable(t *testing.T) {
+	cam := NewCamera()
+	cam.SetFaults(Faults{PowerOffNotExecutable: true})
+	r := make(replies, 16)
+
+	r.packet(t, cam, 0x81, 0x01, 0x04, 0x00, 0x03, 0xFF)
+	assert.Equal(t, visca.Message{0x41}, r.next(t))
+	assert.Equal(t, visca.Message{0x51}, r.next(t))
+
+	// Zoom Tele
+	r.packet(t, cam, 0x81, 0x01, 0x04, 0x07, 0x02, 0xFF)
+	assert.Equal(t, visca.Message{0x41}, r.next(t))
+	assert.Equal(t, visca.Message{0x61, 0x41}, r.next(t))
+	assert.Equal(t, 0, cam.State().Zoom)
+
+	// Power On still works
+	r.packet(t, cam, 0x81, 0x01, 0x04, 0x00, 0x02, 0xFF)
+	assert.Equal(t, visca.Message{0x41}, r.next(t))
+	assert.Equal(t, visca.Message{0x51}, r.next(t))
+}
+
+func TestHoldSockets(t *testing.T) {
+	cam := NewCamera()
+	cam.SetFaults(Faults{HoldSockets: true})
+	r := make(replies, 16)
+
+	r.packet(t, cam, 0x81, 0x01, 0x04, 0x00, 0x02, 0xFF)
+	assert.Equal(t, visca.Message{0x41}, r.next(t))
+	r.packet(t, cam, 0x81, 0x01, 0x04, 0x38, 0x02, 0xFF)
+	assert.Equal(t, visca.Message{0x42}, r.next(t))
+	r.packet(t, cam, 0x81, 0x01, 0x04, 0x38, 0x03, 0xFF)
+	assert.Equal(t, visca.Message{0x60, 0x03}, r.next(t))
+
+	r.packet(t, cam, 0x81, 0x22, 0xFF)
+	assert.Equal(t, visca.Message{0x62, 0x04}, r.next(t))
+	r.packet(t, cam, 0x81, 0x01, 0x04, 0x38, 0x03, 0xFF)
+	assert.Equal(t, visca.Message{0x42}, r.next(t))
+	r.expectNothing(t)
+}
+
+func TestDroppedReplies(t *testing.T) {
+	cam := NewCamera()
+	r := make(replies, 16)
+
+	cam.SetFaults(Faults{DropACK: 1})
+	r.packet(t, cam, 0x81, 0x01, 0x04, 0x00, 0x02, 0xFF)
+	assert.Equal(t, visca.Message{0x51}, r.next(t))
+
+	cam.SetFaults(Faults{DropCompletion: 1})
+	r.packet(t, cam, 0x81, 0x01, 0x04, 0x00, 0x02, 0xFF)
+	assert.Equal(t, visca.Message{0x41}, r.next(t))
+	r.expectNothing(t)
+
+	// the socket was freed all the same
+	r.packet(t, cam, 0x81, 0x21, 0xFF)
+	assert.Equal(t, visca.Message{0x61, 0x05}, r.next(t))
+
+	// inquiries are answered with Completions too
+	r.packet(t, cam, 0x81, 0x09, 0x04, 0x00, 0xFF)
+	r.expectNothing(t)
+}
+
+func TestLatency(t *testing.T) {
+	cam := NewCamera()
+	defer cam.Close()
+	cam.SetFaults(Faults{Latency: 50 * time.Millisecond, Jitter: 20 * time.Millisecond})
+	r := make(replies, 16)
+
+	start := time.Now()
+	r.packet(t, cam, 0x81, 0x01, 0x04, 0x00, 0x02, 0xFF)
+	r.packet(t, cam, 0x81, 0x09, 0x04, 0x00, 0xFF)
+	assert.Equal(t, visca.Message{0x41}, r.next(t))
+	assert.True(t, time.Since(start) >= 50*time.Millisecond)
+	assert.Equal(t, visca.Message{0x51}, r.next(t))
+	assert.Equal(t, visca.Message{0x50, 0x02}, r.next(t))
+
+	// replies still waiting when it's closed are dropped
+	r.packet(t, cam, 0x81, 0x09, 0x04, 0x00, 0xFF)
+	cam.Close()
+	r.expectNothing(t)
+}
+
+func TestCorrupt(t *testing.T) {
+	cam := NewCamera()
+	r := make(replies, 16)
+
+	cam.SetFaults(Faults{Corrupt: 1, Seed: 42})
+	r.packet(t, cam, 0x81, 0x09, 0x04, 0x00, 0xFF)
+	first := r.next(t)
+	assert.NotEqual(t, visca.Message{0x50, 0x02}, first)
+
+	// same seed, same corruption
+	cam.SetFaults(Faults{Corrupt: 1, Seed: 42})
+	r.packet(t, cam, 0x81, 0x09, 0x04, 0x00, 0xFF)
+	assert.Equal(t, first, r.next(t))
+}
+
+func TestControllerWithFaults(t *testing.T) {
+	cam := NewCamera()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+	go cam.ServeListener(ln)
+
+	conn, err := visca.NewConnectionFromString(ln.Addr().String())
+	assert.Nil(t, err)
+	ctrl := visca.NewController()
+	ctrl.Start()
+	defer ctrl.Stop()
+	ctrl.AddCamera(1, conn)
+	defer ctrl.RemoveCamera(1)
+	ctx := context.Background()
+
+	cam.SetFaults(Faults{PowerOffNotExecutable: true})
+	_, err = ctrl.Do(ctx, 1, visca.Message{0x01, 0x04, 0x00, 0x03})
+	assert.Nil(t, err)
+	_, err = ctrl.Do(ctx, 1, visca.Message{0x01, 0x04, 0x07, 0x02})
+	assert.Equal(t, visca.CommandNotExecutable, err.(*visca.ReplyError).Code)
+
+	cam.SetFaults(Faults{HoldSockets: true})
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := ctrl.Do(ctx, 1, visca.Message{0x01, 0x04, 0x00, 0x02})
+			errs <- err
+		}()
+		time.Sleep(20 * time.Millisecond) // wait for the ACK
+	}
+	_, err = ctrl.Do(ctx, 1, visca.Message{0x01, 0x04, 0x00, 0x02})
+	assert.Equal(t, visca.CommandBufferFull, err.(*visca.ReplyError).Code)
+
+	assert.Nil(t, ctrl.Cancel(1, 1))
+	assert.Nil(t, ctrl.Cancel(1, 2))
+	for i := 0; i < 2; i++ {
+		err = <-errs
+		assert.Equal(t, visca.CommandCanceled, err.(*visca.ReplyError).Code)
+	}
+
+	// a lost Completion leaves the caller waiting until it gives up
+	cam.SetFaults(Faults{DropCompletion: 1})
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = ctrl.Do(timeout, 1, visca.Message{0x09, 0x04, 0x00})
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
@@ -0,0 +1,197 @@
+//  server.go - serving a simulated camera over streams and datagrams
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package simulator
+
+import (
+	"io"
+	"net"
+	"sync"
+
+	"github.com/josh23french/visca"
+	"github.com/rs/zerolog/log"
+)
+
+// sessionQueueSize is how many replies can be waiting to be written to a session
+const sessionQueueSize = 64
+
+// session writes replies to one controller
+//
+// The camera replies while holding its lock, so replies are queued and written from a goroutine of their
+// own instead of blocking on a slow reader.
+type session struct {
+	w      io.Writer
+	mu     sync.Mutex // protects out and closed
+	out    chan []byte
+	closed bool
+}
+
+// newSession creates a session writing to w and starts writing
+func newSession(w io.Writer) *session {
+	s := &session{
+		w:   w,
+		out: make(chan []byte, sessionQueueSize),
+	}
+	go s.run()
+	return s
+}
+
+// run writes queued replies until the session is closed
+func (s *session) run() {
+	for b := range s.out {
+		_, err := s.w.Write(b)
+		if err != nil {
+			log.Debug().Err(err).Msg("simulator: error writing reply")
+		}
+	}
+}
+
+// send queues a reply; it's dropped if the session is closed or too far behind
+func (s *session) send(pkt *visca.Packet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.out <- pkt.Bytes():
+	default:
+		log.Warn().Msg("simulator: reply queue full; dropping reply")
+	}
+}
+
+// close stops the session once the queued replies are written
+func (s *session) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.out)
+	}
+}
+
+// Serve answers the VISCA packets read from rw until reading fails, e.g. because rw was closed
+//
+// It returns the read error, which is io.EOF if rw was simply exhausted.
+func (c *Camera) Serve(rw io.ReadWriter) error {
+	s := newSession(rw)
+	defer s.close()
+
+	packets := make(chan *visca.Packet)
+	quit := make(chan struct{})
+	defer close(quit)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- visca.NewScanner(rw).Run(packets, quit)
+	}()
+
+	for {
+		select {
+		case pkt := <-packets:
+			c.handle(pkt, s.send)
+		case err := <-errs:
+			return err
+		}
+	}
+}
+
+// ServeListener serves each connection accepted from ln, until ln is closed
+func (c *Camera) ServeListener(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			err := c.Serve(conn)
+			log.Debug().Err(err).Msgf("simulator: done serving %v", conn.RemoteAddr())
+		}()
+	}
+}
+
+// ServePacketConn answers the VISCA packets in each datagram received on pc, replying to its sender, until
+// pc is closed
+func (c *Camera) ServePacketConn(pc net.PacketConn) error {
+	sessions := make(map[string]*session)
+	defer func() {
+		for _, s := range sessions {
+			s.close()
+		}
+	}()
+
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		s, ok := sessions[addr.String()]
+		if !ok {
+			s = newSession(&datagramWriter{pc, addr})
+			sessions[addr.String()] = s
+		}
+
+		datagram := buf[:n]
+		for len(datagram) > 0 {
+			end := len(datagram)
+			for i, b := range datagram {
+				if b == visca.Terminator {
+					end = i + 1
+					break
+				}
+			}
+			pkt, err := visca.PacketFromBytes(append([]byte(nil), datagram[:end]...))
+			datagram = datagram[end:]
+			if err != nil {
+				log.Debug().Err(err).Msgf("simulator: bad packet from %v", addr)
+				continue
+			}
+			c.handle(pkt, s.send)
+		}
+	}
+}
+
+// ListenAndServe listens on the given network ("tcp", "udp", "unix"...) and address and serves the camera
+func (c *Camera) ListenAndServe(network, address string) error {
+	switch network {
+	case "udp", "udp4", "udp6", "unixgram":
+		pc, err := net.ListenPacket(network, address)
+		if err != nil {
+			return err
+		}
+		defer pc.Close()
+		return c.ServePacketConn(pc)
+	default:
+		ln, err := net.Listen(network, address)
+		if err != nil {
+			return err
+		}
+		defer ln.Close()
+		return c.ServeListener(ln)
+	}
+}
+
+// datagramWriter writes each reply as a datagram to addr
+type datagramWriter struct {
+	pc   net.PacketConn
+	addr net.Addr
+}
+
+// Write sends b as one datagram
+func (w *datagramWriter) Write(b []byte) (int, error) {
+	return w.pc.WriteTo(b, w.addr)
+}
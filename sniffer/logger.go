@@ -0,0 +1,126 @@
+//  logger.go - logs the packets the proxy sees
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sniffer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/josh23french/visca"
+	"github.com/josh23french/visca/dissect"
+)
+
+// TimeFormat is how times are written in the text log
+const TimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// maxUnanswered is how many requests to a camera are remembered while waiting for their replies
+const maxUnanswered = 16
+
+// Logger writes a line for each Record, as text or JSON, decoded with the dissector
+//
+// It keeps track of the requests sent to each camera, so the replies to inquiries can be decoded too. It's safe
+// to use its Log method as the handler for several proxies at once.
+type Logger struct {
+	w          io.Writer
+	json       bool
+	mu         sync.Mutex
+	unanswered map[int]*visca.Pairer // requests without replies, by camera address
+}
+
+// NewLogger creates a Logger that writes to w, as JSON if asJSON is true
+func NewLogger(w io.Writer, asJSON bool) *Logger {
+	return &Logger{
+		w:          w,
+		json:       asJSON,
+		unanswered: make(map[int]*visca.Pairer),
+	}
+}
+
+// entry is a line of the JSON log
+type entry struct {
+	Time      time.Time            `json:"time"`
+	Direction string               `json:"direction"`
+	Bytes     string               `json:"bytes"`
+	Packet    *dissect.Description `json:"packet,omitempty"`
+	Error     string               `json:"error,omitempty"`
+}
+
+// Log writes a Record
+func (l *Logger) Log(r Record) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := entry{
+		Time:      r.Time,
+		Direction: r.Direction.String(),
+		Bytes:     fmt.Sprintf("% X", r.Bytes),
+	}
+	if r.Packet == nil {
+		e.Error = visca.ErrInvalidVISCAPacket.Error()
+	} else {
+		e.Packet = dissect.Packet(r.Packet, l.track(r))
+	}
+
+	if l.json {
+		json.NewEncoder(l.w).Encode(e)
+		return
+	}
+	desc := e.Error
+	if e.Packet != nil {
+		desc = fmt.Sprintf("%d->%d %v", r.Packet.Source(), r.Packet.Destination(), e.Packet)
+	}
+	fmt.Fprintf(l.w, "%v  %-18v  %-47v  %v\n", e.Time.Format(TimeFormat), e.Direction, e.Bytes, desc)
+}
+
+// track remembers requests going to cameras, and returns the request a reply answers, if it's known
+//
+// The requests to each camera are paired with its replies by a visca.Pairer. l.mu must be held.
+func (l *Logger) track(r Record) visca.Message {
+	pkt := r.Packet
+	msg := pkt.Message
+	if r.Direction == ToCamera {
+		switch {
+		case pkt.IsBroadcast() && msg.Type() == visca.MsgCommand:
+			// IF_Clear, which empties every camera's buffers
+			l.unanswered = make(map[int]*visca.Pairer)
+		case msg.Type() == visca.MsgCommand || msg.Type() == visca.MsgInquiry:
+			p := l.pairer(pkt.Destination())
+			p.Add(msg, msg)
+			if p.Len() > maxUnanswered {
+				// its reply must have been missed
+				p.Shift()
+			}
+		}
+		return nil
+	}
+
+	req, _ := l.pairer(pkt.Source()).Pair(msg).(visca.Message)
+	return req
+}
+
+// pairer returns the Pairer for the given camera; l.mu must be held
+func (l *Logger) pairer(camera int) *visca.Pairer {
+	p, ok := l.unanswered[camera]
+	if !ok {
+		p = visca.NewPairer()
+		l.unanswered[camera] = p
+	}
+	return p
+}
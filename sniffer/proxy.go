@@ -0,0 +1,137 @@
+//  proxy.go - forwards VISCA traffic between a controller and a camera, watching it go by
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package sniffer is a man-in-the-middle for VISCA: it sits between a controller, like a hardware joystick or
+// vendor software, and a camera, forwarding bytes both ways untouched and reporting each packet it sees
+//
+//  err := sniffer.Proxy(controller, camera, sniffer.NewLogger(os.Stdout, false).Log)
+package sniffer
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/josh23french/visca"
+)
+
+// maxPacket is how many bytes can go by without a terminator before they're reported as garbage
+const maxPacket = 16
+
+// Direction is which way a packet was going
+type Direction int
+
+// Direction constants
+const (
+	ToCamera     Direction = iota // from the controller to the camera
+	ToController                  // from the camera to the controller
+)
+
+// String returns "controller->camera" or "camera->controller"
+func (d Direction) String() string {
+	if d == ToCamera {
+		return "controller->camera"
+	}
+	return "camera->controller"
+}
+
+// Record is a packet that went through the proxy
+type Record struct {
+	Time      time.Time     // when the end of the packet arrived
+	Direction Direction     // which way it was going
+	Bytes     []byte        // the packet, exactly as it was sent
+	Packet    *visca.Packet // the decoded packet, or nil if Bytes isn't a valid packet
+}
+
+// Proxy forwards bytes between the controller and the camera until either side is closed, calling handle with
+// each packet
+//
+// Bytes are forwarded as soon as they're read and handled, so the proxy doesn't change the timing any more than
+// it has to. handle is called from one goroutine per direction, in order for each direction, and must not block for long.
+// When one side is closed or fails, both are closed; Proxy returns the error that stopped it, or nil if it was
+// a clean close.
+func Proxy(controller, camera io.ReadWriteCloser, handle func(Record)) error {
+	errs := make(chan error, 2)
+	go func() {
+		errs <- forward(camera, controller, ToCamera, handle)
+	}()
+	go func() {
+		errs <- forward(controller, camera, ToController, handle)
+	}()
+
+	err := <-errs
+	controller.Close()
+	camera.Close()
+	<-errs
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// forward copies from src to dst, splitting what goes by into packets for handle
+func forward(dst io.Writer, src io.Reader, dir Direction, handle func(Record)) error {
+	buf := make([]byte, 512)
+	var pending []byte
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			// handle the packets before passing them on, so a request is always handled before its reply
+			pending = split(append(pending, buf[:n]...), time.Now(), dir, handle)
+			_, werr := dst.Write(buf[:n])
+			if werr != nil {
+				return werr
+			}
+		}
+		if err != nil {
+			if len(pending) > 0 {
+				handle(record(pending, time.Now(), dir))
+			}
+			return err
+		}
+	}
+}
+
+// split passes each whole packet in data to handle, returning what's left over
+func split(data []byte, now time.Time, dir Direction, handle func(Record)) []byte {
+	for {
+		advance, token, _ := visca.SplitPackets(data, false)
+		if advance == 0 {
+			break
+		}
+		handle(record(token, now, dir))
+		data = data[advance:]
+	}
+	if len(data) >= maxPacket {
+		handle(record(data, now, dir))
+		return nil
+	}
+	return data
+}
+
+// record makes a Record, decoding the packet if it's valid
+func record(b []byte, now time.Time, dir Direction) Record {
+	r := Record{
+		Time:      now,
+		Direction: dir,
+		Bytes:     append([]byte(nil), b...),
+	}
+	pkt, err := visca.PacketFromBytes(r.Bytes)
+	if err == nil {
+		r.Packet = pkt
+	}
+	return r
+}
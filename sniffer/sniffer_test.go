@@ -0,0 +1,138 @@
+package sniffer
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/josh23french/visca"
+	"github.com/josh23french/visca/simulator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxy(t *testing.T) {
+	controller, proxyController := net.Pipe()
+	proxyCamera, camera := net.Pipe()
+	go simulator.NewCamera().Serve(camera)
+
+	records := make(chan Record, 16)
+	done := make(chan error)
+	go func() {
+		done <- Proxy(proxyController, proxyCamera, func(r Record) { records <- r })
+	}()
+
+	// a packet split across writes still comes out whole, and the bytes get through untouched
+	_, err := controller.Write([]byte{0x81, 0x09, 0x04})
+	assert.Nil(t, err)
+	_, err = controller.Write([]byte{0x00, 0xFF})
+	assert.Nil(t, err)
+
+	reply := make([]byte, 4)
+	_, err = io.ReadFull(controller, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x90, 0x50, 0x02, 0xFF}, reply)
+
+	r := <-records
+	assert.Equal(t, ToCamera, r.Direction)
+	assert.Equal(t, []byte{0x81, 0x09, 0x04, 0x00, 0xFF}, r.Bytes)
+	assert.Equal(t, visca.Message{0x09, 0x04, 0x00}, r.Packet.Message)
+	r = <-records
+	assert.Equal(t, ToController, r.Direction)
+	assert.Equal(t, 1, r.Packet.Source())
+	assert.WithinDuration(t, time.Now(), r.Time, time.Second)
+
+	// garbage is passed on, and reported without a Packet
+	_, err = controller.Write([]byte{0x01, 0x02, 0xFF})
+	assert.Nil(t, err)
+	r = <-records
+	assert.Equal(t, []byte{0x01, 0x02, 0xFF}, r.Bytes)
+	assert.Nil(t, r.Packet)
+
+	controller.Close()
+	select {
+	case err = <-done:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Proxy didn't return when the controller went away")
+	}
+}
+
+func TestSplit(t *testing.T) {
+	var got []Record
+	handle := func(r Record) { got = append(got, r) }
+	now := time.Now()
+
+	rest := split([]byte{0x81, 0x01, 0x04, 0x07, 0x02, 0xFF, 0x90, 0x41}, now, ToCamera, handle)
+	assert.Equal(t, []byte{0x90, 0x41}, rest)
+	assert.Equal(t, 1, len(got))
+
+	// too long without a terminator
+	rest = split(bytes.Repeat([]byte{0x01}, maxPacket), now, ToCamera, handle)
+	assert.Nil(t, rest)
+	assert.Equal(t, 2, len(got))
+	assert.Nil(t, got[1].Packet)
+}
+
+func packet(b ...byte) *visca.Packet {
+	pkt, _ := visca.PacketFromBytes(b)
+	return pkt
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(&buf, false)
+	at := time.Date(2021, 3, 4, 5, 6, 7, 890000000, time.UTC)
+	for _, b := range [][]byte{
+		{0x81, 0x01, 0x04, 0x07, 0x25, 0xFF},
+		{0x81, 0x09, 0x04, 0x47, 0xFF},
+		{0x90, 0x41, 0xFF},
+		{0x90, 0x50, 0x01, 0x02, 0x03, 0x04, 0xFF},
+		{0x90, 0x51, 0xFF},
+		{0x01, 0xFF},
+	} {
+		dir := ToCamera
+		if b[0] == 0x90 {
+			dir = ToController
+		}
+		l.Log(Record{Time: at, Direction: dir, Bytes: b, Packet: packet(b...)})
+	}
+
+	assert.Equal(t, ""+
+		"2021-03-04T05:06:07.890000Z  controller->camera  81 01 04 07 25 FF                                0->1 CAM_Zoom Tele(Variable) speed=5\n"+
+		"2021-03-04T05:06:07.890000Z  controller->camera  81 09 04 47 FF                                   0->1 CAM_ZoomPosInq\n"+
+		"2021-03-04T05:06:07.890000Z  camera->controller  90 41 FF                                         1->0 ACK socket=1\n"+
+		"2021-03-04T05:06:07.890000Z  camera->controller  90 50 01 02 03 04 FF                             1->0 Completion CAM_ZoomPosInq socket=0 position=0x1234\n"+
+		"2021-03-04T05:06:07.890000Z  camera->controller  90 51 FF                                         1->0 Completion socket=1\n"+
+		"2021-03-04T05:06:07.890000Z  controller->camera  01 FF                                            Invalid VISCA packet\n",
+		buf.String())
+}
+
+func TestLoggerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(&buf, true)
+	b := []byte{0x81, 0x09, 0x04, 0x00, 0xFF}
+	l.Log(Record{Time: time.Now(), Direction: ToCamera, Bytes: b, Packet: packet(b...)})
+	b = []byte{0x90, 0x50, 0x02, 0xFF}
+	l.Log(Record{Time: time.Now(), Direction: ToController, Bytes: b, Packet: packet(b...)})
+
+	dec := json.NewDecoder(&buf)
+	var e struct {
+		Direction string
+		Bytes     string
+		Packet    struct {
+			Name    string
+			Request string
+			Fields  []struct{ Name, Text string }
+		}
+	}
+	assert.Nil(t, dec.Decode(&e))
+	assert.Equal(t, "controller->camera", e.Direction)
+	assert.Equal(t, "CAM_PowerInq", e.Packet.Name)
+	assert.Nil(t, dec.Decode(&e))
+	assert.Equal(t, "90 50 02 FF", e.Bytes)
+	assert.Equal(t, "CAM_PowerInq", e.Packet.Request)
+	assert.Equal(t, "On", e.Packet.Fields[1].Text)
+}
@@ -0,0 +1,156 @@
+//  softlimits.go - keeping cameras out of walls and ceilings
+// 	Copyright (C) 2021  Joshua French
+//
+// 	This program is free software: you can redistribute it and/or modify
+// 	it under the terms of the GNU Lesser General Public License as published
+// 	by the Free Software Foundation, either version 3 of the License, or
+// 	(at your option) any later version.
+//
+// 	This program is distributed in the hope that it will be useful,
+// 	but WITHOUT ANY WARRANTY; without even the implied warranty of
+// 	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// 	GNU Lesser General Public License for more details.
+//
+// 	You should have received a copy of the GNU Lesser General Public License
+// 	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package visca
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Error constants
+var (
+	ErrOutsideSoftLimits = errors.New("move is outside the soft limits")
+	ErrInvalidSoftLimits = errors.New("invalid soft limits")
+)
+
+// softLimitTimeout is how long a relative move waits to find out where the camera is, to check it against
+// the soft limits
+const softLimitTimeout = 2 * time.Second
+
+// SoftLimits are a box, in degrees from home, that the Controller keeps a camera's absolute and relative
+// moves inside
+//
+// They're checked before anything is sent, so they work with any camera, but they can't stop a Pan-tiltDrive
+// (PanTilt and friends), which moves until it's told to stop; use Pan-tiltLimitSet on the camera for that.
+type SoftLimits struct {
+	MinPan  float64 // negative is left
+	MaxPan  float64
+	MinTilt float64 // negative is down
+	MaxTilt float64
+	Clamp   bool // move as far as the box allows, instead of returning ErrOutsideSoftLimits
+}
+
+// valid returns true if the box isn't empty
+func (l SoftLimits) valid() bool {
+	for _, v := range []float64{l.MinPan, l.MaxPan, l.MinTilt, l.MaxTilt} {
+		if math.IsNaN(v) {
+			return false
+		}
+	}
+	return l.MinPan <= l.MaxPan && l.MinTilt <= l.MaxTilt
+}
+
+// SetSoftLimits sets the soft limits of the given camera; nil removes them
+func (c *Controller) SetSoftLimits(num int, limits *SoftLimits) error {
+	if num > 7 || num <= 0 {
+		return ErrInvalidCameraNumber
+	}
+	if limits != nil && !limits.valid() {
+		return ErrInvalidSoftLimits
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if limits == nil {
+		c.limits[num] = nil
+		return nil
+	}
+	l := *limits
+	c.limits[num] = &l
+	return nil
+}
+
+// SoftLimits returns the soft limits of the given camera, or nil if it doesn't have any
+func (c *Controller) SoftLimits(num int) *SoftLimits {
+	if num > 7 || num <= 0 {
+		return nil
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.limits[num] == nil {
+		return nil
+	}
+	l := *c.limits[num]
+	return &l
+}
+
+// limitAbsolute applies the camera's soft limits to a move to the given position, in the model's units,
+// returning the position to move to instead
+func (c *Controller) limitAbsolute(camera int, model *Model, pan, tilt int) (int, int, error) {
+	limits := c.SoftLimits(camera)
+	if limits == nil {
+		return pan, tilt, nil
+	}
+	return limits.apply(model, pan, tilt)
+}
+
+// limitRelative applies the camera's soft limits to a move by the given offsets, in the model's units,
+// returning the offsets to move by instead
+//
+// With soft limits, the camera is asked where it is first.
+func (c *Controller) limitRelative(camera int, model *Model, pan, tilt int) (int, int, error) {
+	limits := c.SoftLimits(camera)
+	if limits == nil {
+		return pan, tilt, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), softLimitTimeout)
+	defer cancel()
+	reply, err := c.Do(ctx, camera, Message{0x09, 0x06, 0x12})
+	if err != nil {
+		return 0, 0, err
+	}
+	fromPan, fromTilt, err := model.decodeUnits(reply.Message[1:])
+	if err != nil {
+		return 0, 0, err
+	}
+	toPan, toTilt, err := limits.apply(model, fromPan+pan, fromTilt+tilt)
+	if err != nil {
+		return 0, 0, err
+	}
+	return toPan - fromPan, toTilt - fromTilt, nil
+}
+
+// apply checks a position, in the model's units, against the box, clamping it if l.Clamp is set
+//
+// The box is converted to units so positions the camera reports on its edges count as inside it.
+func (l *SoftLimits) apply(model *Model, pan, tilt int) (int, int, error) {
+	minPan := int(math.Round(l.MinPan * model.PanScale))
+	maxPan := int(math.Round(l.MaxPan * model.PanScale))
+	minTilt := int(math.Round(l.MinTilt * model.TiltScale))
+	maxTilt := int(math.Round(l.MaxTilt * model.TiltScale))
+	if pan >= minPan && pan <= maxPan && tilt >= minTilt && tilt <= maxTilt {
+		return pan, tilt, nil
+	}
+	if !l.Clamp {
+		return 0, 0, fmt.Errorf("%w: pan %.2f°, tilt %.2f°", ErrOutsideSoftLimits, model.PanDegrees(pan),
+			model.TiltDegrees(tilt))
+	}
+	return clamp(pan, minPan, maxPan), clamp(tilt, minTilt, maxTilt), nil
+}
+
+// clamp limits v to min..max
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,76 @@
+package visca
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSetSoftLimits(t *testing.T) {
+	ctrl := NewController()
+	assert.Nil(t, ctrl.SoftLimits(1))
+
+	limits := &SoftLimits{MinPan: -90, MaxPan: 90, MinTilt: -10, MaxTilt: 45}
+	assert.Nil(t, ctrl.SetSoftLimits(1, limits))
+	limits.MaxPan = 170 // SetSoftLimits copies them
+	assert.Equal(t, &SoftLimits{MinPan: -90, MaxPan: 90, MinTilt: -10, MaxTilt: 45}, ctrl.SoftLimits(1))
+
+	assert.Equal(t, ErrInvalidSoftLimits, ctrl.SetSoftLimits(1, &SoftLimits{MinPan: 10, MaxPan: -10}))
+	assert.Equal(t, ErrInvalidCameraNumber, ctrl.SetSoftLimits(8, limits))
+	assert.Nil(t, ctrl.SetSoftLimits(1, nil))
+	assert.Nil(t, ctrl.SoftLimits(1))
+}
+
+func TestSoftLimits(t *testing.T) {
+	ctrl := NewController()
+	ctrl.Start()
+	defer ctrl.Stop()
+
+	conn := &MockConnection{}
+	conn.On("SetReceiveQueue", mock.Anything).Return()
+	conn.On("Start").Return(nil)
+	ctrl.AddCamera(1, conn)
+	ctrl.SetModel(1, ModelPTZOptics)
+	limits := SoftLimits{MinPan: -90, MaxPan: 90, MinTilt: -10, MaxTilt: 45}
+	ctrl.SetSoftLimits(1, &limits)
+	speeds := PanTiltSpeeds{Pan: 0x10, Tilt: 0x10}
+
+	// the camera finishes every command at once, and is at 80° pan, 0° tilt
+	done := func(mock.Arguments) {
+		ctrl.receiveQueue <- reply(t, 0x90, 0x41, 0xFF)
+		ctrl.receiveQueue <- reply(t, 0x90, 0x51, 0xFF)
+	}
+	conn.On("Send", &Packet{0, 1, Message{0x09, 0x06, 0x12}}).Run(func(mock.Arguments) {
+		ctrl.receiveQueue <- reply(t, 0x90, 0x50, 0x00, 0x04, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0xFF)
+	}).Return(nil)
+
+	// refused
+	err := ctrl.PanTiltAbsolute(100, 0, speeds)
+	assert.True(t, errors.Is(err, ErrOutsideSoftLimits))
+	err = ctrl.PanTiltRelative(20, 0, speeds)
+	assert.True(t, errors.Is(err, ErrOutsideSoftLimits))
+
+	// inside, and on the edge
+	conn.On("Send", &Packet{0, 1, Message{0x01, 0x06, 0x02, 0x10, 0x10, 0x00, 0x02, 0x08, 0x08, 0x0F, 0x0F, 0x07, 0x00}}).Run(done).Return(nil).Once()
+	assert.Nil(t, ctrl.PanTiltAbsolute(45, -10, speeds))
+	conn.On("Send", &Packet{0, 1, Message{0x01, 0x06, 0x03, 0x10, 0x10, 0x00, 0x00, 0x09, 0x00, 0x00, 0x00, 0x00, 0x00}}).Run(done).Return(nil).Once()
+	assert.Nil(t, ctrl.PanTiltRelative(10, 0, speeds))
+
+	// clamped to 90°, 45°
+	limits.Clamp = true
+	ctrl.SetSoftLimits(1, &limits)
+	conn.On("Send", &Packet{0, 1, Message{0x01, 0x06, 0x02, 0x10, 0x10, 0x00, 0x05, 0x01, 0x00, 0x00, 0x02, 0x08, 0x08}}).Run(done).Return(nil).Once()
+	assert.Nil(t, ctrl.PanTiltAbsolute(100, 60, speeds))
+	conn.On("Send", &Packet{0, 1, Message{0x01, 0x06, 0x03, 0x10, 0x10, 0x00, 0x00, 0x09, 0x00, 0x0F, 0x0F, 0x07, 0x00}}).Run(done).Return(nil).Once()
+	assert.Nil(t, ctrl.PanTiltRelative(20, -30, speeds))
+
+	// without limits, relative moves don't ask where the camera is
+	ctrl.SetSoftLimits(1, nil)
+	conn.On("Send", &Packet{0, 1, Message{0x01, 0x06, 0x03, 0x10, 0x10, 0x00, 0x01, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00}}).Run(done).Return(nil).Once()
+	assert.Nil(t, ctrl.PanTiltRelative(20, 0, speeds))
+
+	conn.AssertExpectations(t)
+	conn.AssertNumberOfCalls(t, "Send", 8) // 5 moves, and 3 inquiries for the relative ones with limits
+}